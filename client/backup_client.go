@@ -0,0 +1,106 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+
+	pb "github.com/orkhanrustamli/pcbook/pcbook_proto/go"
+
+	"google.golang.org/grpc"
+)
+
+const backupChunkSize = 64 << 10
+
+type BackupClient struct {
+	service pb.BackupServiceClient
+}
+
+func NewBackupClient(conn *grpc.ClientConn) *BackupClient {
+	service := pb.NewBackupServiceClient(conn)
+	return &BackupClient{service}
+}
+
+// Backup writes an archive of the whole server state to writer. Archives can
+// be large, so there is no timeout besides the one of ctx.
+func (client *BackupClient) Backup(ctx context.Context, writer io.Writer) error {
+	stream, err := client.service.Backup(ctx, &pb.BackupRequest{})
+	if err != nil {
+		return fmt.Errorf("cannot start backup: %v", err)
+	}
+
+	size := 0
+	for {
+		res, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("cannot receive backup: %v", err)
+		}
+
+		n, err := writer.Write(res.GetChunkData())
+		if err != nil {
+			return fmt.Errorf("cannot write backup: %v", err)
+		}
+		size += n
+	}
+
+	log.Printf("Backup received, size: %d", size)
+	return nil
+}
+
+// Restore sends an archive written by Backup. With replace, the server state
+// becomes exactly the archive, otherwise the archive is merged into it.
+func (client *BackupClient) Restore(ctx context.Context, reader io.Reader, replace bool) (*pb.RestoreResponse, error) {
+	stream, err := client.service.Restore(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("cannot start restore: %v", err)
+	}
+
+	mode := pb.RestoreInfo_MERGE
+	if replace {
+		mode = pb.RestoreInfo_REPLACE
+	}
+
+	req := &pb.RestoreRequest{
+		Data: &pb.RestoreRequest_Info{
+			Info: &pb.RestoreInfo{Mode: mode},
+		},
+	}
+	if err := stream.Send(req); err != nil {
+		return nil, fmt.Errorf("cannot send restore info: %v", err)
+	}
+
+	buffer := make([]byte, backupChunkSize)
+	for {
+		n, err := reader.Read(buffer)
+		if n > 0 {
+			req := &pb.RestoreRequest{
+				Data: &pb.RestoreRequest_ChunkData{
+					ChunkData: buffer[:n],
+				},
+			}
+			if err := stream.Send(req); err != nil {
+				break
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("cannot read backup: %v", err)
+		}
+	}
+
+	// a failed Send means the server has given up, its status is returned here
+	res, err := stream.CloseAndRecv()
+	if err != nil {
+		return nil, fmt.Errorf("cannot restore backup: %v", err)
+	}
+
+	log.Printf("Backup restored: %d laptops, %d ratings, %d users, %d images",
+		res.GetLaptops(), res.GetRatings(), res.GetUsers(), res.GetImages())
+	return res, nil
+}
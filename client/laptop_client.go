@@ -10,6 +10,7 @@ import (
 	"path/filepath"
 	"time"
 
+	"github.com/orkhanrustamli/pcbook/memory"
 	pb "github.com/orkhanrustamli/pcbook/pcbook_proto/go"
 
 	"google.golang.org/grpc"
@@ -48,11 +49,36 @@ func (client *LaptopClient) CreateLaptop(laptop *pb.Laptop) {
 	log.Printf("Created a laptop in store with id: %v", res.Id)
 }
 
+func (client *LaptopClient) GetLaptop(laptopId string) (*pb.Laptop, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	res, err := client.service.GetLaptop(ctx, &pb.GetLaptopRequest{Id: laptopId})
+	if err != nil {
+		return nil, fmt.Errorf("cannot get laptop: %v", err)
+	}
+
+	return res.GetLaptop(), nil
+}
+
 func (client *LaptopClient) SearchLaptop(filter *pb.Filter) {
 	req := &pb.SearchLaptopRequest{
 		Filter: filter,
 	}
 
+	client.searchLaptop(req)
+}
+
+func (client *LaptopClient) SearchRankedLaptop(filter *pb.Filter, ranking *pb.RankingOptions) {
+	req := &pb.SearchLaptopRequest{
+		Filter:  filter,
+		Ranking: ranking,
+	}
+
+	client.searchLaptop(req)
+}
+
+func (client *LaptopClient) searchLaptop(req *pb.SearchLaptopRequest) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
@@ -64,24 +90,100 @@ func (client *LaptopClient) SearchLaptop(filter *pb.Filter) {
 	for {
 		res, err := stream.Recv()
 		if err == io.EOF {
+			trailer := stream.Trailer()
+			log.Printf(
+				"Search complete: %v, scanned: %v, matched: %v, stop reason: %v",
+				trailer.Get("search-complete"),
+				trailer.Get("search-scanned"),
+				trailer.Get("search-matched"),
+				trailer.Get("search-stop-reason"),
+			)
 			return
 		}
 		if err != nil {
 			log.Fatalf("Cannot read response: %v", err)
 		}
 
+		if suggestion := res.GetDidYouMean(); suggestion != nil {
+			log.Printf("No laptop found, did you mean brand: %q, name: %q?", suggestion.GetBrand(), suggestion.GetName())
+			continue
+		}
+
 		laptop := res.GetLaptop()
 		log.Print("- found: ", laptop.GetId())
 		log.Print("  + brand: ", laptop.GetBrand())
 		log.Print("  + name: ", laptop.GetName())
 		log.Print("  + cpu cores: ", laptop.GetCpu().GetNumberCores())
 		log.Print("  + cpu min ghz: ", laptop.GetCpu().GetMinGhz())
-		log.Print("  + ram: ", laptop.GetRam())
+		log.Print("  + ram: ", memory.Format(laptop.GetRam()))
+		log.Print("  + price: ", laptop.GetPriceUsd())
+		if req.GetRanking() != nil {
+			log.Print("  + score: ", res.GetScore())
+		}
+	}
+}
+
+func (client *LaptopClient) SearchLaptopBatch(filter *pb.Filter, batchSize uint32) {
+	req := &pb.SearchLaptopBatchRequest{
+		Filter:       filter,
+		MaxBatchSize: batchSize,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := client.service.SearchLaptopBatch(ctx, req)
+	if err != nil {
+		log.Fatal("Cannot search laptop: ", err)
+	}
+
+	for {
+		res, err := stream.Recv()
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			log.Fatalf("Cannot read response: %v", err)
+		}
+
+		log.Printf("Received a batch of %d laptops", len(res.GetLaptops()))
+		for _, laptop := range res.GetLaptops() {
+			log.Print("- found: ", laptop.GetId())
+			log.Print("  + brand: ", laptop.GetBrand())
+			log.Print("  + name: ", laptop.GetName())
+			log.Print("  + price: ", laptop.GetPriceUsd())
+		}
+	}
+}
+
+func (client *LaptopClient) SimilarLaptops(laptopId string, k uint32, filter *pb.Filter) ([]*pb.SimilarLaptop, error) {
+	req := &pb.SimilarLaptopsRequest{
+		LaptopId: laptopId,
+		K:        k,
+		Filter:   filter,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	res, err := client.service.SimilarLaptops(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("cannot find similar laptops: %v", err)
+	}
+
+	for _, similar := range res.GetSimilarLaptops() {
+		laptop := similar.GetLaptop()
+		log.Print("- similar: ", laptop.GetId())
+		log.Print("  + brand: ", laptop.GetBrand())
+		log.Print("  + name: ", laptop.GetName())
 		log.Print("  + price: ", laptop.GetPriceUsd())
+		log.Print("  + distance: ", similar.GetDistance())
 	}
+
+	return res.GetSimilarLaptops(), nil
 }
 
-func (client *LaptopClient) UploadImage(laptopId, imagePath string) {
+func (client *LaptopClient) UploadImage(laptopId, imagePath string) string {
 	image, err := os.Open(imagePath)
 	if err != nil {
 		log.Fatalf("Cannot open the image: %v", err)
@@ -139,8 +241,182 @@ func (client *LaptopClient) UploadImage(laptopId, imagePath string) {
 		log.Fatalf("Cannot upload the image: %v", err)
 	}
 
-	log.Printf("Image uploaded with id: %s, size: %d", res.GetId(), res.GetSize())
+	log.Printf("Image uploaded with id: %s, size: %d, type: %s", res.GetId(), res.GetSize(), res.GetImageType())
+	return res.GetId()
+}
+
+// DownloadImage writes the image, or the named variant of it, to writer and
+// returns its metadata
+// StartUpload opens a resumable upload of the image file and returns the ID
+// of its session. ResumeUpload sends the image.
+func (client *LaptopClient) StartUpload(laptopId, imagePath string) (string, error) {
+	info, err := os.Stat(imagePath)
+	if err != nil {
+		return "", fmt.Errorf("cannot stat the image: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req := &pb.StartUploadRequest{
+		Info: &pb.ImageInfo{
+			LaptopId:  laptopId,
+			ImageType: filepath.Ext(imagePath),
+		},
+		Size: uint64(info.Size()),
+	}
+	res, err := client.service.StartUpload(ctx, req)
+	if err != nil {
+		return "", fmt.Errorf("cannot start upload: %v", err)
+	}
+
+	log.Printf("Upload started with session id: %s, expires at: %v", res.GetSessionId(), res.GetExpiresAt().AsTime())
+	return res.GetSessionId(), nil
+}
+
+// ResumeUpload sends the part of the image file the session is missing,
+// from the offset the server committed, and returns the ID of the image once
+// it has all of it. It can be called again after it failed.
+func (client *LaptopClient) ResumeUpload(sessionId, imagePath string) (string, error) {
+	image, err := os.Open(imagePath)
+	if err != nil {
+		return "", fmt.Errorf("cannot open the image: %v", err)
+	}
+	defer image.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	query, err := client.service.QueryUpload(ctx, &pb.QueryUploadRequest{SessionId: sessionId})
+	if err != nil {
+		return "", fmt.Errorf("cannot query upload: %v", err)
+	}
+
+	offset := query.GetCommittedOffset()
+	if offset < query.GetSize() {
+		log.Printf("Resuming upload %s from offset %d of %d", sessionId, offset, query.GetSize())
+
+		if _, err := image.Seek(int64(offset), io.SeekStart); err != nil {
+			return "", fmt.Errorf("cannot seek the image: %v", err)
+		}
+
+		stream, err := client.service.UploadChunk(ctx)
+		if err != nil {
+			return "", fmt.Errorf("cannot upload chunks: %v", err)
+		}
+
+		reader := bufio.NewReader(image)
+		buffer := make([]byte, 1024)
+
+		for offset < query.GetSize() {
+			n, err := reader.Read(buffer)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return "", fmt.Errorf("cannot read the image: %v", err)
+			}
+
+			req := &pb.UploadChunkRequest{
+				SessionId: sessionId,
+				Offset:    offset,
+				ChunkData: buffer[:n],
+			}
+			if err := stream.Send(req); err != nil {
+				// the server rejected the chunk, its status tells why
+				break
+			}
+			offset += uint64(n)
+		}
+
+		res, err := stream.CloseAndRecv()
+		if err != nil {
+			return "", fmt.Errorf("cannot upload chunks: %v", err)
+		}
+		if res.GetCommittedOffset() < query.GetSize() {
+			return "", fmt.Errorf("uploaded %d bytes of an image of %d bytes", res.GetCommittedOffset(), query.GetSize())
+		}
+	}
+
+	res, err := client.service.FinishUpload(ctx, &pb.FinishUploadRequest{SessionId: sessionId})
+	if err != nil {
+		return "", fmt.Errorf("cannot finish upload: %v", err)
+	}
+
+	log.Printf("Image uploaded with id: %s, size: %d, type: %s", res.GetId(), res.GetSize(), res.GetImageType())
+	return res.GetId(), nil
+}
+
+func (client *LaptopClient) DownloadImage(imageId string, variant string, writer io.Writer) (*pb.ImageMetadata, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req := &pb.DownloadImageRequest{
+		ImageId: imageId,
+		Variant: variant,
+	}
+	stream, err := client.service.DownloadImage(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("cannot download image: %v", err)
+	}
+
+	res, err := stream.Recv()
+	if err != nil {
+		return nil, fmt.Errorf("cannot receive image metadata: %v", err)
+	}
+	metadata := res.GetMetadata()
+	if metadata == nil {
+		return nil, fmt.Errorf("image does not start with its metadata")
+	}
+
+	size := uint64(0)
+	for {
+		res, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("cannot receive chunk data: %v", err)
+		}
+
+		chunk := res.GetChunkData()
+		if _, err := writer.Write(chunk); err != nil {
+			return nil, fmt.Errorf("cannot write image: %v", err)
+		}
+		size += uint64(len(chunk))
+	}
+
+	if size != metadata.GetSize() {
+		return nil, fmt.Errorf("received %d bytes of an image of %d bytes", size, metadata.GetSize())
+	}
+
+	log.Printf("Image downloaded with id: %s, size: %d", imageId, size)
+	return metadata, nil
+}
+
+func (client *LaptopClient) ListImages(laptopId string) ([]*pb.StoredImage, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	res, err := client.service.ListImages(ctx, &pb.ListImagesRequest{LaptopId: laptopId})
+	if err != nil {
+		return nil, fmt.Errorf("cannot list images: %v", err)
+	}
+
+	return res.GetImages(), nil
+}
+
+func (client *LaptopClient) DeleteImage(imageId string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := client.service.DeleteImage(ctx, &pb.DeleteImageRequest{ImageId: imageId})
+	if err != nil {
+		return fmt.Errorf("cannot delete image: %v", err)
+	}
 
+	log.Printf("Deleted image with id: %s", imageId)
+	return nil
 }
 
 func (client *LaptopClient) RateLaptop(laptopIds []string, scores []float64) error {
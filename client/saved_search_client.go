@@ -0,0 +1,103 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	pb "github.com/orkhanrustamli/pcbook/pcbook_proto/go"
+
+	"google.golang.org/grpc"
+)
+
+type SavedSearchClient struct {
+	service pb.SavedSearchServiceClient
+}
+
+func NewSavedSearchClient(conn *grpc.ClientConn) *SavedSearchClient {
+	service := pb.NewSavedSearchServiceClient(conn)
+	return &SavedSearchClient{service}
+}
+
+func (client *SavedSearchClient) SaveSearch(name string, filter *pb.Filter) (string, error) {
+	req := &pb.SaveSearchRequest{
+		Name:   name,
+		Filter: filter,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	res, err := client.service.SaveSearch(ctx, req)
+	if err != nil {
+		return "", fmt.Errorf("cannot save search: %v", err)
+	}
+
+	log.Printf("Saved search %s with id: %s", name, res.GetSavedSearch().GetId())
+	return res.GetSavedSearch().GetId(), nil
+}
+
+func (client *SavedSearchClient) ListSavedSearches() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	res, err := client.service.ListSavedSearches(ctx, &pb.ListSavedSearchesRequest{})
+	if err != nil {
+		return fmt.Errorf("cannot list saved searches: %v", err)
+	}
+
+	for _, search := range res.GetSavedSearches() {
+		log.Print("- saved search: ", search.GetId())
+		log.Print("  + name: ", search.GetName())
+		log.Print("  + filter: ", search.GetFilter())
+		log.Print("  + last run: ", search.GetLastRunAt().AsTime())
+	}
+
+	return nil
+}
+
+func (client *SavedSearchClient) DeleteSavedSearch(id string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := client.service.DeleteSavedSearch(ctx, &pb.DeleteSavedSearchRequest{Id: id})
+	if err != nil {
+		return fmt.Errorf("cannot delete saved search: %v", err)
+	}
+
+	log.Printf("Deleted saved search with id: %s", id)
+	return nil
+}
+
+func (client *SavedSearchClient) RunSavedSearch(id string, onlyNew bool) error {
+	req := &pb.RunSavedSearchRequest{
+		Id:      id,
+		OnlyNew: onlyNew,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := client.service.RunSavedSearch(ctx, req)
+	if err != nil {
+		return fmt.Errorf("cannot run saved search: %v", err)
+	}
+
+	for {
+		res, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("cannot read response: %v", err)
+		}
+
+		laptop := res.GetLaptop()
+		log.Print("- found: ", laptop.GetId())
+		log.Print("  + brand: ", laptop.GetBrand())
+		log.Print("  + name: ", laptop.GetName())
+		log.Print("  + created at: ", laptop.GetCreatedAt().AsTime())
+	}
+}
@@ -4,11 +4,14 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/orkhanrustamli/pcbook/client"
 	"github.com/orkhanrustamli/pcbook/genarator"
+	"github.com/orkhanrustamli/pcbook/memory"
 	pb "github.com/orkhanrustamli/pcbook/pcbook_proto/go"
 
 	"google.golang.org/grpc"
@@ -22,11 +25,33 @@ const (
 
 func authMethods() map[string]bool {
 	const laptopServicePath = "/pcbook.LaptopService/"
+	const savedSearchServicePath = "/pcbook.SavedSearchService/"
+	const backupServicePath = "/pcbook.BackupService/"
 
 	return map[string]bool{
 		laptopServicePath + "CreateLaptop": true,
 		laptopServicePath + "UploadImage":  true,
 		laptopServicePath + "RateLaptop":   true,
+		laptopServicePath + "DeleteImage":  true,
+		laptopServicePath + "StartUpload":  true,
+		laptopServicePath + "UploadChunk":  true,
+		laptopServicePath + "QueryUpload":  true,
+		laptopServicePath + "FinishUpload": true,
+		// search RPCs are public, the token only scopes them to the tenant
+		laptopServicePath + "GetLaptop":         true,
+		laptopServicePath + "SearchLaptop":      true,
+		laptopServicePath + "SearchLaptopBatch": true,
+		laptopServicePath + "SimilarLaptops":    true,
+		laptopServicePath + "DownloadImage":     true,
+		laptopServicePath + "ListImages":        true,
+
+		savedSearchServicePath + "SaveSearch":        true,
+		savedSearchServicePath + "ListSavedSearches": true,
+		savedSearchServicePath + "DeleteSavedSearch": true,
+		savedSearchServicePath + "RunSavedSearch":    true,
+
+		backupServicePath + "Backup":  true,
+		backupServicePath + "Restore": true,
 	}
 }
 
@@ -61,7 +86,9 @@ func main() {
 	testRateLaptop(laptopClient)
 
 	// testImageUpload(laptopClient)
+	// testResumableUpload(laptopClient)
 	// testSearchLaptop(laptopClient)
+	// testSavedSearch(laptopClient, client.NewSavedSearchClient(cc2))
 
 }
 
@@ -78,7 +105,7 @@ func testSearchLaptop(laptopClient *client.LaptopClient) {
 		MaxPriceUsd: 3000,
 		MinCpuCores: 4,
 		MinCpuGhz:   2.5,
-		MinRam:      &pb.Memory{Value: 8, Unit: pb.Memory_GIGABYTE},
+		MinRam:      memory.Gigabytes(8),
 	}
 	laptopClient.SearchLaptop(filter)
 }
@@ -86,7 +113,80 @@ func testSearchLaptop(laptopClient *client.LaptopClient) {
 func testImageUpload(laptopClient *client.LaptopClient) {
 	laptop := genarator.NewLaptop()
 	laptopClient.CreateLaptop(laptop)
-	laptopClient.UploadImage(laptop.GetId(), "tmp/laptop.jpg")
+	imageId := laptopClient.UploadImage(laptop.GetId(), "tmp/laptop.jpg")
+
+	image, err := os.Create(filepath.Join(os.TempDir(), imageId+filepath.Ext("tmp/laptop.jpg")))
+	if err != nil {
+		log.Fatalf("Cannot create the image file: %v", err)
+	}
+	defer image.Close()
+
+	if _, err := laptopClient.DownloadImage(imageId, "small", image); err != nil {
+		log.Fatal(err)
+	}
+
+	images, err := laptopClient.ListImages(laptop.GetId())
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("Laptop %s has %d images", laptop.GetId(), len(images))
+
+	if err := laptopClient.DeleteImage(imageId); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func testResumableUpload(laptopClient *client.LaptopClient) {
+	laptop := genarator.NewLaptop()
+	laptopClient.CreateLaptop(laptop)
+
+	sessionId, err := laptopClient.StartUpload(laptop.GetId(), "tmp/laptop.jpg")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for attempt := 1; ; attempt++ {
+		_, err := laptopClient.ResumeUpload(sessionId, "tmp/laptop.jpg")
+		if err == nil {
+			break
+		}
+		if attempt == 5 {
+			log.Fatal(err)
+		}
+
+		log.Printf("Upload interrupted, retrying: %v", err)
+		time.Sleep(time.Second)
+	}
+}
+
+func testSavedSearch(laptopClient *client.LaptopClient, savedSearchClient *client.SavedSearchClient) {
+	filter := &pb.Filter{
+		MaxPriceUsd: 3000,
+		MinCpuCores: 4,
+		MinCpuGhz:   2.5,
+		MinRam:      memory.Gigabytes(8),
+	}
+
+	id, err := savedSearchClient.SaveSearch("daily", filter)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for i := 0; i < 10; i++ {
+		laptopClient.CreateLaptop(genarator.NewLaptop())
+	}
+
+	if err := savedSearchClient.RunSavedSearch(id, true); err != nil {
+		log.Fatal(err)
+	}
+
+	if err := savedSearchClient.ListSavedSearches(); err != nil {
+		log.Fatal(err)
+	}
+
+	if err := savedSearchClient.DeleteSavedSearch(id); err != nil {
+		log.Fatal(err)
+	}
 }
 
 func testRateLaptop(laptopClient *client.LaptopClient) {
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+
+	"github.com/orkhanrustamli/pcbook/service"
+)
+
+func main() {
+	fromStore := flag.String("from-store", "bbolt", "Source store backend: journal, sqlite or bbolt")
+	fromDb := flag.String("from-db", "pcbook.bolt", "Database file or journal directory of the source")
+	fromImages := flag.String("from-images", "img", "Image folder of the source")
+	toStore := flag.String("to-store", "sqlite", "Destination store backend: journal, sqlite or bbolt")
+	toDb := flag.String("to-db", "pcbook.db", "Database file or journal directory of the destination")
+	toImages := flag.String("to-images", "img-migrated", "Image folder of the destination")
+	dryRun := flag.Bool("dry-run", false, "Report what would be copied without writing anything")
+	resume := flag.Bool("resume", false, "Continue an interrupted migration into a destination that already holds records")
+	verify := flag.Bool("verify", true, "Compare checksums of source and destination once the copy is done")
+	flag.Parse()
+
+	source, err := openStores(*fromStore, *fromDb, *fromImages)
+	if err != nil {
+		log.Fatalf("Cannot open source: %v", err)
+	}
+	defer source.Close()
+
+	if !*dryRun {
+		if err := os.MkdirAll(*toImages, 0755); err != nil {
+			log.Fatalf("Cannot create destination image folder: %v", err)
+		}
+	}
+
+	destination, err := openStores(*toStore, *toDb, *toImages)
+	if err != nil {
+		log.Fatalf("Cannot open destination: %v", err)
+	}
+	defer destination.Close()
+
+	for _, storeType := range []string{*fromStore, *toStore} {
+		if storeType != "bbolt" {
+			log.Printf("The %s backend keeps ratings and users in memory, they are not migrated with it", storeType)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	migration := &service.Migration{
+		Source:      source,
+		Destination: destination,
+		DryRun:      *dryRun,
+		Resume:      *resume,
+		Progress: func(kind string, stats service.MigrationStats) {
+			log.Printf("%s: %d copied, %d already in destination", kind, stats.Copied, stats.Skipped)
+		},
+	}
+
+	if *dryRun {
+		log.Print("Dry run, nothing is written to the destination")
+	}
+
+	_, err = migration.Run(ctx)
+	if errors.Is(err, service.ErrDestinationNotEmpty) {
+		log.Fatalf("Cannot migrate: %v, use -resume to continue an earlier migration", err)
+	}
+	if err != nil {
+		log.Fatalf("Cannot migrate: %v, run again with -resume to continue", err)
+	}
+
+	if !*verify || *dryRun {
+		return
+	}
+
+	checksums, err := migration.Verify(ctx)
+	for _, checksum := range checksums {
+		log.Printf(
+			"%s: %d records, %d missing, %d different, source %s, destination %s",
+			checksum.Kind, checksum.Items, checksum.Missing, checksum.Mismatched, checksum.Source, checksum.Destination,
+		)
+	}
+	if err != nil {
+		log.Fatalf("Cannot verify migration: %v", err)
+	}
+
+	log.Print("Migration verified")
+}
+
+func openStores(storeType, dbPath, imageFolder string) (*service.Stores, error) {
+	return service.OpenStores(service.StoreOptions{
+		Type:        storeType,
+		Path:        dbPath,
+		ImageFolder: imageFolder,
+		Fsync:       service.FsyncAlways,
+	})
+}
@@ -1,12 +1,16 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
 	"net"
+	"path/filepath"
 	"time"
 
+	"github.com/orkhanrustamli/pcbook/client"
 	pb "github.com/orkhanrustamli/pcbook/pcbook_proto/go"
 	"github.com/orkhanrustamli/pcbook/service"
 
@@ -17,49 +21,208 @@ import (
 const (
 	secretKey     = "secret"
 	tokenDuration = 15 * time.Minute
+
+	replicationRetryInterval = 5 * time.Second
+	uploadCollectInterval    = time.Minute
 )
 
 var accessManager = map[string][]string{
-	"/pcbook.LaptopService/" + "CreateLaptop": {"admin"},
-	"/pcbook.LaptopService/" + "UploadImage":  {"admin"},
-	"/pcbook.LaptopService/" + "Ratelaptop":   {"admin", "user"},
+	"/pcbook.LaptopService/" + "CreateLaptop": {"admin", service.SuperAdminRole},
+	"/pcbook.LaptopService/" + "UploadImage":  {"admin", service.SuperAdminRole},
+	"/pcbook.LaptopService/" + "RateLaptop":   {"admin", "user", service.SuperAdminRole},
+	"/pcbook.LaptopService/" + "DeleteImage":  {"admin", service.SuperAdminRole},
+	"/pcbook.LaptopService/" + "StartUpload":  {"admin", service.SuperAdminRole},
+	"/pcbook.LaptopService/" + "UploadChunk":  {"admin", service.SuperAdminRole},
+	"/pcbook.LaptopService/" + "QueryUpload":  {"admin", service.SuperAdminRole},
+	"/pcbook.LaptopService/" + "FinishUpload": {"admin", service.SuperAdminRole},
+
+	"/pcbook.SavedSearchService/" + "SaveSearch":        {"admin", "user", service.SuperAdminRole},
+	"/pcbook.SavedSearchService/" + "ListSavedSearches": {"admin", "user", service.SuperAdminRole},
+	"/pcbook.SavedSearchService/" + "DeleteSavedSearch": {"admin", "user", service.SuperAdminRole},
+	"/pcbook.SavedSearchService/" + "RunSavedSearch":    {"admin", "user", service.SuperAdminRole},
+
+	"/pcbook.BackupService/" + "Backup":  {"admin", service.SuperAdminRole},
+	"/pcbook.BackupService/" + "Restore": {"admin", service.SuperAdminRole},
+
+	"/pcbook.ReplicationService/" + "Replicate": {"admin", service.SuperAdminRole},
+}
+
+// readOnlyMethods are the RPCs a follower serves, it rejects every other one
+var readOnlyMethods = map[string]bool{
+	"/pcbook.LaptopService/" + "GetLaptop":         true,
+	"/pcbook.LaptopService/" + "SearchLaptop":      true,
+	"/pcbook.LaptopService/" + "SearchLaptopBatch": true,
+	"/pcbook.LaptopService/" + "SimilarLaptops":    true,
+	"/pcbook.LaptopService/" + "DownloadImage":     true,
+	"/pcbook.LaptopService/" + "ListImages":        true,
+
+	"/pcbook.AuthService/" + "Login": true,
+
+	"/pcbook.BackupService/" + "Backup": true,
+
+	"/pcbook.ReplicationService/" + "ReplicationStatus": true,
+
+	"/grpc.reflection.v1alpha.ServerReflection/" + "ServerReflectionInfo": true,
 }
 
 func seedUsers(userStore service.UserStore) error {
-	err := createUser(userStore, "admin1", "secret1", "admin")
-	if err != nil {
+	err := createUser(userStore, "admin1", "secret1", "admin", "")
+	if err != nil && !errors.Is(err, service.ErrAlreadyExists) {
+		return err
+	}
+
+	err = createUser(userStore, "user1", "secret2", "user", "")
+	if err != nil && !errors.Is(err, service.ErrAlreadyExists) {
+		return err
+	}
+
+	err = createUser(userStore, "superadmin1", "secret3", service.SuperAdminRole, "")
+	if err != nil && !errors.Is(err, service.ErrAlreadyExists) {
+		return err
+	}
+
+	// acme1 works in a catalog of its own
+	err = createUser(userStore, "acme1", "secret4", "admin", "acme")
+	if err != nil && !errors.Is(err, service.ErrAlreadyExists) {
 		return err
 	}
-	return createUser(userStore, "user1", "secret2", "user")
+
+	return nil
 }
 
 func main() {
 	port := flag.Int("port", 0, "Port used for gRPC server")
+	storeType := flag.String("store", "memory", "Store backend: memory, sharded, journal, sqlite or bbolt")
+	dbPath := flag.String("db", "pcbook.db", "Database file used by the sqlite and bbolt stores, or directory used by the journal store")
+	imageFolder := flag.String("images", "img", "Folder the images are stored in")
+	fsync := flag.String("fsync", "always", "Journal fsync policy: always, interval or never")
+	snapshotInterval := flag.Duration("snapshot-interval", 10*time.Minute, "Interval between journal snapshots, 0 to disable")
+	cacheSize := flag.Int("cache-size", 0, "Number of laptops kept in the lookup cache, 0 to disable")
+	cacheTTL := flag.Duration("cache-ttl", time.Minute, "Time a cached laptop stays valid, 0 to keep it until evicted")
+	leader := flag.String("leader", "", "Address of the leader to follow, the server is a read-only follower if set")
+	leaderUsername := flag.String("leader-username", "admin1", "User the follower replicates the leader as")
+	leaderPassword := flag.String("leader-password", "secret1", "Password of the leader user")
+	clusterConfig := flag.String("cluster", "", "Cluster config file, the server is a node of that cluster if set")
+	node := flag.String("node", "", "ID of this server in the cluster config")
+	imageVariants := flag.String("image-variants", "small=160x160,medium=480x480,large=1024x1024", "Renditions made of every uploaded image, as name=WIDTHxHEIGHT")
+	uploadFolder := flag.String("uploads", "", "Folder the resumable uploads are kept in, a folder of the images folder if empty")
+	uploadTTL := flag.Duration("upload-ttl", 24*time.Hour, "Time an upload session is kept after its last chunk")
 	flag.Parse()
 	fmt.Printf("Starting server on port: %d", *port)
 
-	userStore := service.NewInMemoryUserStore()
+	policy, err := service.ParseFsyncPolicy(*fsync)
+	if err != nil {
+		log.Fatalf("Cannot create stores: %v", err)
+	}
+
+	variants, err := service.ParseImageVariants(*imageVariants)
+	if err != nil {
+		log.Fatalf("Cannot parse image variants: %v", err)
+	}
+
+	var cluster *service.Cluster
+	if *clusterConfig != "" {
+		if *leader != "" {
+			log.Fatal("A cluster node cannot be a follower")
+		}
+
+		cluster, err = newCluster(*clusterConfig, *node)
+		if err != nil {
+			log.Fatalf("Cannot join cluster: %v", err)
+		}
+		defer cluster.Close()
+	}
+
+	stores, err := service.OpenStores(service.StoreOptions{
+		Type:             *storeType,
+		Path:             *dbPath,
+		ImageFolder:      *imageFolder,
+		Fsync:            policy,
+		SnapshotInterval: *snapshotInterval,
+	})
+	if err != nil {
+		log.Fatalf("Cannot create stores: %v", err)
+	}
+
+	// restores write through the cache too, so it never serves a replaced laptop
+	if *cacheSize > 0 {
+		stores.Laptops = service.NewCachedLaptopStore(stores.Laptops, *cacheSize, *cacheTTL)
+	}
+
+	var replicationServer *service.ReplicationServer
+	if *leader == "" {
+		replication := service.NewReplicationLog()
+		stores = replication.Wrap(stores)
+		replicationServer = service.NewLeaderReplicationServer(replication, stores)
+	} else {
+		follower, err := newFollower(*leader, *leaderUsername, *leaderPassword, stores)
+		if err != nil {
+			log.Fatalf("Cannot follow leader: %v", err)
+		}
+		go follower.Run(context.Background(), replicationRetryInterval)
+		replicationServer = service.NewFollowerReplicationServer(follower)
+	}
+	laptopStore, ratingStore, userStore := stores.Laptops, stores.Ratings, stores.Users
+
 	jwtManager := service.NewJWTManager(secretKey, tokenDuration)
 	authServer := service.NewAuthServer(userStore, jwtManager)
 
-	err := seedUsers(userStore)
-	if err != nil {
-		log.Fatal("cannot seed users")
+	// followers get their users from the leader
+	if *leader == "" {
+		err = seedUsers(userStore)
+		if err != nil {
+			log.Fatal("cannot seed users")
+		}
+	}
+
+	barrier := service.NewWriteBarrier()
+	laptopServer := service.NewLaptopServer(laptopStore, stores.Images, ratingStore)
+	laptopServer.Barrier = barrier
+	laptopServer.Cluster = cluster
+	laptopServer.ImageVariants = variants
+	// followers take no uploads, and must not clear the sessions of a leader
+	// sharing their folder
+	if *leader == "" {
+		if *uploadFolder == "" {
+			*uploadFolder = filepath.Join(*imageFolder, "uploads")
+		}
+
+		uploads, err := service.NewUploadSessionStore(*uploadFolder, *uploadTTL)
+		if err != nil {
+			log.Fatalf("Cannot create upload session store: %v", err)
+		}
+		go uploads.Run(context.Background(), uploadCollectInterval)
+		laptopServer.Uploads = uploads
 	}
+	backupServer := service.NewBackupServer(stores, barrier)
 
-	laptopStore := service.NewInMemoryLaptopStore()
-	imageStore := service.NewDiskImageStore("img")
-	ratingStore := service.NewInMemoryRatingStore()
-	laptopServer := service.NewLaptopServer(laptopStore, imageStore, ratingStore)
+	savedSearchStore := service.NewInMemorySavedSearchStore()
+	savedSearchServer := service.NewSavedSearchServer(laptopStore, savedSearchStore)
+	savedSearchServer.Cluster = cluster
 
 	authInterceptor := service.NewAuthInterceptor(jwtManager, accessManager)
 
+	interceptors := []service.Interceptor{authInterceptor}
+	if *leader != "" {
+		interceptors = append(interceptors, service.NewReadOnlyInterceptor(*leader, readOnlyMethods))
+	}
+
+	unaryInterceptors := []grpc.UnaryServerInterceptor{}
+	streamInterceptors := []grpc.StreamServerInterceptor{}
+	for _, interceptor := range interceptors {
+		unaryInterceptors = append(unaryInterceptors, interceptor.Unary())
+		streamInterceptors = append(streamInterceptors, interceptor.Stream())
+	}
+
 	grpcServer := grpc.NewServer(
-		grpc.UnaryInterceptor(authInterceptor.Unary()),
-		grpc.StreamInterceptor(authInterceptor.Stream()),
+		grpc.ChainUnaryInterceptor(unaryInterceptors...),
+		grpc.ChainStreamInterceptor(streamInterceptors...),
 	)
 	pb.RegisterLaptopServiceServer(grpcServer, laptopServer)
 	pb.RegisterAuthServiceServer(grpcServer, authServer)
+	pb.RegisterSavedSearchServiceServer(grpcServer, savedSearchServer)
+	pb.RegisterBackupServiceServer(grpcServer, backupServer)
+	pb.RegisterReplicationServiceServer(grpcServer, replicationServer)
 	reflection.Register(grpcServer)
 
 	add := fmt.Sprintf("0.0.0.0:%d", *port)
@@ -74,8 +237,47 @@ func main() {
 	}
 }
 
-func createUser(userStore service.UserStore, username, password, role string) error {
-	user, err := service.NewUser(username, password, role)
+func newCluster(path string, node string) (*service.Cluster, error) {
+	config, err := service.LoadClusterConfig(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return service.NewCluster(config, node, grpc.WithInsecure())
+}
+
+// newFollower logs in to the leader, since replication carries every user
+// and is for admins only
+func newFollower(leader, username, password string, stores *service.Stores) (*service.Follower, error) {
+	authConn, err := grpc.Dial(leader, grpc.WithInsecure())
+	if err != nil {
+		return nil, err
+	}
+
+	authClient := client.NewAuthClient(authConn, username, password)
+	authInterceptor, err := client.NewAuthInterceptor(
+		authClient,
+		map[string]bool{"/pcbook.ReplicationService/Replicate": true},
+		tokenDuration/2,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := grpc.Dial(
+		leader,
+		grpc.WithInsecure(),
+		grpc.WithStreamInterceptor(authInterceptor.Stream),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return service.NewFollower(conn, leader, stores), nil
+}
+
+func createUser(userStore service.UserStore, username, password, role, tenant string) error {
+	user, err := service.NewUser(username, password, role, tenant)
 	if err != nil {
 		return err
 	}
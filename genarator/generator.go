@@ -2,6 +2,7 @@ package genarator
 
 import (
 	"github.com/golang/protobuf/ptypes"
+	"github.com/orkhanrustamli/pcbook/memory"
 	pb "github.com/orkhanrustamli/pcbook/pcbook_proto/go"
 )
 
@@ -45,20 +46,14 @@ func NewGPU() *pb.GPU {
 		Name:   name,
 		MinGhz: minGhz,
 		MaxGhz: maxGhz,
-		Memory: &pb.Memory{
-			Value: uint64(memGB),
-			Unit:  pb.Memory_GIGABYTE,
-		},
+		Memory: memory.Gigabytes(uint64(memGB)),
 	}
 }
 
 func NewRam() *pb.Memory {
 	memGB := randomIntRange(4, 64)
 
-	return &pb.Memory{
-		Value: uint64(memGB),
-		Unit:  pb.Memory_GIGABYTE,
-	}
+	return memory.Gigabytes(uint64(memGB))
 }
 
 func NewSSD() *pb.Storage {
@@ -66,10 +61,7 @@ func NewSSD() *pb.Storage {
 
 	return &pb.Storage{
 		Driver: pb.Storage_SSD,
-		Memory: &pb.Memory{
-			Value: uint64(memGB),
-			Unit:  pb.Memory_GIGABYTE,
-		},
+		Memory: memory.Gigabytes(uint64(memGB)),
 	}
 }
 
@@ -78,10 +70,7 @@ func NewHDD() *pb.Storage {
 
 	return &pb.Storage{
 		Driver: pb.Storage_HDD,
-		Memory: &pb.Memory{
-			Value: uint64(memGB),
-			Unit:  pb.Memory_TERABYTE,
-		},
+		Memory: memory.Terabytes(uint64(memGB)),
 	}
 }
 
@@ -0,0 +1,298 @@
+// Package memory implements unit-aware arithmetic on pb.Memory values.
+//
+// KILOBYTE, MEGABYTE, GIGABYTE and TERABYTE are decimal (SI) units, while
+// KIBIBYTE, MEBIBYTE, GIBIBYTE and TEBIBYTE are binary (IEC) units. Values are
+// compared and added as exact 128-bit bit counts, so no unit can overflow.
+package memory
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"math/bits"
+	"strings"
+	"unicode"
+
+	pb "github.com/orkhanrustamli/pcbook/pcbook_proto/go"
+)
+
+// Errors
+var (
+	ErrUnknownUnit = errors.New("unknown memory unit")
+	ErrOverflow    = errors.New("memory value overflows")
+	ErrInvalid     = errors.New("invalid memory value")
+)
+
+type unitInfo struct {
+	bits   uint64
+	symbol string
+}
+
+var units = map[pb.Memory_Unit]unitInfo{
+	pb.Memory_BIT:      {1, "bit"},
+	pb.Memory_BYTE:     {8, "B"},
+	pb.Memory_KILOBYTE: {8 * 1e3, "KB"},
+	pb.Memory_MEGABYTE: {8 * 1e6, "MB"},
+	pb.Memory_GIGABYTE: {8 * 1e9, "GB"},
+	pb.Memory_TERABYTE: {8 * 1e12, "TB"},
+	pb.Memory_KIBIBYTE: {8 << 10, "KiB"},
+	pb.Memory_MEBIBYTE: {8 << 20, "MiB"},
+	pb.Memory_GIBIBYTE: {8 << 30, "GiB"},
+	pb.Memory_TEBIBYTE: {8 << 40, "TiB"},
+}
+
+// Units to try when normalizing, from the largest to the smallest
+var (
+	decimalUnits = []pb.Memory_Unit{
+		pb.Memory_TERABYTE,
+		pb.Memory_GIGABYTE,
+		pb.Memory_MEGABYTE,
+		pb.Memory_KILOBYTE,
+		pb.Memory_BYTE,
+		pb.Memory_BIT,
+	}
+	binaryUnits = []pb.Memory_Unit{
+		pb.Memory_TEBIBYTE,
+		pb.Memory_GIBIBYTE,
+		pb.Memory_MEBIBYTE,
+		pb.Memory_KIBIBYTE,
+		pb.Memory_BYTE,
+		pb.Memory_BIT,
+	}
+)
+
+// Size is an exact number of bits
+type Size struct {
+	hi, lo uint64
+}
+
+func Bits(value uint64) *pb.Memory      { return &pb.Memory{Value: value, Unit: pb.Memory_BIT} }
+func Bytes(value uint64) *pb.Memory     { return &pb.Memory{Value: value, Unit: pb.Memory_BYTE} }
+func Kilobytes(value uint64) *pb.Memory { return &pb.Memory{Value: value, Unit: pb.Memory_KILOBYTE} }
+func Megabytes(value uint64) *pb.Memory { return &pb.Memory{Value: value, Unit: pb.Memory_MEGABYTE} }
+func Gigabytes(value uint64) *pb.Memory { return &pb.Memory{Value: value, Unit: pb.Memory_GIGABYTE} }
+func Terabytes(value uint64) *pb.Memory { return &pb.Memory{Value: value, Unit: pb.Memory_TERABYTE} }
+func Kibibytes(value uint64) *pb.Memory { return &pb.Memory{Value: value, Unit: pb.Memory_KIBIBYTE} }
+func Mebibytes(value uint64) *pb.Memory { return &pb.Memory{Value: value, Unit: pb.Memory_MEBIBYTE} }
+func Gibibytes(value uint64) *pb.Memory { return &pb.Memory{Value: value, Unit: pb.Memory_GIBIBYTE} }
+func Tebibytes(value uint64) *pb.Memory { return &pb.Memory{Value: value, Unit: pb.Memory_TEBIBYTE} }
+
+// Validate returns ErrInvalid if memory is nil and ErrUnknownUnit if its unit
+// is not supported
+func Validate(memory *pb.Memory) error {
+	if memory == nil {
+		return fmt.Errorf("%w: memory is not set", ErrInvalid)
+	}
+
+	if _, ok := units[memory.GetUnit()]; !ok {
+		return fmt.Errorf("%w: %v", ErrUnknownUnit, memory.GetUnit())
+	}
+
+	return nil
+}
+
+// SizeOf returns the exact number of bits of memory
+func SizeOf(memory *pb.Memory) (Size, error) {
+	if err := Validate(memory); err != nil {
+		return Size{}, err
+	}
+
+	hi, lo := bits.Mul64(memory.GetValue(), units[memory.GetUnit()].bits)
+	return Size{hi, lo}, nil
+}
+
+func (size Size) Cmp(other Size) int {
+	switch {
+	case size.hi < other.hi:
+		return -1
+	case size.hi > other.hi:
+		return 1
+	case size.lo < other.lo:
+		return -1
+	case size.lo > other.lo:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func (size Size) Add(other Size) (Size, error) {
+	lo, carry := bits.Add64(size.lo, other.lo, 0)
+	hi, carry := bits.Add64(size.hi, other.hi, carry)
+	if carry != 0 {
+		return Size{}, ErrOverflow
+	}
+
+	return Size{hi, lo}, nil
+}
+
+// In returns the size expressed in the given unit, which may be fractional
+func (size Size) In(unit pb.Memory_Unit) (float64, error) {
+	info, ok := units[unit]
+	if !ok {
+		return 0, fmt.Errorf("%w: %v", ErrUnknownUnit, unit)
+	}
+
+	value, _ := new(big.Rat).SetFrac(size.bigInt(), new(big.Int).SetUint64(info.bits)).Float64()
+	return value, nil
+}
+
+func (size Size) bigInt() *big.Int {
+	value := new(big.Int).SetUint64(size.hi)
+	value.Lsh(value, 64)
+	return value.Or(value, new(big.Int).SetUint64(size.lo))
+}
+
+// Compare returns -1, 0 or 1 depending on whether a is smaller than, equal to
+// or larger than b
+func Compare(a, b *pb.Memory) (int, error) {
+	sizeA, err := SizeOf(a)
+	if err != nil {
+		return 0, err
+	}
+
+	sizeB, err := SizeOf(b)
+	if err != nil {
+		return 0, err
+	}
+
+	return sizeA.Cmp(sizeB), nil
+}
+
+// Add returns a+b, normalized to the unit family of a
+func Add(a, b *pb.Memory) (*pb.Memory, error) {
+	sizeA, err := SizeOf(a)
+	if err != nil {
+		return nil, err
+	}
+
+	sizeB, err := SizeOf(b)
+	if err != nil {
+		return nil, err
+	}
+
+	sum, err := sizeA.Add(sizeB)
+	if err != nil {
+		return nil, err
+	}
+
+	return fromSize(sum, familyOf(a.GetUnit()))
+}
+
+// Normalize returns memory in the largest unit of the same family (decimal or
+// binary) that represents it exactly, e.g. 2048MiB becomes 2GiB
+func Normalize(memory *pb.Memory) (*pb.Memory, error) {
+	size, err := SizeOf(memory)
+	if err != nil {
+		return nil, err
+	}
+
+	return fromSize(size, familyOf(memory.GetUnit()))
+}
+
+// In returns memory expressed in the given unit, which may be fractional
+func In(memory *pb.Memory, unit pb.Memory_Unit) (float64, error) {
+	size, err := SizeOf(memory)
+	if err != nil {
+		return 0, err
+	}
+
+	return size.In(unit)
+}
+
+func familyOf(unit pb.Memory_Unit) []pb.Memory_Unit {
+	switch unit {
+	case pb.Memory_KIBIBYTE, pb.Memory_MEBIBYTE, pb.Memory_GIBIBYTE, pb.Memory_TEBIBYTE:
+		return binaryUnits
+	default:
+		return decimalUnits
+	}
+}
+
+func fromSize(size Size, family []pb.Memory_Unit) (*pb.Memory, error) {
+	for _, unit := range family {
+		factor := units[unit].bits
+		if size.hi >= factor {
+			continue
+		}
+
+		value, remainder := bits.Div64(size.hi, size.lo, factor)
+		if remainder == 0 {
+			return &pb.Memory{Value: value, Unit: unit}, nil
+		}
+	}
+
+	return nil, ErrOverflow
+}
+
+// Format returns a human-readable representation such as "16GB" or "512GiB"
+func Format(memory *pb.Memory) string {
+	info, ok := units[memory.GetUnit()]
+	if !ok {
+		return fmt.Sprintf("%d %v", memory.GetValue(), memory.GetUnit())
+	}
+
+	return fmt.Sprintf("%d%s", memory.GetValue(), info.symbol)
+}
+
+// Parse reads a human-readable size such as "16GB", "512 GiB" or "1.5TB".
+// Fractional values are converted to the largest smaller unit of the same
+// family that represents them exactly.
+func Parse(text string) (*pb.Memory, error) {
+	text = strings.TrimSpace(text)
+	split := strings.IndexFunc(text, func(r rune) bool {
+		return !unicode.IsDigit(r) && r != '.'
+	})
+	if split <= 0 {
+		return nil, fmt.Errorf("%w: %q", ErrInvalid, text)
+	}
+
+	number := text[:split]
+	unit, err := parseUnit(strings.TrimSpace(text[split:]))
+	if err != nil {
+		return nil, err
+	}
+
+	value, ok := new(big.Rat).SetString(number)
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrInvalid, text)
+	}
+
+	bitCount := value.Mul(value, new(big.Rat).SetInt(new(big.Int).SetUint64(units[unit].bits)))
+	if !bitCount.IsInt() {
+		return nil, fmt.Errorf("%w: %q is not a whole number of bits", ErrInvalid, text)
+	}
+
+	total := bitCount.Num()
+	if total.BitLen() > 128 {
+		return nil, ErrOverflow
+	}
+
+	size := Size{
+		hi: new(big.Int).Rsh(total, 64).Uint64(),
+		lo: new(big.Int).And(total, new(big.Int).SetUint64(^uint64(0))).Uint64(),
+	}
+
+	if value, remainder := new(big.Int).QuoRem(total, new(big.Int).SetUint64(units[unit].bits), new(big.Int)); remainder.Sign() == 0 && value.IsUint64() {
+		return &pb.Memory{Value: value.Uint64(), Unit: unit}, nil
+	}
+
+	return fromSize(size, familyOf(unit))
+}
+
+func parseUnit(symbol string) (pb.Memory_Unit, error) {
+	switch symbol {
+	case "b", "bit", "bits":
+		return pb.Memory_BIT, nil
+	case "B", "byte", "bytes":
+		return pb.Memory_BYTE, nil
+	}
+
+	for unit, info := range units {
+		if unit != pb.Memory_BIT && unit != pb.Memory_BYTE && strings.EqualFold(symbol, info.symbol) {
+			return unit, nil
+		}
+	}
+
+	return pb.Memory_UNKNOWN, fmt.Errorf("%w: %q", ErrUnknownUnit, symbol)
+}
@@ -0,0 +1,122 @@
+package memory
+
+import (
+	"testing"
+
+	pb "github.com/orkhanrustamli/pcbook/pcbook_proto/go"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestCompare(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name string
+		a    *pb.Memory
+		b    *pb.Memory
+		cmp  int
+	}{
+		{"same_unit", Gigabytes(8), Gigabytes(16), -1},
+		{"mixed_units", Megabytes(8000), Gigabytes(8), 0},
+		{"decimal_vs_binary", Gigabytes(1), Gibibytes(1), -1},
+		{"bits_vs_bytes", Bits(16), Bytes(2), 0},
+		{"huge_terabytes", Terabytes(3_000_000), Gigabytes(8), 1},
+		{"max_value", Tebibytes(^uint64(0)), Terabytes(^uint64(0)), 1},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			cmp, err := Compare(tc.a, tc.b)
+			require.NoError(t, err)
+			require.Equal(t, tc.cmp, cmp)
+
+			cmp, err = Compare(tc.b, tc.a)
+			require.NoError(t, err)
+			require.Equal(t, -tc.cmp, cmp)
+		})
+	}
+}
+
+func TestUnknownUnit(t *testing.T) {
+	t.Parallel()
+
+	unknown := &pb.Memory{Value: 8}
+
+	_, err := Compare(unknown, Gigabytes(8))
+	require.ErrorIs(t, err, ErrUnknownUnit)
+
+	_, err = Add(Gigabytes(8), unknown)
+	require.ErrorIs(t, err, ErrUnknownUnit)
+
+	_, err = Normalize(&pb.Memory{Value: 8, Unit: pb.Memory_Unit(42)})
+	require.ErrorIs(t, err, ErrUnknownUnit)
+
+	_, err = Parse("8 XB")
+	require.ErrorIs(t, err, ErrUnknownUnit)
+}
+
+func TestAddAndNormalize(t *testing.T) {
+	t.Parallel()
+
+	sum, err := Add(Gigabytes(1), Megabytes(500))
+	require.NoError(t, err)
+	require.True(t, proto.Equal(Megabytes(1500), sum))
+
+	sum, err = Add(Gibibytes(1), Gibibytes(1))
+	require.NoError(t, err)
+	require.True(t, proto.Equal(Gibibytes(2), sum))
+
+	sum, err = Add(Terabytes(^uint64(0)), Terabytes(1))
+	require.ErrorIs(t, err, ErrOverflow)
+	require.Nil(t, sum)
+
+	normalized, err := Normalize(Mebibytes(2048))
+	require.NoError(t, err)
+	require.True(t, proto.Equal(Gibibytes(2), normalized))
+
+	normalized, err = Normalize(Bits(16000))
+	require.NoError(t, err)
+	require.True(t, proto.Equal(Kilobytes(2), normalized))
+
+	normalized, err = Normalize(Bits(3))
+	require.NoError(t, err)
+	require.True(t, proto.Equal(Bits(3), normalized))
+}
+
+func TestFormatAndParse(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		text   string
+		memory *pb.Memory
+		format string
+	}{
+		{"16GB", Gigabytes(16), "16GB"},
+		{"512 GiB", Gibibytes(512), "512GiB"},
+		{"1.5TB", Gigabytes(1500), "1500GB"},
+		{"0.5 gib", Mebibytes(512), "512MiB"},
+		{"64b", Bits(64), "64bit"},
+		{"64 B", Bytes(64), "64B"},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.text, func(t *testing.T) {
+			t.Parallel()
+
+			memory, err := Parse(tc.text)
+			require.NoError(t, err)
+			require.True(t, proto.Equal(tc.memory, memory), "got %v", memory)
+			require.Equal(t, tc.format, Format(memory))
+		})
+	}
+
+	for _, text := range []string{"", "GB", "1.2.3GB", "0.1bit"} {
+		_, err := Parse(text)
+		require.Error(t, err, text)
+	}
+}
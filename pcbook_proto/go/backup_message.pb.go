@@ -0,0 +1,517 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.26.0
+// 	protoc        v3.19.4
+// source: backup_message.proto
+
+package pcbook
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type BackupHeader struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Version   uint32                 `protobuf:"varint,1,opt,name=version,proto3" json:"version,omitempty"`
+	CreatedAt *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+}
+
+func (x *BackupHeader) Reset() {
+	*x = BackupHeader{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_backup_message_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *BackupHeader) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BackupHeader) ProtoMessage() {}
+
+func (x *BackupHeader) ProtoReflect() protoreflect.Message {
+	mi := &file_backup_message_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BackupHeader.ProtoReflect.Descriptor instead.
+func (*BackupHeader) Descriptor() ([]byte, []int) {
+	return file_backup_message_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *BackupHeader) GetVersion() uint32 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *BackupHeader) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+type BackupRating struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	LaptopId string        `protobuf:"bytes,1,opt,name=laptop_id,json=laptopId,proto3" json:"laptop_id,omitempty"`
+	Rating   *RatingRecord `protobuf:"bytes,2,opt,name=rating,proto3" json:"rating,omitempty"`
+}
+
+func (x *BackupRating) Reset() {
+	*x = BackupRating{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_backup_message_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *BackupRating) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BackupRating) ProtoMessage() {}
+
+func (x *BackupRating) ProtoReflect() protoreflect.Message {
+	mi := &file_backup_message_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BackupRating.ProtoReflect.Descriptor instead.
+func (*BackupRating) Descriptor() ([]byte, []int) {
+	return file_backup_message_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *BackupRating) GetLaptopId() string {
+	if x != nil {
+		return x.LaptopId
+	}
+	return ""
+}
+
+func (x *BackupRating) GetRating() *RatingRecord {
+	if x != nil {
+		return x.Rating
+	}
+	return nil
+}
+
+type BackupTrailer struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Records uint64 `protobuf:"varint,1,opt,name=records,proto3" json:"records,omitempty"`
+	Sha256  []byte `protobuf:"bytes,2,opt,name=sha256,proto3" json:"sha256,omitempty"`
+}
+
+func (x *BackupTrailer) Reset() {
+	*x = BackupTrailer{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_backup_message_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *BackupTrailer) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BackupTrailer) ProtoMessage() {}
+
+func (x *BackupTrailer) ProtoReflect() protoreflect.Message {
+	mi := &file_backup_message_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BackupTrailer.ProtoReflect.Descriptor instead.
+func (*BackupTrailer) Descriptor() ([]byte, []int) {
+	return file_backup_message_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *BackupTrailer) GetRecords() uint64 {
+	if x != nil {
+		return x.Records
+	}
+	return 0
+}
+
+func (x *BackupTrailer) GetSha256() []byte {
+	if x != nil {
+		return x.Sha256
+	}
+	return nil
+}
+
+// An archive is a header, then laptops, ratings, users and images in that
+// order, and a trailer. Every image record is followed by its data as image
+// chunks.
+type BackupRecord struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Types that are assignable to Record:
+	//	*BackupRecord_Header
+	//	*BackupRecord_Laptop
+	//	*BackupRecord_Rating
+	//	*BackupRecord_User
+	//	*BackupRecord_Image
+	//	*BackupRecord_ImageChunk
+	//	*BackupRecord_Trailer
+	Record isBackupRecord_Record `protobuf_oneof:"record"`
+}
+
+func (x *BackupRecord) Reset() {
+	*x = BackupRecord{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_backup_message_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *BackupRecord) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BackupRecord) ProtoMessage() {}
+
+func (x *BackupRecord) ProtoReflect() protoreflect.Message {
+	mi := &file_backup_message_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BackupRecord.ProtoReflect.Descriptor instead.
+func (*BackupRecord) Descriptor() ([]byte, []int) {
+	return file_backup_message_proto_rawDescGZIP(), []int{3}
+}
+
+func (m *BackupRecord) GetRecord() isBackupRecord_Record {
+	if m != nil {
+		return m.Record
+	}
+	return nil
+}
+
+func (x *BackupRecord) GetHeader() *BackupHeader {
+	if x, ok := x.GetRecord().(*BackupRecord_Header); ok {
+		return x.Header
+	}
+	return nil
+}
+
+func (x *BackupRecord) GetLaptop() *Laptop {
+	if x, ok := x.GetRecord().(*BackupRecord_Laptop); ok {
+		return x.Laptop
+	}
+	return nil
+}
+
+func (x *BackupRecord) GetRating() *BackupRating {
+	if x, ok := x.GetRecord().(*BackupRecord_Rating); ok {
+		return x.Rating
+	}
+	return nil
+}
+
+func (x *BackupRecord) GetUser() *UserRecord {
+	if x, ok := x.GetRecord().(*BackupRecord_User); ok {
+		return x.User
+	}
+	return nil
+}
+
+func (x *BackupRecord) GetImage() *ImageRecord {
+	if x, ok := x.GetRecord().(*BackupRecord_Image); ok {
+		return x.Image
+	}
+	return nil
+}
+
+func (x *BackupRecord) GetImageChunk() []byte {
+	if x, ok := x.GetRecord().(*BackupRecord_ImageChunk); ok {
+		return x.ImageChunk
+	}
+	return nil
+}
+
+func (x *BackupRecord) GetTrailer() *BackupTrailer {
+	if x, ok := x.GetRecord().(*BackupRecord_Trailer); ok {
+		return x.Trailer
+	}
+	return nil
+}
+
+type isBackupRecord_Record interface {
+	isBackupRecord_Record()
+}
+
+type BackupRecord_Header struct {
+	Header *BackupHeader `protobuf:"bytes,1,opt,name=header,proto3,oneof"`
+}
+
+type BackupRecord_Laptop struct {
+	Laptop *Laptop `protobuf:"bytes,2,opt,name=laptop,proto3,oneof"`
+}
+
+type BackupRecord_Rating struct {
+	Rating *BackupRating `protobuf:"bytes,3,opt,name=rating,proto3,oneof"`
+}
+
+type BackupRecord_User struct {
+	User *UserRecord `protobuf:"bytes,4,opt,name=user,proto3,oneof"`
+}
+
+type BackupRecord_Image struct {
+	Image *ImageRecord `protobuf:"bytes,5,opt,name=image,proto3,oneof"`
+}
+
+type BackupRecord_ImageChunk struct {
+	ImageChunk []byte `protobuf:"bytes,6,opt,name=image_chunk,json=imageChunk,proto3,oneof"`
+}
+
+type BackupRecord_Trailer struct {
+	Trailer *BackupTrailer `protobuf:"bytes,7,opt,name=trailer,proto3,oneof"`
+}
+
+func (*BackupRecord_Header) isBackupRecord_Record() {}
+
+func (*BackupRecord_Laptop) isBackupRecord_Record() {}
+
+func (*BackupRecord_Rating) isBackupRecord_Record() {}
+
+func (*BackupRecord_User) isBackupRecord_Record() {}
+
+func (*BackupRecord_Image) isBackupRecord_Record() {}
+
+func (*BackupRecord_ImageChunk) isBackupRecord_Record() {}
+
+func (*BackupRecord_Trailer) isBackupRecord_Record() {}
+
+var File_backup_message_proto protoreflect.FileDescriptor
+
+var file_backup_message_proto_rawDesc = []byte{
+	0x0a, 0x14, 0x62, 0x61, 0x63, 0x6b, 0x75, 0x70, 0x5f, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65,
+	0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x06, 0x70, 0x63, 0x62, 0x6f, 0x6f, 0x6b, 0x1a, 0x14,
+	0x6c, 0x61, 0x70, 0x74, 0x6f, 0x70, 0x5f, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x2e, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x1a, 0x13, 0x73, 0x74, 0x6f, 0x72, 0x65, 0x5f, 0x6d, 0x65, 0x73, 0x73,
+	0x61, 0x67, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x1a, 0x1f, 0x67, 0x6f, 0x6f, 0x67, 0x6c,
+	0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2f, 0x74, 0x69, 0x6d, 0x65, 0x73,
+	0x74, 0x61, 0x6d, 0x70, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0x63, 0x0a, 0x0c, 0x42, 0x61,
+	0x63, 0x6b, 0x75, 0x70, 0x48, 0x65, 0x61, 0x64, 0x65, 0x72, 0x12, 0x18, 0x0a, 0x07, 0x76, 0x65,
+	0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x07, 0x76, 0x65, 0x72,
+	0x73, 0x69, 0x6f, 0x6e, 0x12, 0x39, 0x0a, 0x0a, 0x63, 0x72, 0x65, 0x61, 0x74, 0x65, 0x64, 0x5f,
+	0x61, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c,
+	0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x54, 0x69, 0x6d, 0x65, 0x73,
+	0x74, 0x61, 0x6d, 0x70, 0x52, 0x09, 0x63, 0x72, 0x65, 0x61, 0x74, 0x65, 0x64, 0x41, 0x74, 0x22,
+	0x59, 0x0a, 0x0c, 0x42, 0x61, 0x63, 0x6b, 0x75, 0x70, 0x52, 0x61, 0x74, 0x69, 0x6e, 0x67, 0x12,
+	0x1b, 0x0a, 0x09, 0x6c, 0x61, 0x70, 0x74, 0x6f, 0x70, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x08, 0x6c, 0x61, 0x70, 0x74, 0x6f, 0x70, 0x49, 0x64, 0x12, 0x2c, 0x0a, 0x06,
+	0x72, 0x61, 0x74, 0x69, 0x6e, 0x67, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x14, 0x2e, 0x70,
+	0x63, 0x62, 0x6f, 0x6f, 0x6b, 0x2e, 0x52, 0x61, 0x74, 0x69, 0x6e, 0x67, 0x52, 0x65, 0x63, 0x6f,
+	0x72, 0x64, 0x52, 0x06, 0x72, 0x61, 0x74, 0x69, 0x6e, 0x67, 0x22, 0x41, 0x0a, 0x0d, 0x42, 0x61,
+	0x63, 0x6b, 0x75, 0x70, 0x54, 0x72, 0x61, 0x69, 0x6c, 0x65, 0x72, 0x12, 0x18, 0x0a, 0x07, 0x72,
+	0x65, 0x63, 0x6f, 0x72, 0x64, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x04, 0x52, 0x07, 0x72, 0x65,
+	0x63, 0x6f, 0x72, 0x64, 0x73, 0x12, 0x16, 0x0a, 0x06, 0x73, 0x68, 0x61, 0x32, 0x35, 0x36, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x06, 0x73, 0x68, 0x61, 0x32, 0x35, 0x36, 0x22, 0xcf, 0x02,
+	0x0a, 0x0c, 0x42, 0x61, 0x63, 0x6b, 0x75, 0x70, 0x52, 0x65, 0x63, 0x6f, 0x72, 0x64, 0x12, 0x2e,
+	0x0a, 0x06, 0x68, 0x65, 0x61, 0x64, 0x65, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x14,
+	0x2e, 0x70, 0x63, 0x62, 0x6f, 0x6f, 0x6b, 0x2e, 0x42, 0x61, 0x63, 0x6b, 0x75, 0x70, 0x48, 0x65,
+	0x61, 0x64, 0x65, 0x72, 0x48, 0x00, 0x52, 0x06, 0x68, 0x65, 0x61, 0x64, 0x65, 0x72, 0x12, 0x28,
+	0x0a, 0x06, 0x6c, 0x61, 0x70, 0x74, 0x6f, 0x70, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0e,
+	0x2e, 0x70, 0x63, 0x62, 0x6f, 0x6f, 0x6b, 0x2e, 0x4c, 0x61, 0x70, 0x74, 0x6f, 0x70, 0x48, 0x00,
+	0x52, 0x06, 0x6c, 0x61, 0x70, 0x74, 0x6f, 0x70, 0x12, 0x2e, 0x0a, 0x06, 0x72, 0x61, 0x74, 0x69,
+	0x6e, 0x67, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x14, 0x2e, 0x70, 0x63, 0x62, 0x6f, 0x6f,
+	0x6b, 0x2e, 0x42, 0x61, 0x63, 0x6b, 0x75, 0x70, 0x52, 0x61, 0x74, 0x69, 0x6e, 0x67, 0x48, 0x00,
+	0x52, 0x06, 0x72, 0x61, 0x74, 0x69, 0x6e, 0x67, 0x12, 0x28, 0x0a, 0x04, 0x75, 0x73, 0x65, 0x72,
+	0x18, 0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x12, 0x2e, 0x70, 0x63, 0x62, 0x6f, 0x6f, 0x6b, 0x2e,
+	0x55, 0x73, 0x65, 0x72, 0x52, 0x65, 0x63, 0x6f, 0x72, 0x64, 0x48, 0x00, 0x52, 0x04, 0x75, 0x73,
+	0x65, 0x72, 0x12, 0x2b, 0x0a, 0x05, 0x69, 0x6d, 0x61, 0x67, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28,
+	0x0b, 0x32, 0x13, 0x2e, 0x70, 0x63, 0x62, 0x6f, 0x6f, 0x6b, 0x2e, 0x49, 0x6d, 0x61, 0x67, 0x65,
+	0x52, 0x65, 0x63, 0x6f, 0x72, 0x64, 0x48, 0x00, 0x52, 0x05, 0x69, 0x6d, 0x61, 0x67, 0x65, 0x12,
+	0x21, 0x0a, 0x0b, 0x69, 0x6d, 0x61, 0x67, 0x65, 0x5f, 0x63, 0x68, 0x75, 0x6e, 0x6b, 0x18, 0x06,
+	0x20, 0x01, 0x28, 0x0c, 0x48, 0x00, 0x52, 0x0a, 0x69, 0x6d, 0x61, 0x67, 0x65, 0x43, 0x68, 0x75,
+	0x6e, 0x6b, 0x12, 0x31, 0x0a, 0x07, 0x74, 0x72, 0x61, 0x69, 0x6c, 0x65, 0x72, 0x18, 0x07, 0x20,
+	0x01, 0x28, 0x0b, 0x32, 0x15, 0x2e, 0x70, 0x63, 0x62, 0x6f, 0x6f, 0x6b, 0x2e, 0x42, 0x61, 0x63,
+	0x6b, 0x75, 0x70, 0x54, 0x72, 0x61, 0x69, 0x6c, 0x65, 0x72, 0x48, 0x00, 0x52, 0x07, 0x74, 0x72,
+	0x61, 0x69, 0x6c, 0x65, 0x72, 0x42, 0x08, 0x0a, 0x06, 0x72, 0x65, 0x63, 0x6f, 0x72, 0x64, 0x42,
+	0x0b, 0x5a, 0x09, 0x2e, 0x2f, 0x3b, 0x70, 0x63, 0x62, 0x6f, 0x6f, 0x6b, 0x62, 0x06, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_backup_message_proto_rawDescOnce sync.Once
+	file_backup_message_proto_rawDescData = file_backup_message_proto_rawDesc
+)
+
+func file_backup_message_proto_rawDescGZIP() []byte {
+	file_backup_message_proto_rawDescOnce.Do(func() {
+		file_backup_message_proto_rawDescData = protoimpl.X.CompressGZIP(file_backup_message_proto_rawDescData)
+	})
+	return file_backup_message_proto_rawDescData
+}
+
+var file_backup_message_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
+var file_backup_message_proto_goTypes = []interface{}{
+	(*BackupHeader)(nil),          // 0: pcbook.BackupHeader
+	(*BackupRating)(nil),          // 1: pcbook.BackupRating
+	(*BackupTrailer)(nil),         // 2: pcbook.BackupTrailer
+	(*BackupRecord)(nil),          // 3: pcbook.BackupRecord
+	(*timestamppb.Timestamp)(nil), // 4: google.protobuf.Timestamp
+	(*RatingRecord)(nil),          // 5: pcbook.RatingRecord
+	(*Laptop)(nil),                // 6: pcbook.Laptop
+	(*UserRecord)(nil),            // 7: pcbook.UserRecord
+	(*ImageRecord)(nil),           // 8: pcbook.ImageRecord
+}
+var file_backup_message_proto_depIdxs = []int32{
+	4, // 0: pcbook.BackupHeader.created_at:type_name -> google.protobuf.Timestamp
+	5, // 1: pcbook.BackupRating.rating:type_name -> pcbook.RatingRecord
+	0, // 2: pcbook.BackupRecord.header:type_name -> pcbook.BackupHeader
+	6, // 3: pcbook.BackupRecord.laptop:type_name -> pcbook.Laptop
+	1, // 4: pcbook.BackupRecord.rating:type_name -> pcbook.BackupRating
+	7, // 5: pcbook.BackupRecord.user:type_name -> pcbook.UserRecord
+	8, // 6: pcbook.BackupRecord.image:type_name -> pcbook.ImageRecord
+	2, // 7: pcbook.BackupRecord.trailer:type_name -> pcbook.BackupTrailer
+	8, // [8:8] is the sub-list for method output_type
+	8, // [8:8] is the sub-list for method input_type
+	8, // [8:8] is the sub-list for extension type_name
+	8, // [8:8] is the sub-list for extension extendee
+	0, // [0:8] is the sub-list for field type_name
+}
+
+func init() { file_backup_message_proto_init() }
+func file_backup_message_proto_init() {
+	if File_backup_message_proto != nil {
+		return
+	}
+	file_laptop_message_proto_init()
+	file_store_message_proto_init()
+	if !protoimpl.UnsafeEnabled {
+		file_backup_message_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*BackupHeader); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_backup_message_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*BackupRating); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_backup_message_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*BackupTrailer); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_backup_message_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*BackupRecord); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	file_backup_message_proto_msgTypes[3].OneofWrappers = []interface{}{
+		(*BackupRecord_Header)(nil),
+		(*BackupRecord_Laptop)(nil),
+		(*BackupRecord_Rating)(nil),
+		(*BackupRecord_User)(nil),
+		(*BackupRecord_Image)(nil),
+		(*BackupRecord_ImageChunk)(nil),
+		(*BackupRecord_Trailer)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_backup_message_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   4,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_backup_message_proto_goTypes,
+		DependencyIndexes: file_backup_message_proto_depIdxs,
+		MessageInfos:      file_backup_message_proto_msgTypes,
+	}.Build()
+	File_backup_message_proto = out.File
+	file_backup_message_proto_rawDesc = nil
+	file_backup_message_proto_goTypes = nil
+	file_backup_message_proto_depIdxs = nil
+}
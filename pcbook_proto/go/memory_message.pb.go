@@ -30,18 +30,26 @@ const (
 	Memory_MEGABYTE Memory_Unit = 4
 	Memory_GIGABYTE Memory_Unit = 5
 	Memory_TERABYTE Memory_Unit = 6
+	Memory_KIBIBYTE Memory_Unit = 7
+	Memory_MEBIBYTE Memory_Unit = 8
+	Memory_GIBIBYTE Memory_Unit = 9
+	Memory_TEBIBYTE Memory_Unit = 10
 )
 
 // Enum value maps for Memory_Unit.
 var (
 	Memory_Unit_name = map[int32]string{
-		0: "UNKNOWN",
-		1: "BIT",
-		2: "BYTE",
-		3: "KILOBYTE",
-		4: "MEGABYTE",
-		5: "GIGABYTE",
-		6: "TERABYTE",
+		0:  "UNKNOWN",
+		1:  "BIT",
+		2:  "BYTE",
+		3:  "KILOBYTE",
+		4:  "MEGABYTE",
+		5:  "GIGABYTE",
+		6:  "TERABYTE",
+		7:  "KIBIBYTE",
+		8:  "MEBIBYTE",
+		9:  "GIBIBYTE",
+		10: "TEBIBYTE",
 	}
 	Memory_Unit_value = map[string]int32{
 		"UNKNOWN":  0,
@@ -51,6 +59,10 @@ var (
 		"MEGABYTE": 4,
 		"GIGABYTE": 5,
 		"TERABYTE": 6,
+		"KIBIBYTE": 7,
+		"MEBIBYTE": 8,
+		"GIBIBYTE": 9,
+		"TEBIBYTE": 10,
 	}
 )
 
@@ -140,19 +152,23 @@ var File_memory_message_proto protoreflect.FileDescriptor
 
 var file_memory_message_proto_rawDesc = []byte{
 	0x0a, 0x14, 0x6d, 0x65, 0x6d, 0x6f, 0x72, 0x79, 0x5f, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65,
-	0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x06, 0x70, 0x63, 0x62, 0x6f, 0x6f, 0x6b, 0x22, 0xa7,
+	0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x06, 0x70, 0x63, 0x62, 0x6f, 0x6f, 0x6b, 0x22, 0xe0,
 	0x01, 0x0a, 0x06, 0x4d, 0x65, 0x6d, 0x6f, 0x72, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c,
 	0x75, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x04, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x12,
 	0x27, 0x0a, 0x04, 0x75, 0x6e, 0x69, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x13, 0x2e,
 	0x70, 0x63, 0x62, 0x6f, 0x6f, 0x6b, 0x2e, 0x4d, 0x65, 0x6d, 0x6f, 0x72, 0x79, 0x2e, 0x55, 0x6e,
-	0x69, 0x74, 0x52, 0x04, 0x75, 0x6e, 0x69, 0x74, 0x22, 0x5e, 0x0a, 0x04, 0x55, 0x6e, 0x69, 0x74,
-	0x12, 0x0b, 0x0a, 0x07, 0x55, 0x4e, 0x4b, 0x4e, 0x4f, 0x57, 0x4e, 0x10, 0x00, 0x12, 0x07, 0x0a,
-	0x03, 0x42, 0x49, 0x54, 0x10, 0x01, 0x12, 0x08, 0x0a, 0x04, 0x42, 0x59, 0x54, 0x45, 0x10, 0x02,
-	0x12, 0x0c, 0x0a, 0x08, 0x4b, 0x49, 0x4c, 0x4f, 0x42, 0x59, 0x54, 0x45, 0x10, 0x03, 0x12, 0x0c,
-	0x0a, 0x08, 0x4d, 0x45, 0x47, 0x41, 0x42, 0x59, 0x54, 0x45, 0x10, 0x04, 0x12, 0x0c, 0x0a, 0x08,
-	0x47, 0x49, 0x47, 0x41, 0x42, 0x59, 0x54, 0x45, 0x10, 0x05, 0x12, 0x0c, 0x0a, 0x08, 0x54, 0x45,
-	0x52, 0x41, 0x42, 0x59, 0x54, 0x45, 0x10, 0x06, 0x42, 0x0b, 0x5a, 0x09, 0x2e, 0x2f, 0x3b, 0x70,
-	0x63, 0x62, 0x6f, 0x6f, 0x6b, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x69, 0x74, 0x52, 0x04, 0x75, 0x6e, 0x69, 0x74, 0x22, 0x96, 0x01, 0x0a, 0x04, 0x55, 0x6e, 0x69,
+	0x74, 0x12, 0x0b, 0x0a, 0x07, 0x55, 0x4e, 0x4b, 0x4e, 0x4f, 0x57, 0x4e, 0x10, 0x00, 0x12, 0x07,
+	0x0a, 0x03, 0x42, 0x49, 0x54, 0x10, 0x01, 0x12, 0x08, 0x0a, 0x04, 0x42, 0x59, 0x54, 0x45, 0x10,
+	0x02, 0x12, 0x0c, 0x0a, 0x08, 0x4b, 0x49, 0x4c, 0x4f, 0x42, 0x59, 0x54, 0x45, 0x10, 0x03, 0x12,
+	0x0c, 0x0a, 0x08, 0x4d, 0x45, 0x47, 0x41, 0x42, 0x59, 0x54, 0x45, 0x10, 0x04, 0x12, 0x0c, 0x0a,
+	0x08, 0x47, 0x49, 0x47, 0x41, 0x42, 0x59, 0x54, 0x45, 0x10, 0x05, 0x12, 0x0c, 0x0a, 0x08, 0x54,
+	0x45, 0x52, 0x41, 0x42, 0x59, 0x54, 0x45, 0x10, 0x06, 0x12, 0x0c, 0x0a, 0x08, 0x4b, 0x49, 0x42,
+	0x49, 0x42, 0x59, 0x54, 0x45, 0x10, 0x07, 0x12, 0x0c, 0x0a, 0x08, 0x4d, 0x45, 0x42, 0x49, 0x42,
+	0x59, 0x54, 0x45, 0x10, 0x08, 0x12, 0x0c, 0x0a, 0x08, 0x47, 0x49, 0x42, 0x49, 0x42, 0x59, 0x54,
+	0x45, 0x10, 0x09, 0x12, 0x0c, 0x0a, 0x08, 0x54, 0x45, 0x42, 0x49, 0x42, 0x59, 0x54, 0x45, 0x10,
+	0x0a, 0x42, 0x0b, 0x5a, 0x09, 0x2e, 0x2f, 0x3b, 0x70, 0x63, 0x62, 0x6f, 0x6f, 0x6b, 0x62, 0x06,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
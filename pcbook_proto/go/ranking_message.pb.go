@@ -0,0 +1,188 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.26.0
+// 	protoc        v3.19.4
+// source: ranking_message.proto
+
+package pcbook
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type RankingOptions struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	SpecMatchWeight        float64 `protobuf:"fixed64,1,opt,name=spec_match_weight,json=specMatchWeight,proto3" json:"spec_match_weight,omitempty"`
+	AverageRatingWeight    float64 `protobuf:"fixed64,2,opt,name=average_rating_weight,json=averageRatingWeight,proto3" json:"average_rating_weight,omitempty"`
+	RatingCountWeight      float64 `protobuf:"fixed64,3,opt,name=rating_count_weight,json=ratingCountWeight,proto3" json:"rating_count_weight,omitempty"`
+	PricePerformanceWeight float64 `protobuf:"fixed64,4,opt,name=price_performance_weight,json=pricePerformanceWeight,proto3" json:"price_performance_weight,omitempty"`
+	RecencyWeight          float64 `protobuf:"fixed64,5,opt,name=recency_weight,json=recencyWeight,proto3" json:"recency_weight,omitempty"`
+}
+
+func (x *RankingOptions) Reset() {
+	*x = RankingOptions{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_ranking_message_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *RankingOptions) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RankingOptions) ProtoMessage() {}
+
+func (x *RankingOptions) ProtoReflect() protoreflect.Message {
+	mi := &file_ranking_message_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RankingOptions.ProtoReflect.Descriptor instead.
+func (*RankingOptions) Descriptor() ([]byte, []int) {
+	return file_ranking_message_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *RankingOptions) GetSpecMatchWeight() float64 {
+	if x != nil {
+		return x.SpecMatchWeight
+	}
+	return 0
+}
+
+func (x *RankingOptions) GetAverageRatingWeight() float64 {
+	if x != nil {
+		return x.AverageRatingWeight
+	}
+	return 0
+}
+
+func (x *RankingOptions) GetRatingCountWeight() float64 {
+	if x != nil {
+		return x.RatingCountWeight
+	}
+	return 0
+}
+
+func (x *RankingOptions) GetPricePerformanceWeight() float64 {
+	if x != nil {
+		return x.PricePerformanceWeight
+	}
+	return 0
+}
+
+func (x *RankingOptions) GetRecencyWeight() float64 {
+	if x != nil {
+		return x.RecencyWeight
+	}
+	return 0
+}
+
+var File_ranking_message_proto protoreflect.FileDescriptor
+
+var file_ranking_message_proto_rawDesc = []byte{
+	0x0a, 0x15, 0x72, 0x61, 0x6e, 0x6b, 0x69, 0x6e, 0x67, 0x5f, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67,
+	0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x06, 0x70, 0x63, 0x62, 0x6f, 0x6f, 0x6b, 0x22,
+	0x81, 0x02, 0x0a, 0x0e, 0x52, 0x61, 0x6e, 0x6b, 0x69, 0x6e, 0x67, 0x4f, 0x70, 0x74, 0x69, 0x6f,
+	0x6e, 0x73, 0x12, 0x2a, 0x0a, 0x11, 0x73, 0x70, 0x65, 0x63, 0x5f, 0x6d, 0x61, 0x74, 0x63, 0x68,
+	0x5f, 0x77, 0x65, 0x69, 0x67, 0x68, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x01, 0x52, 0x0f, 0x73,
+	0x70, 0x65, 0x63, 0x4d, 0x61, 0x74, 0x63, 0x68, 0x57, 0x65, 0x69, 0x67, 0x68, 0x74, 0x12, 0x32,
+	0x0a, 0x15, 0x61, 0x76, 0x65, 0x72, 0x61, 0x67, 0x65, 0x5f, 0x72, 0x61, 0x74, 0x69, 0x6e, 0x67,
+	0x5f, 0x77, 0x65, 0x69, 0x67, 0x68, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x01, 0x52, 0x13, 0x61,
+	0x76, 0x65, 0x72, 0x61, 0x67, 0x65, 0x52, 0x61, 0x74, 0x69, 0x6e, 0x67, 0x57, 0x65, 0x69, 0x67,
+	0x68, 0x74, 0x12, 0x2e, 0x0a, 0x13, 0x72, 0x61, 0x74, 0x69, 0x6e, 0x67, 0x5f, 0x63, 0x6f, 0x75,
+	0x6e, 0x74, 0x5f, 0x77, 0x65, 0x69, 0x67, 0x68, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x01, 0x52,
+	0x11, 0x72, 0x61, 0x74, 0x69, 0x6e, 0x67, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x57, 0x65, 0x69, 0x67,
+	0x68, 0x74, 0x12, 0x38, 0x0a, 0x18, 0x70, 0x72, 0x69, 0x63, 0x65, 0x5f, 0x70, 0x65, 0x72, 0x66,
+	0x6f, 0x72, 0x6d, 0x61, 0x6e, 0x63, 0x65, 0x5f, 0x77, 0x65, 0x69, 0x67, 0x68, 0x74, 0x18, 0x04,
+	0x20, 0x01, 0x28, 0x01, 0x52, 0x16, 0x70, 0x72, 0x69, 0x63, 0x65, 0x50, 0x65, 0x72, 0x66, 0x6f,
+	0x72, 0x6d, 0x61, 0x6e, 0x63, 0x65, 0x57, 0x65, 0x69, 0x67, 0x68, 0x74, 0x12, 0x25, 0x0a, 0x0e,
+	0x72, 0x65, 0x63, 0x65, 0x6e, 0x63, 0x79, 0x5f, 0x77, 0x65, 0x69, 0x67, 0x68, 0x74, 0x18, 0x05,
+	0x20, 0x01, 0x28, 0x01, 0x52, 0x0d, 0x72, 0x65, 0x63, 0x65, 0x6e, 0x63, 0x79, 0x57, 0x65, 0x69,
+	0x67, 0x68, 0x74, 0x42, 0x0b, 0x5a, 0x09, 0x2e, 0x2f, 0x3b, 0x70, 0x63, 0x62, 0x6f, 0x6f, 0x6b,
+	0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_ranking_message_proto_rawDescOnce sync.Once
+	file_ranking_message_proto_rawDescData = file_ranking_message_proto_rawDesc
+)
+
+func file_ranking_message_proto_rawDescGZIP() []byte {
+	file_ranking_message_proto_rawDescOnce.Do(func() {
+		file_ranking_message_proto_rawDescData = protoimpl.X.CompressGZIP(file_ranking_message_proto_rawDescData)
+	})
+	return file_ranking_message_proto_rawDescData
+}
+
+var file_ranking_message_proto_msgTypes = make([]protoimpl.MessageInfo, 1)
+var file_ranking_message_proto_goTypes = []interface{}{
+	(*RankingOptions)(nil), // 0: pcbook.RankingOptions
+}
+var file_ranking_message_proto_depIdxs = []int32{
+	0, // [0:0] is the sub-list for method output_type
+	0, // [0:0] is the sub-list for method input_type
+	0, // [0:0] is the sub-list for extension type_name
+	0, // [0:0] is the sub-list for extension extendee
+	0, // [0:0] is the sub-list for field type_name
+}
+
+func init() { file_ranking_message_proto_init() }
+func file_ranking_message_proto_init() {
+	if File_ranking_message_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_ranking_message_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*RankingOptions); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_ranking_message_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   1,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_ranking_message_proto_goTypes,
+		DependencyIndexes: file_ranking_message_proto_depIdxs,
+		MessageInfos:      file_ranking_message_proto_msgTypes,
+	}.Build()
+	File_ranking_message_proto = out.File
+	file_ranking_message_proto_rawDesc = nil
+	file_ranking_message_proto_goTypes = nil
+	file_ranking_message_proto_depIdxs = nil
+}
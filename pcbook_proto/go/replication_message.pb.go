@@ -0,0 +1,421 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.26.0
+// 	protoc        v3.19.4
+// source: replication_message.proto
+
+package pcbook
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Mutation is one write to the stores of a leader. Every change carries the
+// full resulting state of the record, so applying it is idempotent.
+type Mutation struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Sequence    uint64                 `protobuf:"varint,1,opt,name=sequence,proto3" json:"sequence,omitempty"`
+	CommittedAt *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=committed_at,json=committedAt,proto3" json:"committed_at,omitempty"`
+	// Types that are assignable to Change:
+	//	*Mutation_SaveLaptop
+	//	*Mutation_DeleteLaptop
+	//	*Mutation_SetRating
+	//	*Mutation_DeleteRating
+	//	*Mutation_SaveUser
+	//	*Mutation_DeleteUser
+	//	*Mutation_SaveImage
+	//	*Mutation_DeleteImage
+	Change isMutation_Change `protobuf_oneof:"change"`
+	// image_data is the content of save_image
+	ImageData []byte `protobuf:"bytes,11,opt,name=image_data,json=imageData,proto3" json:"image_data,omitempty"`
+}
+
+func (x *Mutation) Reset() {
+	*x = Mutation{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_replication_message_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Mutation) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Mutation) ProtoMessage() {}
+
+func (x *Mutation) ProtoReflect() protoreflect.Message {
+	mi := &file_replication_message_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Mutation.ProtoReflect.Descriptor instead.
+func (*Mutation) Descriptor() ([]byte, []int) {
+	return file_replication_message_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Mutation) GetSequence() uint64 {
+	if x != nil {
+		return x.Sequence
+	}
+	return 0
+}
+
+func (x *Mutation) GetCommittedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CommittedAt
+	}
+	return nil
+}
+
+func (m *Mutation) GetChange() isMutation_Change {
+	if m != nil {
+		return m.Change
+	}
+	return nil
+}
+
+func (x *Mutation) GetSaveLaptop() *Laptop {
+	if x, ok := x.GetChange().(*Mutation_SaveLaptop); ok {
+		return x.SaveLaptop
+	}
+	return nil
+}
+
+func (x *Mutation) GetDeleteLaptop() string {
+	if x, ok := x.GetChange().(*Mutation_DeleteLaptop); ok {
+		return x.DeleteLaptop
+	}
+	return ""
+}
+
+func (x *Mutation) GetSetRating() *BackupRating {
+	if x, ok := x.GetChange().(*Mutation_SetRating); ok {
+		return x.SetRating
+	}
+	return nil
+}
+
+func (x *Mutation) GetDeleteRating() string {
+	if x, ok := x.GetChange().(*Mutation_DeleteRating); ok {
+		return x.DeleteRating
+	}
+	return ""
+}
+
+func (x *Mutation) GetSaveUser() *UserRecord {
+	if x, ok := x.GetChange().(*Mutation_SaveUser); ok {
+		return x.SaveUser
+	}
+	return nil
+}
+
+func (x *Mutation) GetDeleteUser() string {
+	if x, ok := x.GetChange().(*Mutation_DeleteUser); ok {
+		return x.DeleteUser
+	}
+	return ""
+}
+
+func (x *Mutation) GetSaveImage() *ImageRecord {
+	if x, ok := x.GetChange().(*Mutation_SaveImage); ok {
+		return x.SaveImage
+	}
+	return nil
+}
+
+func (x *Mutation) GetDeleteImage() string {
+	if x, ok := x.GetChange().(*Mutation_DeleteImage); ok {
+		return x.DeleteImage
+	}
+	return ""
+}
+
+func (x *Mutation) GetImageData() []byte {
+	if x != nil {
+		return x.ImageData
+	}
+	return nil
+}
+
+type isMutation_Change interface {
+	isMutation_Change()
+}
+
+type Mutation_SaveLaptop struct {
+	SaveLaptop *Laptop `protobuf:"bytes,3,opt,name=save_laptop,json=saveLaptop,proto3,oneof"`
+}
+
+type Mutation_DeleteLaptop struct {
+	DeleteLaptop string `protobuf:"bytes,4,opt,name=delete_laptop,json=deleteLaptop,proto3,oneof"`
+}
+
+type Mutation_SetRating struct {
+	SetRating *BackupRating `protobuf:"bytes,5,opt,name=set_rating,json=setRating,proto3,oneof"`
+}
+
+type Mutation_DeleteRating struct {
+	DeleteRating string `protobuf:"bytes,6,opt,name=delete_rating,json=deleteRating,proto3,oneof"`
+}
+
+type Mutation_SaveUser struct {
+	SaveUser *UserRecord `protobuf:"bytes,7,opt,name=save_user,json=saveUser,proto3,oneof"`
+}
+
+type Mutation_DeleteUser struct {
+	DeleteUser string `protobuf:"bytes,8,opt,name=delete_user,json=deleteUser,proto3,oneof"`
+}
+
+type Mutation_SaveImage struct {
+	SaveImage *ImageRecord `protobuf:"bytes,9,opt,name=save_image,json=saveImage,proto3,oneof"`
+}
+
+type Mutation_DeleteImage struct {
+	DeleteImage string `protobuf:"bytes,10,opt,name=delete_image,json=deleteImage,proto3,oneof"`
+}
+
+func (*Mutation_SaveLaptop) isMutation_Change() {}
+
+func (*Mutation_DeleteLaptop) isMutation_Change() {}
+
+func (*Mutation_SetRating) isMutation_Change() {}
+
+func (*Mutation_DeleteRating) isMutation_Change() {}
+
+func (*Mutation_SaveUser) isMutation_Change() {}
+
+func (*Mutation_DeleteUser) isMutation_Change() {}
+
+func (*Mutation_SaveImage) isMutation_Change() {}
+
+func (*Mutation_DeleteImage) isMutation_Change() {}
+
+type ReplicationHeartbeat struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Sequence uint64                 `protobuf:"varint,1,opt,name=sequence,proto3" json:"sequence,omitempty"`
+	SentAt   *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=sent_at,json=sentAt,proto3" json:"sent_at,omitempty"`
+}
+
+func (x *ReplicationHeartbeat) Reset() {
+	*x = ReplicationHeartbeat{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_replication_message_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ReplicationHeartbeat) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReplicationHeartbeat) ProtoMessage() {}
+
+func (x *ReplicationHeartbeat) ProtoReflect() protoreflect.Message {
+	mi := &file_replication_message_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReplicationHeartbeat.ProtoReflect.Descriptor instead.
+func (*ReplicationHeartbeat) Descriptor() ([]byte, []int) {
+	return file_replication_message_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ReplicationHeartbeat) GetSequence() uint64 {
+	if x != nil {
+		return x.Sequence
+	}
+	return 0
+}
+
+func (x *ReplicationHeartbeat) GetSentAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.SentAt
+	}
+	return nil
+}
+
+var File_replication_message_proto protoreflect.FileDescriptor
+
+var file_replication_message_proto_rawDesc = []byte{
+	0x0a, 0x19, 0x72, 0x65, 0x70, 0x6c, 0x69, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x5f, 0x6d, 0x65,
+	0x73, 0x73, 0x61, 0x67, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x06, 0x70, 0x63, 0x62,
+	0x6f, 0x6f, 0x6b, 0x1a, 0x14, 0x6c, 0x61, 0x70, 0x74, 0x6f, 0x70, 0x5f, 0x6d, 0x65, 0x73, 0x73,
+	0x61, 0x67, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x1a, 0x13, 0x73, 0x74, 0x6f, 0x72, 0x65,
+	0x5f, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x1a, 0x14,
+	0x62, 0x61, 0x63, 0x6b, 0x75, 0x70, 0x5f, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x2e, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x1a, 0x1f, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x62, 0x75, 0x66, 0x2f, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x2e,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0xf7, 0x03, 0x0a, 0x08, 0x4d, 0x75, 0x74, 0x61, 0x74, 0x69,
+	0x6f, 0x6e, 0x12, 0x1a, 0x0a, 0x08, 0x73, 0x65, 0x71, 0x75, 0x65, 0x6e, 0x63, 0x65, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x04, 0x52, 0x08, 0x73, 0x65, 0x71, 0x75, 0x65, 0x6e, 0x63, 0x65, 0x12, 0x3d,
+	0x0a, 0x0c, 0x63, 0x6f, 0x6d, 0x6d, 0x69, 0x74, 0x74, 0x65, 0x64, 0x5f, 0x61, 0x74, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x54, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70,
+	0x52, 0x0b, 0x63, 0x6f, 0x6d, 0x6d, 0x69, 0x74, 0x74, 0x65, 0x64, 0x41, 0x74, 0x12, 0x31, 0x0a,
+	0x0b, 0x73, 0x61, 0x76, 0x65, 0x5f, 0x6c, 0x61, 0x70, 0x74, 0x6f, 0x70, 0x18, 0x03, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x0e, 0x2e, 0x70, 0x63, 0x62, 0x6f, 0x6f, 0x6b, 0x2e, 0x4c, 0x61, 0x70, 0x74,
+	0x6f, 0x70, 0x48, 0x00, 0x52, 0x0a, 0x73, 0x61, 0x76, 0x65, 0x4c, 0x61, 0x70, 0x74, 0x6f, 0x70,
+	0x12, 0x25, 0x0a, 0x0d, 0x64, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x5f, 0x6c, 0x61, 0x70, 0x74, 0x6f,
+	0x70, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x48, 0x00, 0x52, 0x0c, 0x64, 0x65, 0x6c, 0x65, 0x74,
+	0x65, 0x4c, 0x61, 0x70, 0x74, 0x6f, 0x70, 0x12, 0x35, 0x0a, 0x0a, 0x73, 0x65, 0x74, 0x5f, 0x72,
+	0x61, 0x74, 0x69, 0x6e, 0x67, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x14, 0x2e, 0x70, 0x63,
+	0x62, 0x6f, 0x6f, 0x6b, 0x2e, 0x42, 0x61, 0x63, 0x6b, 0x75, 0x70, 0x52, 0x61, 0x74, 0x69, 0x6e,
+	0x67, 0x48, 0x00, 0x52, 0x09, 0x73, 0x65, 0x74, 0x52, 0x61, 0x74, 0x69, 0x6e, 0x67, 0x12, 0x25,
+	0x0a, 0x0d, 0x64, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x5f, 0x72, 0x61, 0x74, 0x69, 0x6e, 0x67, 0x18,
+	0x06, 0x20, 0x01, 0x28, 0x09, 0x48, 0x00, 0x52, 0x0c, 0x64, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x52,
+	0x61, 0x74, 0x69, 0x6e, 0x67, 0x12, 0x31, 0x0a, 0x09, 0x73, 0x61, 0x76, 0x65, 0x5f, 0x75, 0x73,
+	0x65, 0x72, 0x18, 0x07, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x12, 0x2e, 0x70, 0x63, 0x62, 0x6f, 0x6f,
+	0x6b, 0x2e, 0x55, 0x73, 0x65, 0x72, 0x52, 0x65, 0x63, 0x6f, 0x72, 0x64, 0x48, 0x00, 0x52, 0x08,
+	0x73, 0x61, 0x76, 0x65, 0x55, 0x73, 0x65, 0x72, 0x12, 0x21, 0x0a, 0x0b, 0x64, 0x65, 0x6c, 0x65,
+	0x74, 0x65, 0x5f, 0x75, 0x73, 0x65, 0x72, 0x18, 0x08, 0x20, 0x01, 0x28, 0x09, 0x48, 0x00, 0x52,
+	0x0a, 0x64, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x55, 0x73, 0x65, 0x72, 0x12, 0x34, 0x0a, 0x0a, 0x73,
+	0x61, 0x76, 0x65, 0x5f, 0x69, 0x6d, 0x61, 0x67, 0x65, 0x18, 0x09, 0x20, 0x01, 0x28, 0x0b, 0x32,
+	0x13, 0x2e, 0x70, 0x63, 0x62, 0x6f, 0x6f, 0x6b, 0x2e, 0x49, 0x6d, 0x61, 0x67, 0x65, 0x52, 0x65,
+	0x63, 0x6f, 0x72, 0x64, 0x48, 0x00, 0x52, 0x09, 0x73, 0x61, 0x76, 0x65, 0x49, 0x6d, 0x61, 0x67,
+	0x65, 0x12, 0x23, 0x0a, 0x0c, 0x64, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x5f, 0x69, 0x6d, 0x61, 0x67,
+	0x65, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x09, 0x48, 0x00, 0x52, 0x0b, 0x64, 0x65, 0x6c, 0x65, 0x74,
+	0x65, 0x49, 0x6d, 0x61, 0x67, 0x65, 0x12, 0x1d, 0x0a, 0x0a, 0x69, 0x6d, 0x61, 0x67, 0x65, 0x5f,
+	0x64, 0x61, 0x74, 0x61, 0x18, 0x0b, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x09, 0x69, 0x6d, 0x61, 0x67,
+	0x65, 0x44, 0x61, 0x74, 0x61, 0x42, 0x08, 0x0a, 0x06, 0x63, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x22,
+	0x67, 0x0a, 0x14, 0x52, 0x65, 0x70, 0x6c, 0x69, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x48, 0x65,
+	0x61, 0x72, 0x74, 0x62, 0x65, 0x61, 0x74, 0x12, 0x1a, 0x0a, 0x08, 0x73, 0x65, 0x71, 0x75, 0x65,
+	0x6e, 0x63, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x04, 0x52, 0x08, 0x73, 0x65, 0x71, 0x75, 0x65,
+	0x6e, 0x63, 0x65, 0x12, 0x33, 0x0a, 0x07, 0x73, 0x65, 0x6e, 0x74, 0x5f, 0x61, 0x74, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x54, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70,
+	0x52, 0x06, 0x73, 0x65, 0x6e, 0x74, 0x41, 0x74, 0x42, 0x0b, 0x5a, 0x09, 0x2e, 0x2f, 0x3b, 0x70,
+	0x63, 0x62, 0x6f, 0x6f, 0x6b, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_replication_message_proto_rawDescOnce sync.Once
+	file_replication_message_proto_rawDescData = file_replication_message_proto_rawDesc
+)
+
+func file_replication_message_proto_rawDescGZIP() []byte {
+	file_replication_message_proto_rawDescOnce.Do(func() {
+		file_replication_message_proto_rawDescData = protoimpl.X.CompressGZIP(file_replication_message_proto_rawDescData)
+	})
+	return file_replication_message_proto_rawDescData
+}
+
+var file_replication_message_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_replication_message_proto_goTypes = []interface{}{
+	(*Mutation)(nil),              // 0: pcbook.Mutation
+	(*ReplicationHeartbeat)(nil),  // 1: pcbook.ReplicationHeartbeat
+	(*timestamppb.Timestamp)(nil), // 2: google.protobuf.Timestamp
+	(*Laptop)(nil),                // 3: pcbook.Laptop
+	(*BackupRating)(nil),          // 4: pcbook.BackupRating
+	(*UserRecord)(nil),            // 5: pcbook.UserRecord
+	(*ImageRecord)(nil),           // 6: pcbook.ImageRecord
+}
+var file_replication_message_proto_depIdxs = []int32{
+	2, // 0: pcbook.Mutation.committed_at:type_name -> google.protobuf.Timestamp
+	3, // 1: pcbook.Mutation.save_laptop:type_name -> pcbook.Laptop
+	4, // 2: pcbook.Mutation.set_rating:type_name -> pcbook.BackupRating
+	5, // 3: pcbook.Mutation.save_user:type_name -> pcbook.UserRecord
+	6, // 4: pcbook.Mutation.save_image:type_name -> pcbook.ImageRecord
+	2, // 5: pcbook.ReplicationHeartbeat.sent_at:type_name -> google.protobuf.Timestamp
+	6, // [6:6] is the sub-list for method output_type
+	6, // [6:6] is the sub-list for method input_type
+	6, // [6:6] is the sub-list for extension type_name
+	6, // [6:6] is the sub-list for extension extendee
+	0, // [0:6] is the sub-list for field type_name
+}
+
+func init() { file_replication_message_proto_init() }
+func file_replication_message_proto_init() {
+	if File_replication_message_proto != nil {
+		return
+	}
+	file_laptop_message_proto_init()
+	file_store_message_proto_init()
+	file_backup_message_proto_init()
+	if !protoimpl.UnsafeEnabled {
+		file_replication_message_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Mutation); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_replication_message_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ReplicationHeartbeat); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	file_replication_message_proto_msgTypes[0].OneofWrappers = []interface{}{
+		(*Mutation_SaveLaptop)(nil),
+		(*Mutation_DeleteLaptop)(nil),
+		(*Mutation_SetRating)(nil),
+		(*Mutation_DeleteRating)(nil),
+		(*Mutation_SaveUser)(nil),
+		(*Mutation_DeleteUser)(nil),
+		(*Mutation_SaveImage)(nil),
+		(*Mutation_DeleteImage)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_replication_message_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   2,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_replication_message_proto_goTypes,
+		DependencyIndexes: file_replication_message_proto_depIdxs,
+		MessageInfos:      file_replication_message_proto_msgTypes,
+	}.Build()
+	File_replication_message_proto = out.File
+	file_replication_message_proto_rawDesc = nil
+	file_replication_message_proto_goTypes = nil
+	file_replication_message_proto_depIdxs = nil
+}
@@ -0,0 +1,538 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.26.0
+// 	protoc        v3.19.4
+// source: replication_service.proto
+
+package pcbook
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type ReplicationStatusResponse_Role int32
+
+const (
+	ReplicationStatusResponse_UNKNOWN  ReplicationStatusResponse_Role = 0
+	ReplicationStatusResponse_LEADER   ReplicationStatusResponse_Role = 1
+	ReplicationStatusResponse_FOLLOWER ReplicationStatusResponse_Role = 2
+)
+
+// Enum value maps for ReplicationStatusResponse_Role.
+var (
+	ReplicationStatusResponse_Role_name = map[int32]string{
+		0: "UNKNOWN",
+		1: "LEADER",
+		2: "FOLLOWER",
+	}
+	ReplicationStatusResponse_Role_value = map[string]int32{
+		"UNKNOWN":  0,
+		"LEADER":   1,
+		"FOLLOWER": 2,
+	}
+)
+
+func (x ReplicationStatusResponse_Role) Enum() *ReplicationStatusResponse_Role {
+	p := new(ReplicationStatusResponse_Role)
+	*p = x
+	return p
+}
+
+func (x ReplicationStatusResponse_Role) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ReplicationStatusResponse_Role) Descriptor() protoreflect.EnumDescriptor {
+	return file_replication_service_proto_enumTypes[0].Descriptor()
+}
+
+func (ReplicationStatusResponse_Role) Type() protoreflect.EnumType {
+	return &file_replication_service_proto_enumTypes[0]
+}
+
+func (x ReplicationStatusResponse_Role) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use ReplicationStatusResponse_Role.Descriptor instead.
+func (ReplicationStatusResponse_Role) EnumDescriptor() ([]byte, []int) {
+	return file_replication_service_proto_rawDescGZIP(), []int{3, 0}
+}
+
+type ReplicateRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *ReplicateRequest) Reset() {
+	*x = ReplicateRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_replication_service_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ReplicateRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReplicateRequest) ProtoMessage() {}
+
+func (x *ReplicateRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_replication_service_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReplicateRequest.ProtoReflect.Descriptor instead.
+func (*ReplicateRequest) Descriptor() ([]byte, []int) {
+	return file_replication_service_proto_rawDescGZIP(), []int{0}
+}
+
+// A replication stream starts with the sequence of a snapshot followed by
+// the snapshot itself, written as a backup archive. Mutations after that
+// sequence follow in order, and heartbeats tell the latest sequence of the
+// leader while there is nothing to send.
+type ReplicateResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Types that are assignable to Data:
+	//	*ReplicateResponse_SnapshotSequence
+	//	*ReplicateResponse_SnapshotChunk
+	//	*ReplicateResponse_Mutation
+	//	*ReplicateResponse_Heartbeat
+	Data isReplicateResponse_Data `protobuf_oneof:"data"`
+}
+
+func (x *ReplicateResponse) Reset() {
+	*x = ReplicateResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_replication_service_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ReplicateResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReplicateResponse) ProtoMessage() {}
+
+func (x *ReplicateResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_replication_service_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReplicateResponse.ProtoReflect.Descriptor instead.
+func (*ReplicateResponse) Descriptor() ([]byte, []int) {
+	return file_replication_service_proto_rawDescGZIP(), []int{1}
+}
+
+func (m *ReplicateResponse) GetData() isReplicateResponse_Data {
+	if m != nil {
+		return m.Data
+	}
+	return nil
+}
+
+func (x *ReplicateResponse) GetSnapshotSequence() uint64 {
+	if x, ok := x.GetData().(*ReplicateResponse_SnapshotSequence); ok {
+		return x.SnapshotSequence
+	}
+	return 0
+}
+
+func (x *ReplicateResponse) GetSnapshotChunk() []byte {
+	if x, ok := x.GetData().(*ReplicateResponse_SnapshotChunk); ok {
+		return x.SnapshotChunk
+	}
+	return nil
+}
+
+func (x *ReplicateResponse) GetMutation() *Mutation {
+	if x, ok := x.GetData().(*ReplicateResponse_Mutation); ok {
+		return x.Mutation
+	}
+	return nil
+}
+
+func (x *ReplicateResponse) GetHeartbeat() *ReplicationHeartbeat {
+	if x, ok := x.GetData().(*ReplicateResponse_Heartbeat); ok {
+		return x.Heartbeat
+	}
+	return nil
+}
+
+type isReplicateResponse_Data interface {
+	isReplicateResponse_Data()
+}
+
+type ReplicateResponse_SnapshotSequence struct {
+	SnapshotSequence uint64 `protobuf:"varint,1,opt,name=snapshot_sequence,json=snapshotSequence,proto3,oneof"`
+}
+
+type ReplicateResponse_SnapshotChunk struct {
+	SnapshotChunk []byte `protobuf:"bytes,2,opt,name=snapshot_chunk,json=snapshotChunk,proto3,oneof"`
+}
+
+type ReplicateResponse_Mutation struct {
+	Mutation *Mutation `protobuf:"bytes,3,opt,name=mutation,proto3,oneof"`
+}
+
+type ReplicateResponse_Heartbeat struct {
+	Heartbeat *ReplicationHeartbeat `protobuf:"bytes,4,opt,name=heartbeat,proto3,oneof"`
+}
+
+func (*ReplicateResponse_SnapshotSequence) isReplicateResponse_Data() {}
+
+func (*ReplicateResponse_SnapshotChunk) isReplicateResponse_Data() {}
+
+func (*ReplicateResponse_Mutation) isReplicateResponse_Data() {}
+
+func (*ReplicateResponse_Heartbeat) isReplicateResponse_Data() {}
+
+type ReplicationStatusRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *ReplicationStatusRequest) Reset() {
+	*x = ReplicationStatusRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_replication_service_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ReplicationStatusRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReplicationStatusRequest) ProtoMessage() {}
+
+func (x *ReplicationStatusRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_replication_service_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReplicationStatusRequest.ProtoReflect.Descriptor instead.
+func (*ReplicationStatusRequest) Descriptor() ([]byte, []int) {
+	return file_replication_service_proto_rawDescGZIP(), []int{2}
+}
+
+type ReplicationStatusResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Role            ReplicationStatusResponse_Role `protobuf:"varint,1,opt,name=role,proto3,enum=pcbook.ReplicationStatusResponse_Role" json:"role,omitempty"`
+	LeaderAddress   string                         `protobuf:"bytes,2,opt,name=leader_address,json=leaderAddress,proto3" json:"leader_address,omitempty"`
+	Connected       bool                           `protobuf:"varint,3,opt,name=connected,proto3" json:"connected,omitempty"`
+	AppliedSequence uint64                         `protobuf:"varint,4,opt,name=applied_sequence,json=appliedSequence,proto3" json:"applied_sequence,omitempty"`
+	LeaderSequence  uint64                         `protobuf:"varint,5,opt,name=leader_sequence,json=leaderSequence,proto3" json:"leader_sequence,omitempty"`
+	// lag_sequences is the number of mutations the follower has yet to apply
+	LagSequences uint64 `protobuf:"varint,6,opt,name=lag_sequences,json=lagSequences,proto3" json:"lag_sequences,omitempty"`
+	// lag_seconds is the time since the follower was last caught up
+	LagSeconds float64 `protobuf:"fixed64,7,opt,name=lag_seconds,json=lagSeconds,proto3" json:"lag_seconds,omitempty"`
+}
+
+func (x *ReplicationStatusResponse) Reset() {
+	*x = ReplicationStatusResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_replication_service_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ReplicationStatusResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReplicationStatusResponse) ProtoMessage() {}
+
+func (x *ReplicationStatusResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_replication_service_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReplicationStatusResponse.ProtoReflect.Descriptor instead.
+func (*ReplicationStatusResponse) Descriptor() ([]byte, []int) {
+	return file_replication_service_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ReplicationStatusResponse) GetRole() ReplicationStatusResponse_Role {
+	if x != nil {
+		return x.Role
+	}
+	return ReplicationStatusResponse_UNKNOWN
+}
+
+func (x *ReplicationStatusResponse) GetLeaderAddress() string {
+	if x != nil {
+		return x.LeaderAddress
+	}
+	return ""
+}
+
+func (x *ReplicationStatusResponse) GetConnected() bool {
+	if x != nil {
+		return x.Connected
+	}
+	return false
+}
+
+func (x *ReplicationStatusResponse) GetAppliedSequence() uint64 {
+	if x != nil {
+		return x.AppliedSequence
+	}
+	return 0
+}
+
+func (x *ReplicationStatusResponse) GetLeaderSequence() uint64 {
+	if x != nil {
+		return x.LeaderSequence
+	}
+	return 0
+}
+
+func (x *ReplicationStatusResponse) GetLagSequences() uint64 {
+	if x != nil {
+		return x.LagSequences
+	}
+	return 0
+}
+
+func (x *ReplicationStatusResponse) GetLagSeconds() float64 {
+	if x != nil {
+		return x.LagSeconds
+	}
+	return 0
+}
+
+var File_replication_service_proto protoreflect.FileDescriptor
+
+var file_replication_service_proto_rawDesc = []byte{
+	0x0a, 0x19, 0x72, 0x65, 0x70, 0x6c, 0x69, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x5f, 0x73, 0x65,
+	0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x06, 0x70, 0x63, 0x62,
+	0x6f, 0x6f, 0x6b, 0x1a, 0x19, 0x72, 0x65, 0x70, 0x6c, 0x69, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e,
+	0x5f, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0x12,
+	0x0a, 0x10, 0x52, 0x65, 0x70, 0x6c, 0x69, 0x63, 0x61, 0x74, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x22, 0xe1, 0x01, 0x0a, 0x11, 0x52, 0x65, 0x70, 0x6c, 0x69, 0x63, 0x61, 0x74, 0x65,
+	0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x2d, 0x0a, 0x11, 0x73, 0x6e, 0x61, 0x70,
+	0x73, 0x68, 0x6f, 0x74, 0x5f, 0x73, 0x65, 0x71, 0x75, 0x65, 0x6e, 0x63, 0x65, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x04, 0x48, 0x00, 0x52, 0x10, 0x73, 0x6e, 0x61, 0x70, 0x73, 0x68, 0x6f, 0x74, 0x53,
+	0x65, 0x71, 0x75, 0x65, 0x6e, 0x63, 0x65, 0x12, 0x27, 0x0a, 0x0e, 0x73, 0x6e, 0x61, 0x70, 0x73,
+	0x68, 0x6f, 0x74, 0x5f, 0x63, 0x68, 0x75, 0x6e, 0x6b, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x48,
+	0x00, 0x52, 0x0d, 0x73, 0x6e, 0x61, 0x70, 0x73, 0x68, 0x6f, 0x74, 0x43, 0x68, 0x75, 0x6e, 0x6b,
+	0x12, 0x2e, 0x0a, 0x08, 0x6d, 0x75, 0x74, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x03, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x10, 0x2e, 0x70, 0x63, 0x62, 0x6f, 0x6f, 0x6b, 0x2e, 0x4d, 0x75, 0x74, 0x61,
+	0x74, 0x69, 0x6f, 0x6e, 0x48, 0x00, 0x52, 0x08, 0x6d, 0x75, 0x74, 0x61, 0x74, 0x69, 0x6f, 0x6e,
+	0x12, 0x3c, 0x0a, 0x09, 0x68, 0x65, 0x61, 0x72, 0x74, 0x62, 0x65, 0x61, 0x74, 0x18, 0x04, 0x20,
+	0x01, 0x28, 0x0b, 0x32, 0x1c, 0x2e, 0x70, 0x63, 0x62, 0x6f, 0x6f, 0x6b, 0x2e, 0x52, 0x65, 0x70,
+	0x6c, 0x69, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x48, 0x65, 0x61, 0x72, 0x74, 0x62, 0x65, 0x61,
+	0x74, 0x48, 0x00, 0x52, 0x09, 0x68, 0x65, 0x61, 0x72, 0x74, 0x62, 0x65, 0x61, 0x74, 0x42, 0x06,
+	0x0a, 0x04, 0x64, 0x61, 0x74, 0x61, 0x22, 0x1a, 0x0a, 0x18, 0x52, 0x65, 0x70, 0x6c, 0x69, 0x63,
+	0x61, 0x74, 0x69, 0x6f, 0x6e, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x22, 0xe5, 0x02, 0x0a, 0x19, 0x52, 0x65, 0x70, 0x6c, 0x69, 0x63, 0x61, 0x74, 0x69,
+	0x6f, 0x6e, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65,
+	0x12, 0x3a, 0x0a, 0x04, 0x72, 0x6f, 0x6c, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x26,
+	0x2e, 0x70, 0x63, 0x62, 0x6f, 0x6f, 0x6b, 0x2e, 0x52, 0x65, 0x70, 0x6c, 0x69, 0x63, 0x61, 0x74,
+	0x69, 0x6f, 0x6e, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
+	0x65, 0x2e, 0x52, 0x6f, 0x6c, 0x65, 0x52, 0x04, 0x72, 0x6f, 0x6c, 0x65, 0x12, 0x25, 0x0a, 0x0e,
+	0x6c, 0x65, 0x61, 0x64, 0x65, 0x72, 0x5f, 0x61, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x0d, 0x6c, 0x65, 0x61, 0x64, 0x65, 0x72, 0x41, 0x64, 0x64, 0x72,
+	0x65, 0x73, 0x73, 0x12, 0x1c, 0x0a, 0x09, 0x63, 0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74, 0x65, 0x64,
+	0x18, 0x03, 0x20, 0x01, 0x28, 0x08, 0x52, 0x09, 0x63, 0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74, 0x65,
+	0x64, 0x12, 0x29, 0x0a, 0x10, 0x61, 0x70, 0x70, 0x6c, 0x69, 0x65, 0x64, 0x5f, 0x73, 0x65, 0x71,
+	0x75, 0x65, 0x6e, 0x63, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0f, 0x61, 0x70, 0x70,
+	0x6c, 0x69, 0x65, 0x64, 0x53, 0x65, 0x71, 0x75, 0x65, 0x6e, 0x63, 0x65, 0x12, 0x27, 0x0a, 0x0f,
+	0x6c, 0x65, 0x61, 0x64, 0x65, 0x72, 0x5f, 0x73, 0x65, 0x71, 0x75, 0x65, 0x6e, 0x63, 0x65, 0x18,
+	0x05, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0e, 0x6c, 0x65, 0x61, 0x64, 0x65, 0x72, 0x53, 0x65, 0x71,
+	0x75, 0x65, 0x6e, 0x63, 0x65, 0x12, 0x23, 0x0a, 0x0d, 0x6c, 0x61, 0x67, 0x5f, 0x73, 0x65, 0x71,
+	0x75, 0x65, 0x6e, 0x63, 0x65, 0x73, 0x18, 0x06, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0c, 0x6c, 0x61,
+	0x67, 0x53, 0x65, 0x71, 0x75, 0x65, 0x6e, 0x63, 0x65, 0x73, 0x12, 0x1f, 0x0a, 0x0b, 0x6c, 0x61,
+	0x67, 0x5f, 0x73, 0x65, 0x63, 0x6f, 0x6e, 0x64, 0x73, 0x18, 0x07, 0x20, 0x01, 0x28, 0x01, 0x52,
+	0x0a, 0x6c, 0x61, 0x67, 0x53, 0x65, 0x63, 0x6f, 0x6e, 0x64, 0x73, 0x22, 0x2d, 0x0a, 0x04, 0x52,
+	0x6f, 0x6c, 0x65, 0x12, 0x0b, 0x0a, 0x07, 0x55, 0x4e, 0x4b, 0x4e, 0x4f, 0x57, 0x4e, 0x10, 0x00,
+	0x12, 0x0a, 0x0a, 0x06, 0x4c, 0x45, 0x41, 0x44, 0x45, 0x52, 0x10, 0x01, 0x12, 0x0c, 0x0a, 0x08,
+	0x46, 0x4f, 0x4c, 0x4c, 0x4f, 0x57, 0x45, 0x52, 0x10, 0x02, 0x32, 0xb6, 0x01, 0x0a, 0x12, 0x52,
+	0x65, 0x70, 0x6c, 0x69, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63,
+	0x65, 0x12, 0x44, 0x0a, 0x09, 0x52, 0x65, 0x70, 0x6c, 0x69, 0x63, 0x61, 0x74, 0x65, 0x12, 0x18,
+	0x2e, 0x70, 0x63, 0x62, 0x6f, 0x6f, 0x6b, 0x2e, 0x52, 0x65, 0x70, 0x6c, 0x69, 0x63, 0x61, 0x74,
+	0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x19, 0x2e, 0x70, 0x63, 0x62, 0x6f, 0x6f,
+	0x6b, 0x2e, 0x52, 0x65, 0x70, 0x6c, 0x69, 0x63, 0x61, 0x74, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f,
+	0x6e, 0x73, 0x65, 0x22, 0x00, 0x30, 0x01, 0x12, 0x5a, 0x0a, 0x11, 0x52, 0x65, 0x70, 0x6c, 0x69,
+	0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x20, 0x2e, 0x70,
+	0x63, 0x62, 0x6f, 0x6f, 0x6b, 0x2e, 0x52, 0x65, 0x70, 0x6c, 0x69, 0x63, 0x61, 0x74, 0x69, 0x6f,
+	0x6e, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x21,
+	0x2e, 0x70, 0x63, 0x62, 0x6f, 0x6f, 0x6b, 0x2e, 0x52, 0x65, 0x70, 0x6c, 0x69, 0x63, 0x61, 0x74,
+	0x69, 0x6f, 0x6e, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
+	0x65, 0x22, 0x00, 0x42, 0x0b, 0x5a, 0x09, 0x2e, 0x2f, 0x3b, 0x70, 0x63, 0x62, 0x6f, 0x6f, 0x6b,
+	0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_replication_service_proto_rawDescOnce sync.Once
+	file_replication_service_proto_rawDescData = file_replication_service_proto_rawDesc
+)
+
+func file_replication_service_proto_rawDescGZIP() []byte {
+	file_replication_service_proto_rawDescOnce.Do(func() {
+		file_replication_service_proto_rawDescData = protoimpl.X.CompressGZIP(file_replication_service_proto_rawDescData)
+	})
+	return file_replication_service_proto_rawDescData
+}
+
+var file_replication_service_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_replication_service_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
+var file_replication_service_proto_goTypes = []interface{}{
+	(ReplicationStatusResponse_Role)(0), // 0: pcbook.ReplicationStatusResponse.Role
+	(*ReplicateRequest)(nil),            // 1: pcbook.ReplicateRequest
+	(*ReplicateResponse)(nil),           // 2: pcbook.ReplicateResponse
+	(*ReplicationStatusRequest)(nil),    // 3: pcbook.ReplicationStatusRequest
+	(*ReplicationStatusResponse)(nil),   // 4: pcbook.ReplicationStatusResponse
+	(*Mutation)(nil),                    // 5: pcbook.Mutation
+	(*ReplicationHeartbeat)(nil),        // 6: pcbook.ReplicationHeartbeat
+}
+var file_replication_service_proto_depIdxs = []int32{
+	5, // 0: pcbook.ReplicateResponse.mutation:type_name -> pcbook.Mutation
+	6, // 1: pcbook.ReplicateResponse.heartbeat:type_name -> pcbook.ReplicationHeartbeat
+	0, // 2: pcbook.ReplicationStatusResponse.role:type_name -> pcbook.ReplicationStatusResponse.Role
+	1, // 3: pcbook.ReplicationService.Replicate:input_type -> pcbook.ReplicateRequest
+	3, // 4: pcbook.ReplicationService.ReplicationStatus:input_type -> pcbook.ReplicationStatusRequest
+	2, // 5: pcbook.ReplicationService.Replicate:output_type -> pcbook.ReplicateResponse
+	4, // 6: pcbook.ReplicationService.ReplicationStatus:output_type -> pcbook.ReplicationStatusResponse
+	5, // [5:7] is the sub-list for method output_type
+	3, // [3:5] is the sub-list for method input_type
+	3, // [3:3] is the sub-list for extension type_name
+	3, // [3:3] is the sub-list for extension extendee
+	0, // [0:3] is the sub-list for field type_name
+}
+
+func init() { file_replication_service_proto_init() }
+func file_replication_service_proto_init() {
+	if File_replication_service_proto != nil {
+		return
+	}
+	file_replication_message_proto_init()
+	if !protoimpl.UnsafeEnabled {
+		file_replication_service_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ReplicateRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_replication_service_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ReplicateResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_replication_service_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ReplicationStatusRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_replication_service_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ReplicationStatusResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	file_replication_service_proto_msgTypes[1].OneofWrappers = []interface{}{
+		(*ReplicateResponse_SnapshotSequence)(nil),
+		(*ReplicateResponse_SnapshotChunk)(nil),
+		(*ReplicateResponse_Mutation)(nil),
+		(*ReplicateResponse_Heartbeat)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_replication_service_proto_rawDesc,
+			NumEnums:      1,
+			NumMessages:   4,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_replication_service_proto_goTypes,
+		DependencyIndexes: file_replication_service_proto_depIdxs,
+		EnumInfos:         file_replication_service_proto_enumTypes,
+		MessageInfos:      file_replication_service_proto_msgTypes,
+	}.Build()
+	File_replication_service_proto = out.File
+	file_replication_service_proto_rawDesc = nil
+	file_replication_service_proto_goTypes = nil
+	file_replication_service_proto_depIdxs = nil
+}
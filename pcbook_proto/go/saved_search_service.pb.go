@@ -0,0 +1,568 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.26.0
+// 	protoc        v3.19.4
+// source: saved_search_service.proto
+
+package pcbook
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type SaveSearchRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Name   string  `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Filter *Filter `protobuf:"bytes,2,opt,name=filter,proto3" json:"filter,omitempty"`
+}
+
+func (x *SaveSearchRequest) Reset() {
+	*x = SaveSearchRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_saved_search_service_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SaveSearchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SaveSearchRequest) ProtoMessage() {}
+
+func (x *SaveSearchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_saved_search_service_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SaveSearchRequest.ProtoReflect.Descriptor instead.
+func (*SaveSearchRequest) Descriptor() ([]byte, []int) {
+	return file_saved_search_service_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *SaveSearchRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *SaveSearchRequest) GetFilter() *Filter {
+	if x != nil {
+		return x.Filter
+	}
+	return nil
+}
+
+type SaveSearchResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	SavedSearch *SavedSearch `protobuf:"bytes,1,opt,name=saved_search,json=savedSearch,proto3" json:"saved_search,omitempty"`
+}
+
+func (x *SaveSearchResponse) Reset() {
+	*x = SaveSearchResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_saved_search_service_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SaveSearchResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SaveSearchResponse) ProtoMessage() {}
+
+func (x *SaveSearchResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_saved_search_service_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SaveSearchResponse.ProtoReflect.Descriptor instead.
+func (*SaveSearchResponse) Descriptor() ([]byte, []int) {
+	return file_saved_search_service_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *SaveSearchResponse) GetSavedSearch() *SavedSearch {
+	if x != nil {
+		return x.SavedSearch
+	}
+	return nil
+}
+
+type ListSavedSearchesRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *ListSavedSearchesRequest) Reset() {
+	*x = ListSavedSearchesRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_saved_search_service_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ListSavedSearchesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListSavedSearchesRequest) ProtoMessage() {}
+
+func (x *ListSavedSearchesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_saved_search_service_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListSavedSearchesRequest.ProtoReflect.Descriptor instead.
+func (*ListSavedSearchesRequest) Descriptor() ([]byte, []int) {
+	return file_saved_search_service_proto_rawDescGZIP(), []int{2}
+}
+
+type ListSavedSearchesResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	SavedSearches []*SavedSearch `protobuf:"bytes,1,rep,name=saved_searches,json=savedSearches,proto3" json:"saved_searches,omitempty"`
+}
+
+func (x *ListSavedSearchesResponse) Reset() {
+	*x = ListSavedSearchesResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_saved_search_service_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ListSavedSearchesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListSavedSearchesResponse) ProtoMessage() {}
+
+func (x *ListSavedSearchesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_saved_search_service_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListSavedSearchesResponse.ProtoReflect.Descriptor instead.
+func (*ListSavedSearchesResponse) Descriptor() ([]byte, []int) {
+	return file_saved_search_service_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ListSavedSearchesResponse) GetSavedSearches() []*SavedSearch {
+	if x != nil {
+		return x.SavedSearches
+	}
+	return nil
+}
+
+type DeleteSavedSearchRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+}
+
+func (x *DeleteSavedSearchRequest) Reset() {
+	*x = DeleteSavedSearchRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_saved_search_service_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *DeleteSavedSearchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteSavedSearchRequest) ProtoMessage() {}
+
+func (x *DeleteSavedSearchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_saved_search_service_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteSavedSearchRequest.ProtoReflect.Descriptor instead.
+func (*DeleteSavedSearchRequest) Descriptor() ([]byte, []int) {
+	return file_saved_search_service_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *DeleteSavedSearchRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type DeleteSavedSearchResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *DeleteSavedSearchResponse) Reset() {
+	*x = DeleteSavedSearchResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_saved_search_service_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *DeleteSavedSearchResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteSavedSearchResponse) ProtoMessage() {}
+
+func (x *DeleteSavedSearchResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_saved_search_service_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteSavedSearchResponse.ProtoReflect.Descriptor instead.
+func (*DeleteSavedSearchResponse) Descriptor() ([]byte, []int) {
+	return file_saved_search_service_proto_rawDescGZIP(), []int{5}
+}
+
+type RunSavedSearchRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id      string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	OnlyNew bool   `protobuf:"varint,2,opt,name=only_new,json=onlyNew,proto3" json:"only_new,omitempty"`
+}
+
+func (x *RunSavedSearchRequest) Reset() {
+	*x = RunSavedSearchRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_saved_search_service_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *RunSavedSearchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RunSavedSearchRequest) ProtoMessage() {}
+
+func (x *RunSavedSearchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_saved_search_service_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RunSavedSearchRequest.ProtoReflect.Descriptor instead.
+func (*RunSavedSearchRequest) Descriptor() ([]byte, []int) {
+	return file_saved_search_service_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *RunSavedSearchRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *RunSavedSearchRequest) GetOnlyNew() bool {
+	if x != nil {
+		return x.OnlyNew
+	}
+	return false
+}
+
+var File_saved_search_service_proto protoreflect.FileDescriptor
+
+var file_saved_search_service_proto_rawDesc = []byte{
+	0x0a, 0x1a, 0x73, 0x61, 0x76, 0x65, 0x64, 0x5f, 0x73, 0x65, 0x61, 0x72, 0x63, 0x68, 0x5f, 0x73,
+	0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x06, 0x70, 0x63,
+	0x62, 0x6f, 0x6f, 0x6b, 0x1a, 0x14, 0x66, 0x69, 0x6c, 0x74, 0x65, 0x72, 0x5f, 0x6d, 0x65, 0x73,
+	0x73, 0x61, 0x67, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x1a, 0x14, 0x6c, 0x61, 0x70, 0x74,
+	0x6f, 0x70, 0x5f, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x1a, 0x1a, 0x73, 0x61, 0x76, 0x65, 0x64, 0x5f, 0x73, 0x65, 0x61, 0x72, 0x63, 0x68, 0x5f, 0x6d,
+	0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0x4f, 0x0a, 0x11,
+	0x53, 0x61, 0x76, 0x65, 0x53, 0x65, 0x61, 0x72, 0x63, 0x68, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x26, 0x0a, 0x06, 0x66, 0x69, 0x6c, 0x74, 0x65, 0x72, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0e, 0x2e, 0x70, 0x63, 0x62, 0x6f, 0x6f, 0x6b, 0x2e, 0x46,
+	0x69, 0x6c, 0x74, 0x65, 0x72, 0x52, 0x06, 0x66, 0x69, 0x6c, 0x74, 0x65, 0x72, 0x22, 0x4c, 0x0a,
+	0x12, 0x53, 0x61, 0x76, 0x65, 0x53, 0x65, 0x61, 0x72, 0x63, 0x68, 0x52, 0x65, 0x73, 0x70, 0x6f,
+	0x6e, 0x73, 0x65, 0x12, 0x36, 0x0a, 0x0c, 0x73, 0x61, 0x76, 0x65, 0x64, 0x5f, 0x73, 0x65, 0x61,
+	0x72, 0x63, 0x68, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x13, 0x2e, 0x70, 0x63, 0x62, 0x6f,
+	0x6f, 0x6b, 0x2e, 0x53, 0x61, 0x76, 0x65, 0x64, 0x53, 0x65, 0x61, 0x72, 0x63, 0x68, 0x52, 0x0b,
+	0x73, 0x61, 0x76, 0x65, 0x64, 0x53, 0x65, 0x61, 0x72, 0x63, 0x68, 0x22, 0x1a, 0x0a, 0x18, 0x4c,
+	0x69, 0x73, 0x74, 0x53, 0x61, 0x76, 0x65, 0x64, 0x53, 0x65, 0x61, 0x72, 0x63, 0x68, 0x65, 0x73,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x22, 0x57, 0x0a, 0x19, 0x4c, 0x69, 0x73, 0x74, 0x53,
+	0x61, 0x76, 0x65, 0x64, 0x53, 0x65, 0x61, 0x72, 0x63, 0x68, 0x65, 0x73, 0x52, 0x65, 0x73, 0x70,
+	0x6f, 0x6e, 0x73, 0x65, 0x12, 0x3a, 0x0a, 0x0e, 0x73, 0x61, 0x76, 0x65, 0x64, 0x5f, 0x73, 0x65,
+	0x61, 0x72, 0x63, 0x68, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x13, 0x2e, 0x70,
+	0x63, 0x62, 0x6f, 0x6f, 0x6b, 0x2e, 0x53, 0x61, 0x76, 0x65, 0x64, 0x53, 0x65, 0x61, 0x72, 0x63,
+	0x68, 0x52, 0x0d, 0x73, 0x61, 0x76, 0x65, 0x64, 0x53, 0x65, 0x61, 0x72, 0x63, 0x68, 0x65, 0x73,
+	0x22, 0x2a, 0x0a, 0x18, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x53, 0x61, 0x76, 0x65, 0x64, 0x53,
+	0x65, 0x61, 0x72, 0x63, 0x68, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x0e, 0x0a, 0x02,
+	0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x22, 0x1b, 0x0a, 0x19,
+	0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x53, 0x61, 0x76, 0x65, 0x64, 0x53, 0x65, 0x61, 0x72, 0x63,
+	0x68, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x42, 0x0a, 0x15, 0x52, 0x75, 0x6e,
+	0x53, 0x61, 0x76, 0x65, 0x64, 0x53, 0x65, 0x61, 0x72, 0x63, 0x68, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02,
+	0x69, 0x64, 0x12, 0x19, 0x0a, 0x08, 0x6f, 0x6e, 0x6c, 0x79, 0x5f, 0x6e, 0x65, 0x77, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x08, 0x52, 0x07, 0x6f, 0x6e, 0x6c, 0x79, 0x4e, 0x65, 0x77, 0x32, 0xe6, 0x02,
+	0x0a, 0x12, 0x53, 0x61, 0x76, 0x65, 0x64, 0x53, 0x65, 0x61, 0x72, 0x63, 0x68, 0x53, 0x65, 0x72,
+	0x76, 0x69, 0x63, 0x65, 0x12, 0x45, 0x0a, 0x0a, 0x53, 0x61, 0x76, 0x65, 0x53, 0x65, 0x61, 0x72,
+	0x63, 0x68, 0x12, 0x19, 0x2e, 0x70, 0x63, 0x62, 0x6f, 0x6f, 0x6b, 0x2e, 0x53, 0x61, 0x76, 0x65,
+	0x53, 0x65, 0x61, 0x72, 0x63, 0x68, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1a, 0x2e,
+	0x70, 0x63, 0x62, 0x6f, 0x6f, 0x6b, 0x2e, 0x53, 0x61, 0x76, 0x65, 0x53, 0x65, 0x61, 0x72, 0x63,
+	0x68, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x12, 0x5a, 0x0a, 0x11, 0x4c,
+	0x69, 0x73, 0x74, 0x53, 0x61, 0x76, 0x65, 0x64, 0x53, 0x65, 0x61, 0x72, 0x63, 0x68, 0x65, 0x73,
+	0x12, 0x20, 0x2e, 0x70, 0x63, 0x62, 0x6f, 0x6f, 0x6b, 0x2e, 0x4c, 0x69, 0x73, 0x74, 0x53, 0x61,
+	0x76, 0x65, 0x64, 0x53, 0x65, 0x61, 0x72, 0x63, 0x68, 0x65, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x1a, 0x21, 0x2e, 0x70, 0x63, 0x62, 0x6f, 0x6f, 0x6b, 0x2e, 0x4c, 0x69, 0x73, 0x74,
+	0x53, 0x61, 0x76, 0x65, 0x64, 0x53, 0x65, 0x61, 0x72, 0x63, 0x68, 0x65, 0x73, 0x52, 0x65, 0x73,
+	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x12, 0x5a, 0x0a, 0x11, 0x44, 0x65, 0x6c, 0x65, 0x74,
+	0x65, 0x53, 0x61, 0x76, 0x65, 0x64, 0x53, 0x65, 0x61, 0x72, 0x63, 0x68, 0x12, 0x20, 0x2e, 0x70,
+	0x63, 0x62, 0x6f, 0x6f, 0x6b, 0x2e, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x53, 0x61, 0x76, 0x65,
+	0x64, 0x53, 0x65, 0x61, 0x72, 0x63, 0x68, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x21,
+	0x2e, 0x70, 0x63, 0x62, 0x6f, 0x6f, 0x6b, 0x2e, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x53, 0x61,
+	0x76, 0x65, 0x64, 0x53, 0x65, 0x61, 0x72, 0x63, 0x68, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
+	0x65, 0x22, 0x00, 0x12, 0x51, 0x0a, 0x0e, 0x52, 0x75, 0x6e, 0x53, 0x61, 0x76, 0x65, 0x64, 0x53,
+	0x65, 0x61, 0x72, 0x63, 0x68, 0x12, 0x1d, 0x2e, 0x70, 0x63, 0x62, 0x6f, 0x6f, 0x6b, 0x2e, 0x52,
+	0x75, 0x6e, 0x53, 0x61, 0x76, 0x65, 0x64, 0x53, 0x65, 0x61, 0x72, 0x63, 0x68, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x1a, 0x1c, 0x2e, 0x70, 0x63, 0x62, 0x6f, 0x6f, 0x6b, 0x2e, 0x53, 0x65,
+	0x61, 0x72, 0x63, 0x68, 0x4c, 0x61, 0x70, 0x74, 0x6f, 0x70, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e,
+	0x73, 0x65, 0x22, 0x00, 0x30, 0x01, 0x42, 0x0b, 0x5a, 0x09, 0x2e, 0x2f, 0x3b, 0x70, 0x63, 0x62,
+	0x6f, 0x6f, 0x6b, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_saved_search_service_proto_rawDescOnce sync.Once
+	file_saved_search_service_proto_rawDescData = file_saved_search_service_proto_rawDesc
+)
+
+func file_saved_search_service_proto_rawDescGZIP() []byte {
+	file_saved_search_service_proto_rawDescOnce.Do(func() {
+		file_saved_search_service_proto_rawDescData = protoimpl.X.CompressGZIP(file_saved_search_service_proto_rawDescData)
+	})
+	return file_saved_search_service_proto_rawDescData
+}
+
+var file_saved_search_service_proto_msgTypes = make([]protoimpl.MessageInfo, 7)
+var file_saved_search_service_proto_goTypes = []interface{}{
+	(*SaveSearchRequest)(nil),         // 0: pcbook.SaveSearchRequest
+	(*SaveSearchResponse)(nil),        // 1: pcbook.SaveSearchResponse
+	(*ListSavedSearchesRequest)(nil),  // 2: pcbook.ListSavedSearchesRequest
+	(*ListSavedSearchesResponse)(nil), // 3: pcbook.ListSavedSearchesResponse
+	(*DeleteSavedSearchRequest)(nil),  // 4: pcbook.DeleteSavedSearchRequest
+	(*DeleteSavedSearchResponse)(nil), // 5: pcbook.DeleteSavedSearchResponse
+	(*RunSavedSearchRequest)(nil),     // 6: pcbook.RunSavedSearchRequest
+	(*Filter)(nil),                    // 7: pcbook.Filter
+	(*SavedSearch)(nil),               // 8: pcbook.SavedSearch
+	(*SearchLaptopResponse)(nil),      // 9: pcbook.SearchLaptopResponse
+}
+var file_saved_search_service_proto_depIdxs = []int32{
+	7, // 0: pcbook.SaveSearchRequest.filter:type_name -> pcbook.Filter
+	8, // 1: pcbook.SaveSearchResponse.saved_search:type_name -> pcbook.SavedSearch
+	8, // 2: pcbook.ListSavedSearchesResponse.saved_searches:type_name -> pcbook.SavedSearch
+	0, // 3: pcbook.SavedSearchService.SaveSearch:input_type -> pcbook.SaveSearchRequest
+	2, // 4: pcbook.SavedSearchService.ListSavedSearches:input_type -> pcbook.ListSavedSearchesRequest
+	4, // 5: pcbook.SavedSearchService.DeleteSavedSearch:input_type -> pcbook.DeleteSavedSearchRequest
+	6, // 6: pcbook.SavedSearchService.RunSavedSearch:input_type -> pcbook.RunSavedSearchRequest
+	1, // 7: pcbook.SavedSearchService.SaveSearch:output_type -> pcbook.SaveSearchResponse
+	3, // 8: pcbook.SavedSearchService.ListSavedSearches:output_type -> pcbook.ListSavedSearchesResponse
+	5, // 9: pcbook.SavedSearchService.DeleteSavedSearch:output_type -> pcbook.DeleteSavedSearchResponse
+	9, // 10: pcbook.SavedSearchService.RunSavedSearch:output_type -> pcbook.SearchLaptopResponse
+	7, // [7:11] is the sub-list for method output_type
+	3, // [3:7] is the sub-list for method input_type
+	3, // [3:3] is the sub-list for extension type_name
+	3, // [3:3] is the sub-list for extension extendee
+	0, // [0:3] is the sub-list for field type_name
+}
+
+func init() { file_saved_search_service_proto_init() }
+func file_saved_search_service_proto_init() {
+	if File_saved_search_service_proto != nil {
+		return
+	}
+	file_filter_message_proto_init()
+	file_laptop_service_proto_init()
+	file_saved_search_message_proto_init()
+	if !protoimpl.UnsafeEnabled {
+		file_saved_search_service_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SaveSearchRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_saved_search_service_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SaveSearchResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_saved_search_service_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ListSavedSearchesRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_saved_search_service_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ListSavedSearchesResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_saved_search_service_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*DeleteSavedSearchRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_saved_search_service_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*DeleteSavedSearchResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_saved_search_service_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*RunSavedSearchRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_saved_search_service_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   7,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_saved_search_service_proto_goTypes,
+		DependencyIndexes: file_saved_search_service_proto_depIdxs,
+		MessageInfos:      file_saved_search_service_proto_msgTypes,
+	}.Build()
+	File_saved_search_service_proto = out.File
+	file_saved_search_service_proto_rawDesc = nil
+	file_saved_search_service_proto_goTypes = nil
+	file_saved_search_service_proto_depIdxs = nil
+}
@@ -0,0 +1,237 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+
+package pcbook
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// SavedSearchServiceClient is the client API for SavedSearchService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type SavedSearchServiceClient interface {
+	SaveSearch(ctx context.Context, in *SaveSearchRequest, opts ...grpc.CallOption) (*SaveSearchResponse, error)
+	ListSavedSearches(ctx context.Context, in *ListSavedSearchesRequest, opts ...grpc.CallOption) (*ListSavedSearchesResponse, error)
+	DeleteSavedSearch(ctx context.Context, in *DeleteSavedSearchRequest, opts ...grpc.CallOption) (*DeleteSavedSearchResponse, error)
+	RunSavedSearch(ctx context.Context, in *RunSavedSearchRequest, opts ...grpc.CallOption) (SavedSearchService_RunSavedSearchClient, error)
+}
+
+type savedSearchServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewSavedSearchServiceClient(cc grpc.ClientConnInterface) SavedSearchServiceClient {
+	return &savedSearchServiceClient{cc}
+}
+
+func (c *savedSearchServiceClient) SaveSearch(ctx context.Context, in *SaveSearchRequest, opts ...grpc.CallOption) (*SaveSearchResponse, error) {
+	out := new(SaveSearchResponse)
+	err := c.cc.Invoke(ctx, "/pcbook.SavedSearchService/SaveSearch", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *savedSearchServiceClient) ListSavedSearches(ctx context.Context, in *ListSavedSearchesRequest, opts ...grpc.CallOption) (*ListSavedSearchesResponse, error) {
+	out := new(ListSavedSearchesResponse)
+	err := c.cc.Invoke(ctx, "/pcbook.SavedSearchService/ListSavedSearches", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *savedSearchServiceClient) DeleteSavedSearch(ctx context.Context, in *DeleteSavedSearchRequest, opts ...grpc.CallOption) (*DeleteSavedSearchResponse, error) {
+	out := new(DeleteSavedSearchResponse)
+	err := c.cc.Invoke(ctx, "/pcbook.SavedSearchService/DeleteSavedSearch", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *savedSearchServiceClient) RunSavedSearch(ctx context.Context, in *RunSavedSearchRequest, opts ...grpc.CallOption) (SavedSearchService_RunSavedSearchClient, error) {
+	stream, err := c.cc.NewStream(ctx, &SavedSearchService_ServiceDesc.Streams[0], "/pcbook.SavedSearchService/RunSavedSearch", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &savedSearchServiceRunSavedSearchClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type SavedSearchService_RunSavedSearchClient interface {
+	Recv() (*SearchLaptopResponse, error)
+	grpc.ClientStream
+}
+
+type savedSearchServiceRunSavedSearchClient struct {
+	grpc.ClientStream
+}
+
+func (x *savedSearchServiceRunSavedSearchClient) Recv() (*SearchLaptopResponse, error) {
+	m := new(SearchLaptopResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// SavedSearchServiceServer is the server API for SavedSearchService service.
+// All implementations must embed UnimplementedSavedSearchServiceServer
+// for forward compatibility
+type SavedSearchServiceServer interface {
+	SaveSearch(context.Context, *SaveSearchRequest) (*SaveSearchResponse, error)
+	ListSavedSearches(context.Context, *ListSavedSearchesRequest) (*ListSavedSearchesResponse, error)
+	DeleteSavedSearch(context.Context, *DeleteSavedSearchRequest) (*DeleteSavedSearchResponse, error)
+	RunSavedSearch(*RunSavedSearchRequest, SavedSearchService_RunSavedSearchServer) error
+	mustEmbedUnimplementedSavedSearchServiceServer()
+}
+
+// UnimplementedSavedSearchServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedSavedSearchServiceServer struct {
+}
+
+func (UnimplementedSavedSearchServiceServer) SaveSearch(context.Context, *SaveSearchRequest) (*SaveSearchResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SaveSearch not implemented")
+}
+func (UnimplementedSavedSearchServiceServer) ListSavedSearches(context.Context, *ListSavedSearchesRequest) (*ListSavedSearchesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListSavedSearches not implemented")
+}
+func (UnimplementedSavedSearchServiceServer) DeleteSavedSearch(context.Context, *DeleteSavedSearchRequest) (*DeleteSavedSearchResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteSavedSearch not implemented")
+}
+func (UnimplementedSavedSearchServiceServer) RunSavedSearch(*RunSavedSearchRequest, SavedSearchService_RunSavedSearchServer) error {
+	return status.Errorf(codes.Unimplemented, "method RunSavedSearch not implemented")
+}
+func (UnimplementedSavedSearchServiceServer) mustEmbedUnimplementedSavedSearchServiceServer() {}
+
+// UnsafeSavedSearchServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to SavedSearchServiceServer will
+// result in compilation errors.
+type UnsafeSavedSearchServiceServer interface {
+	mustEmbedUnimplementedSavedSearchServiceServer()
+}
+
+func RegisterSavedSearchServiceServer(s grpc.ServiceRegistrar, srv SavedSearchServiceServer) {
+	s.RegisterService(&SavedSearchService_ServiceDesc, srv)
+}
+
+func _SavedSearchService_SaveSearch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SaveSearchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SavedSearchServiceServer).SaveSearch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pcbook.SavedSearchService/SaveSearch",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SavedSearchServiceServer).SaveSearch(ctx, req.(*SaveSearchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SavedSearchService_ListSavedSearches_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListSavedSearchesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SavedSearchServiceServer).ListSavedSearches(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pcbook.SavedSearchService/ListSavedSearches",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SavedSearchServiceServer).ListSavedSearches(ctx, req.(*ListSavedSearchesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SavedSearchService_DeleteSavedSearch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteSavedSearchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SavedSearchServiceServer).DeleteSavedSearch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pcbook.SavedSearchService/DeleteSavedSearch",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SavedSearchServiceServer).DeleteSavedSearch(ctx, req.(*DeleteSavedSearchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SavedSearchService_RunSavedSearch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(RunSavedSearchRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(SavedSearchServiceServer).RunSavedSearch(m, &savedSearchServiceRunSavedSearchServer{stream})
+}
+
+type SavedSearchService_RunSavedSearchServer interface {
+	Send(*SearchLaptopResponse) error
+	grpc.ServerStream
+}
+
+type savedSearchServiceRunSavedSearchServer struct {
+	grpc.ServerStream
+}
+
+func (x *savedSearchServiceRunSavedSearchServer) Send(m *SearchLaptopResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// SavedSearchService_ServiceDesc is the grpc.ServiceDesc for SavedSearchService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var SavedSearchService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "pcbook.SavedSearchService",
+	HandlerType: (*SavedSearchServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "SaveSearch",
+			Handler:    _SavedSearchService_SaveSearch_Handler,
+		},
+		{
+			MethodName: "ListSavedSearches",
+			Handler:    _SavedSearchService_ListSavedSearches_Handler,
+		},
+		{
+			MethodName: "DeleteSavedSearch",
+			Handler:    _SavedSearchService_DeleteSavedSearch_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "RunSavedSearch",
+			Handler:       _SavedSearchService_RunSavedSearch_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "saved_search_service.proto",
+}
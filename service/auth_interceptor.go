@@ -33,7 +33,7 @@ func (authInterceptor *AuthInterceptor) Unary() grpc.UnaryServerInterceptor {
 		info *grpc.UnaryServerInfo,
 		handler grpc.UnaryHandler,
 	) (interface{}, error) {
-		err := authInterceptor.authorize(ctx, info.FullMethod)
+		ctx, err := authInterceptor.authorize(ctx, info.FullMethod)
 		if err != nil {
 			return nil, err
 		}
@@ -49,42 +49,73 @@ func (authInterceptor *AuthInterceptor) Stream() grpc.StreamServerInterceptor {
 		info *grpc.StreamServerInfo,
 		handler grpc.StreamHandler,
 	) error {
-		err := authInterceptor.authorize(stream.Context(), info.FullMethod)
+		ctx, err := authInterceptor.authorize(stream.Context(), info.FullMethod)
 		if err != nil {
 			return err
 		}
 
-		return handler(srv, stream)
+		return handler(srv, &authServerStream{stream, ctx})
 	}
 }
 
-func (authInterceptor *AuthInterceptor) authorize(ctx context.Context, method string) error {
+// authorize checks the access token of the request against the roles the
+// method allows. Public methods run without a token, but a token sent to
+// one still decides the tenant the request runs in, so an invalid token is
+// rejected there too rather than silently running in the default tenant.
+func (authInterceptor *AuthInterceptor) authorize(ctx context.Context, method string) (context.Context, error) {
 	allowedRoles, protected := authInterceptor.accessManager[method]
-	if !protected {
-		return nil
-	}
 
 	md, ok := metadata.FromIncomingContext(ctx)
 	if !ok {
-		return status.Errorf(codes.Unauthenticated, "metadata is not provided")
+		if !protected {
+			return ctx, nil
+		}
+		return nil, status.Errorf(codes.Unauthenticated, "metadata is not provided")
 	}
 
 	values, ok := md["authorization"]
 	if !ok {
-		return status.Errorf(codes.Unauthenticated, "authorization token not provided")
+		if !protected {
+			return ctx, nil
+		}
+		return nil, status.Errorf(codes.Unauthenticated, "authorization token not provided")
 	}
 
 	accessToken := values[0]
 	userClaims, err := authInterceptor.jwtManager.Verify(accessToken)
 	if err != nil {
-		return status.Errorf(codes.Unauthenticated, "access token is invalid: %v", err)
+		return nil, status.Errorf(codes.Unauthenticated, "access token is invalid: %v", err)
+	}
+
+	if !protected {
+		return ContextWithUserClaims(ctx, userClaims), nil
 	}
 
 	for _, role := range allowedRoles {
 		if userClaims.Role == role {
-			return nil
+			return ContextWithUserClaims(ctx, userClaims), nil
 		}
 	}
 
-	return status.Errorf(codes.PermissionDenied, "no permission to access this RPC")
+	return nil, status.Errorf(codes.PermissionDenied, "no permission to access this RPC")
+}
+
+type userClaimsKey struct{}
+
+func ContextWithUserClaims(ctx context.Context, userClaims *UserClaims) context.Context {
+	return context.WithValue(ctx, userClaimsKey{}, userClaims)
+}
+
+func UserClaimsFromContext(ctx context.Context) (*UserClaims, bool) {
+	userClaims, ok := ctx.Value(userClaimsKey{}).(*UserClaims)
+	return userClaims, ok
+}
+
+type authServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (stream *authServerStream) Context() context.Context {
+	return stream.ctx
 }
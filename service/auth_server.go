@@ -36,7 +36,7 @@ func (authServer *AuthServer) Login(ctx context.Context, req *pb.LoginRequest) (
 		return nil, status.Errorf(codes.InvalidArgument, "password provided for user:%s is incorrect", password)
 	}
 
-	accessToken, err := authServer.jwtManager.Generate(username, user.Role)
+	accessToken, err := authServer.jwtManager.Generate(username, user.Role, user.Tenant)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "cannot generate a token for user:%s -- err: %v", username, err)
 	}
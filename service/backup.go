@@ -0,0 +1,484 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"sort"
+	"sync"
+
+	pb "github.com/orkhanrustamli/pcbook/pcbook_proto/go"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	backupVersion   = 1
+	backupChunkSize = 64 << 10
+)
+
+var ErrInvalidBackup = errors.New("invalid backup archive")
+
+// WriteBarrier lets a backup or a restore hold every write back while it
+// reads or replaces the stores. Writers share the barrier, so they never
+// wait on each other. A nil barrier does not hold anything back.
+type WriteBarrier struct {
+	mutex sync.RWMutex
+}
+
+func NewWriteBarrier() *WriteBarrier {
+	return &WriteBarrier{}
+}
+
+// Write runs write unless the stores are frozen, in which case it waits
+func (barrier *WriteBarrier) Write(write func() error) error {
+	if barrier == nil {
+		return write()
+	}
+
+	barrier.mutex.RLock()
+	defer barrier.mutex.RUnlock()
+
+	return write()
+}
+
+// Freeze runs do once every running write is done, and holds back new
+// writes until it returns
+func (barrier *WriteBarrier) Freeze(do func() error) error {
+	if barrier == nil {
+		return do()
+	}
+
+	barrier.mutex.Lock()
+	defer barrier.mutex.Unlock()
+
+	return do()
+}
+
+// WriteBackup writes an archive of every laptop, rating, user and image of
+// stores to writer. The records are copied and the image files opened while
+// the barrier is frozen, so the archive is a consistent point-in-time view
+// even though writing it out does not hold writes back.
+func WriteBackup(ctx context.Context, writer io.Writer, stores *Stores, barrier *WriteBarrier) error {
+	snapshot := &backupSnapshot{}
+	defer snapshot.close()
+
+	err := barrier.Freeze(func() error {
+		return snapshot.take(ctx, stores)
+	})
+	if err != nil {
+		return err
+	}
+
+	return snapshot.write(ctx, writer)
+}
+
+// ReadBackup reads an archive into stores, which are meant to be empty
+// in-memory stores that the archive is checked in before it is restored. It
+// fails with ErrInvalidBackup if the archive is malformed, and with
+// ErrChecksumMismatch if its records do not match the trailer.
+func ReadBackup(ctx context.Context, reader io.Reader, stores *Stores) error {
+	archive := &backupReader{
+		reader: bufio.NewReader(reader),
+		hash:   sha256.New(),
+	}
+
+	header := &pb.BackupRecord{}
+	if err := archive.read(header); err != nil {
+		return err
+	}
+	if header.GetHeader() == nil {
+		return fmt.Errorf("%w: archive does not start with a header", ErrInvalidBackup)
+	}
+	if header.GetHeader().GetVersion() != backupVersion {
+		return fmt.Errorf("%w: unsupported version %d", ErrInvalidBackup, header.GetHeader().GetVersion())
+	}
+
+	var image *ImageInfo
+	imageData := bytes.Buffer{}
+	importImage := func() error {
+		if err := stores.Images.Import(image, &imageData); err != nil {
+			return fmt.Errorf("cannot import image %s: %w", image.Id, err)
+		}
+		image = nil
+		imageData.Reset()
+		return nil
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		record := &pb.BackupRecord{}
+		if err := archive.read(record); err != nil {
+			return err
+		}
+
+		if image != nil && record.GetRecord() != nil {
+			if _, ok := record.GetRecord().(*pb.BackupRecord_ImageChunk); !ok {
+				return fmt.Errorf("%w: image %s is incomplete", ErrInvalidBackup, image.Id)
+			}
+		}
+
+		var err error
+		switch record := record.GetRecord().(type) {
+		case *pb.BackupRecord_Laptop:
+			err = stores.Laptops.Save(record.Laptop)
+		case *pb.BackupRecord_Rating:
+			rating := record.Rating.GetRating()
+			err = stores.Ratings.Set(record.Rating.GetLaptopId(), NewRating(int(rating.GetCount()), rating.GetSum()))
+		case *pb.BackupRecord_User:
+			err = stores.Users.Save(&User{
+				Username: record.User.GetUsername(),
+				Password: record.User.GetPasswordHash(),
+				Role:     record.User.GetRole(),
+				Tenant:   record.User.GetTenant(),
+			})
+		case *pb.BackupRecord_Image:
+			image = &ImageInfo{
+				Id:         record.Image.GetId(),
+				LaptopId:   record.Image.GetLaptopId(),
+				Type:       record.Image.GetImageType(),
+				Size:       int64(record.Image.GetSize()),
+				UploadedAt: record.Image.GetUploadedAt().AsTime(),
+			}
+			if image.Size == 0 {
+				err = importImage()
+			}
+		case *pb.BackupRecord_ImageChunk:
+			if image == nil || int64(imageData.Len()+len(record.ImageChunk)) > image.Size {
+				return fmt.Errorf("%w: unexpected image data", ErrInvalidBackup)
+			}
+			imageData.Write(record.ImageChunk)
+			if int64(imageData.Len()) == image.Size {
+				err = importImage()
+			}
+		case *pb.BackupRecord_Trailer:
+			if image != nil {
+				return fmt.Errorf("%w: image %s is incomplete", ErrInvalidBackup, image.Id)
+			}
+			return archive.check(record.Trailer)
+		default:
+			return fmt.Errorf("%w: unexpected record", ErrInvalidBackup)
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidBackup, err)
+		}
+	}
+}
+
+// RestoreBackup copies the records read from an archive into stores while
+// the barrier is frozen. With replace, stores are emptied first and checked
+// against the archive afterwards. Otherwise the archive is merged: laptops,
+// users and images already in stores are kept, and ratings take the value of
+// the archive.
+func RestoreBackup(
+	ctx context.Context,
+	archive *Stores,
+	stores *Stores,
+	barrier *WriteBarrier,
+	replace bool,
+) (map[string]MigrationStats, error) {
+	var results map[string]MigrationStats
+	err := barrier.Freeze(func() error {
+		if replace {
+			if err := clearStores(ctx, stores); err != nil {
+				return fmt.Errorf("cannot clear stores: %w", err)
+			}
+		}
+
+		migration := &Migration{
+			Source:      archive,
+			Destination: stores,
+			Resume:      true,
+		}
+
+		var err error
+		results, err = migration.Run(ctx)
+		if err != nil || !replace {
+			return err
+		}
+
+		_, err = migration.Verify(ctx)
+		return err
+	})
+
+	return results, err
+}
+
+// clearStores collects the keys of every store before deleting anything, so
+// no store is modified while it is being listed
+func clearStores(ctx context.Context, stores *Stores) error {
+	laptopIds := []string{}
+	_, err := stores.Laptops.Search(ctx, nil, func(laptop *pb.Laptop) error {
+		laptopIds = append(laptopIds, laptop.GetId())
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	ratedIds := []string{}
+	err = stores.Ratings.List(func(laptopId string, rating *Rating) error {
+		ratedIds = append(ratedIds, laptopId)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	usernames := []string{}
+	err = stores.Users.List(func(user *User) error {
+		usernames = append(usernames, user.Username)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	imageIds := []string{}
+	err = stores.Images.List(func(image *ImageInfo) error {
+		imageIds = append(imageIds, image.Id)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, id := range imageIds {
+		if err := stores.Images.Delete(id); err != nil && !errors.Is(err, ErrNotFound) {
+			return err
+		}
+	}
+	for _, username := range usernames {
+		if err := stores.Users.Delete(username); err != nil && !errors.Is(err, ErrNotFound) {
+			return err
+		}
+	}
+	for _, laptopId := range ratedIds {
+		if err := stores.Ratings.Delete(laptopId); err != nil && !errors.Is(err, ErrNotFound) {
+			return err
+		}
+	}
+	for _, id := range laptopIds {
+		if err := stores.Laptops.Delete(id); err != nil && !errors.Is(err, ErrNotFound) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type backupSnapshot struct {
+	laptops []*pb.Laptop
+	ratings []*pb.BackupRating
+	users   []*pb.UserRecord
+	images  []*ImageInfo
+	// files are open until the snapshot is closed, so an image deleted after
+	// the snapshot was taken can still be read
+	files []*os.File
+}
+
+func (snapshot *backupSnapshot) take(ctx context.Context, stores *Stores) error {
+	_, err := stores.Laptops.Search(ctx, nil, func(laptop *pb.Laptop) error {
+		snapshot.laptops = append(snapshot.laptops, laptop)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("cannot read laptops: %w", err)
+	}
+	sort.Slice(snapshot.laptops, func(i, j int) bool {
+		return snapshot.laptops[i].GetId() < snapshot.laptops[j].GetId()
+	})
+
+	err = stores.Ratings.List(func(laptopId string, rating *Rating) error {
+		snapshot.ratings = append(snapshot.ratings, &pb.BackupRating{
+			LaptopId: laptopId,
+			Rating: &pb.RatingRecord{
+				Count: uint32(rating.count),
+				Sum:   rating.sum,
+			},
+		})
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("cannot read ratings: %w", err)
+	}
+
+	err = stores.Users.List(func(user *User) error {
+		snapshot.users = append(snapshot.users, &pb.UserRecord{
+			Username:     user.Username,
+			PasswordHash: user.Password,
+			Role:         user.Role,
+			Tenant:       user.Tenant,
+		})
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("cannot read users: %w", err)
+	}
+
+	return stores.Images.List(func(image *ImageInfo) error {
+		file, err := os.Open(image.Path)
+		if err != nil {
+			return fmt.Errorf("cannot open image %s: %v", image.Id, err)
+		}
+
+		snapshot.images = append(snapshot.images, image)
+		snapshot.files = append(snapshot.files, file)
+		return nil
+	})
+}
+
+func (snapshot *backupSnapshot) write(ctx context.Context, writer io.Writer) error {
+	archive := &backupWriter{
+		ctx:    ctx,
+		writer: writer,
+		hash:   sha256.New(),
+	}
+
+	archive.write(&pb.BackupRecord{Record: &pb.BackupRecord_Header{Header: &pb.BackupHeader{
+		Version:   backupVersion,
+		CreatedAt: timestamppb.Now(),
+	}}})
+
+	for _, laptop := range snapshot.laptops {
+		archive.write(&pb.BackupRecord{Record: &pb.BackupRecord_Laptop{Laptop: laptop}})
+	}
+	for _, rating := range snapshot.ratings {
+		archive.write(&pb.BackupRecord{Record: &pb.BackupRecord_Rating{Rating: rating}})
+	}
+	for _, user := range snapshot.users {
+		archive.write(&pb.BackupRecord{Record: &pb.BackupRecord_User{User: user}})
+	}
+	for i, image := range snapshot.images {
+		archive.writeImage(image, snapshot.files[i])
+	}
+
+	return archive.close()
+}
+
+func (snapshot *backupSnapshot) close() {
+	for _, file := range snapshot.files {
+		file.Close()
+	}
+	snapshot.files = nil
+}
+
+// backupWriter keeps the first error, so that a whole archive can be
+// written before checking it
+type backupWriter struct {
+	ctx     context.Context
+	writer  io.Writer
+	hash    hash.Hash
+	records uint64
+	err     error
+}
+
+func (archive *backupWriter) write(record *pb.BackupRecord) {
+	if archive.err != nil {
+		return
+	}
+	if archive.err = archive.ctx.Err(); archive.err != nil {
+		return
+	}
+
+	payload, err := proto.Marshal(record)
+	if err != nil {
+		archive.err = fmt.Errorf("cannot marshal record: %v", err)
+		return
+	}
+
+	archive.hash.Write(payload)
+	archive.records++
+	archive.err = writeRecordPayload(archive.writer, payload)
+}
+
+func (archive *backupWriter) writeImage(image *ImageInfo, file *os.File) {
+	archive.write(&pb.BackupRecord{Record: &pb.BackupRecord_Image{Image: &pb.ImageRecord{
+		Id:         image.Id,
+		LaptopId:   image.LaptopId,
+		ImageType:  image.Type,
+		Size:       uint64(image.Size),
+		UploadedAt: timestamppb.New(image.UploadedAt),
+	}}})
+
+	buffer := make([]byte, backupChunkSize)
+	written := int64(0)
+	for archive.err == nil {
+		n, err := file.Read(buffer)
+		if n > 0 {
+			written += int64(n)
+			archive.write(&pb.BackupRecord{Record: &pb.BackupRecord_ImageChunk{ImageChunk: buffer[:n]}})
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			archive.err = fmt.Errorf("cannot read image %s: %v", image.Id, err)
+			return
+		}
+	}
+
+	if archive.err == nil && written != image.Size {
+		archive.err = fmt.Errorf("image %s has %d bytes instead of %d", image.Id, written, image.Size)
+	}
+}
+
+// close writes the trailer, which is not part of the checksum it carries
+func (archive *backupWriter) close() error {
+	if archive.err != nil {
+		return archive.err
+	}
+
+	return writeRecord(archive.writer, &pb.BackupRecord{Record: &pb.BackupRecord_Trailer{Trailer: &pb.BackupTrailer{
+		Records: archive.records,
+		Sha256:  archive.hash.Sum(nil),
+	}}})
+}
+
+type backupReader struct {
+	reader  *bufio.Reader
+	hash    hash.Hash
+	records uint64
+}
+
+func (archive *backupReader) read(record *pb.BackupRecord) error {
+	payload, _, err := readRecordPayload(archive.reader)
+	if err == io.EOF {
+		return fmt.Errorf("%w: archive is truncated", ErrInvalidBackup)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidBackup, err)
+	}
+
+	if err := proto.Unmarshal(payload, record); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidBackup, err)
+	}
+
+	if record.GetTrailer() == nil {
+		archive.hash.Write(payload)
+		archive.records++
+	}
+	return nil
+}
+
+func (archive *backupReader) check(trailer *pb.BackupTrailer) error {
+	if trailer.GetRecords() != archive.records ||
+		!bytes.Equal(trailer.GetSha256(), archive.hash.Sum(nil)) {
+		return ErrChecksumMismatch
+	}
+
+	if _, err := archive.reader.Peek(1); err != io.EOF {
+		return fmt.Errorf("%w: data after the trailer", ErrInvalidBackup)
+	}
+
+	return nil
+}
@@ -0,0 +1,150 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"log"
+	"os"
+
+	pb "github.com/orkhanrustamli/pcbook/pcbook_proto/go"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type BackupServer struct {
+	stores  *Stores
+	barrier *WriteBarrier
+	pb.UnimplementedBackupServiceServer
+}
+
+// NewBackupServer backs up and restores stores. The barrier must be the one
+// every writer to stores goes through, see LaptopServer.Barrier.
+func NewBackupServer(stores *Stores, barrier *WriteBarrier) *BackupServer {
+	return &BackupServer{
+		stores:  stores,
+		barrier: barrier,
+	}
+}
+
+func (server *BackupServer) Backup(req *pb.BackupRequest, stream pb.BackupService_BackupServer) error {
+	if err := authorizeBackup(stream.Context()); err != nil {
+		return err
+	}
+
+	log.Print("Received a backup request")
+
+	writer := bufio.NewWriterSize(&backupStreamWriter{stream}, backupChunkSize)
+	err := WriteBackup(stream.Context(), writer, server.stores, server.barrier)
+	if err == nil {
+		err = writer.Flush()
+	}
+	if err != nil {
+		return logAndReturnError(status.Errorf(codes.Internal, "Cannot write backup: %v", err))
+	}
+
+	return nil
+}
+
+// Restore reads and checks the whole archive before it touches the stores,
+// so a corrupt or truncated archive leaves the server state as it was
+func (server *BackupServer) Restore(stream pb.BackupService_RestoreServer) error {
+	if err := authorizeBackup(stream.Context()); err != nil {
+		return err
+	}
+
+	req, err := stream.Recv()
+	if err != nil {
+		return logAndReturnError(status.Errorf(codes.Unknown, "Cannot receive restore info"))
+	}
+
+	mode := req.GetInfo().GetMode()
+	if mode != pb.RestoreInfo_REPLACE && mode != pb.RestoreInfo_MERGE {
+		return logAndReturnError(status.Errorf(codes.InvalidArgument, "Unknown restore mode: %v", mode))
+	}
+	log.Printf("Received a restore request with mode %v", mode)
+
+	imageFolder, err := os.MkdirTemp("", "pcbook-restore-*")
+	if err != nil {
+		return status.Errorf(codes.Internal, "Cannot create image folder: %v", err)
+	}
+	defer os.RemoveAll(imageFolder)
+
+	archive, err := OpenStores(StoreOptions{Type: "memory", ImageFolder: imageFolder})
+	if err != nil {
+		return status.Errorf(codes.Internal, "Cannot open stores: %v", err)
+	}
+	defer archive.Close()
+
+	err = ReadBackup(stream.Context(), &restoreStreamReader{stream: stream}, archive)
+	if errors.Is(err, ErrInvalidBackup) || errors.Is(err, ErrChecksumMismatch) {
+		return logAndReturnError(status.Errorf(codes.DataLoss, "Cannot read backup: %v", err))
+	}
+	if err != nil {
+		return logAndReturnError(status.Errorf(codes.Unknown, "Cannot read backup: %v", err))
+	}
+
+	results, err := RestoreBackup(stream.Context(), archive, server.stores, server.barrier, mode == pb.RestoreInfo_REPLACE)
+	if err != nil {
+		return logAndReturnError(status.Errorf(codes.Internal, "Cannot restore backup: %v", err))
+	}
+
+	res := &pb.RestoreResponse{
+		Laptops: uint32(results[MigrationLaptops].Copied),
+		Ratings: uint32(results[MigrationRatings].Copied),
+		Users:   uint32(results[MigrationUsers].Copied),
+		Images:  uint32(results[MigrationImages].Copied),
+	}
+	if err := stream.SendAndClose(res); err != nil {
+		return status.Errorf(codes.Unknown, "Cannot send response: %v", err)
+	}
+
+	log.Printf("Restored backup: %v", res)
+	return nil
+}
+
+// authorizeBackup lets only an admin of the default tenant or a super-admin
+// through, since archives span every tenant and carry password hashes
+func authorizeBackup(ctx context.Context) error {
+	if tenant, scoped := tenantScope(ctx); scoped && tenant != "" {
+		return logAndReturnError(status.Errorf(codes.PermissionDenied, "Backups span every tenant"))
+	}
+
+	return nil
+}
+
+type backupStreamWriter struct {
+	stream pb.BackupService_BackupServer
+}
+
+// Write sends p right away, the stream does not keep it after Send returns
+func (writer *backupStreamWriter) Write(p []byte) (int, error) {
+	if err := writer.stream.Send(&pb.BackupResponse{ChunkData: p}); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+type restoreStreamReader struct {
+	stream pb.BackupService_RestoreServer
+	chunk  []byte
+}
+
+func (reader *restoreStreamReader) Read(p []byte) (int, error) {
+	for len(reader.chunk) == 0 {
+		req, err := reader.stream.Recv()
+		if err != nil {
+			return 0, err
+		}
+		if req.GetInfo() != nil {
+			return 0, errors.New("restore info sent twice")
+		}
+
+		reader.chunk = req.GetChunkData()
+	}
+
+	n := copy(p, reader.chunk)
+	reader.chunk = reader.chunk[n:]
+	return n, nil
+}
@@ -0,0 +1,247 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/orkhanrustamli/pcbook/genarator"
+	pb "github.com/orkhanrustamli/pcbook/pcbook_proto/go"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestClientBackupRestore(t *testing.T) {
+	t.Parallel()
+
+	source := newTestBackupStores(t)
+	laptopIds := []string{}
+	for i := 0; i < 3; i++ {
+		laptop := genarator.NewLaptop()
+		laptop.Tenant = "acme"
+		laptopIds = append(laptopIds, laptop.GetId())
+		require.NoError(t, source.Laptops.Save(laptop))
+		source.Ratings.Rate(laptop.GetId(), float64(i+5))
+
+		// larger than a chunk, so images span several records
+		imageData := bytes.Repeat([]byte(laptop.GetName()), backupChunkSize/4)
+		_, err := source.Images.Save(laptop.GetId(), ".jpg", *bytes.NewBuffer(imageData))
+		require.NoError(t, err)
+	}
+
+	user, err := NewUser("admin1", "secret", "admin", "")
+	require.NoError(t, err)
+	require.NoError(t, source.Users.Save(user))
+
+	archive := bytes.Buffer{}
+	sourceClient := startTestBackupClient(t, startTestBackupServer(t, source))
+	stream, err := sourceClient.Backup(context.Background(), &pb.BackupRequest{})
+	require.NoError(t, err)
+	for {
+		res, err := stream.Recv()
+		if err != nil {
+			break
+		}
+		archive.Write(res.GetChunkData())
+	}
+	require.NotZero(t, archive.Len())
+
+	destination, err := OpenStores(StoreOptions{
+		Type:        "bbolt",
+		Path:        filepath.Join(t.TempDir(), "pcbook.bolt"),
+		ImageFolder: t.TempDir(),
+	})
+	require.NoError(t, err)
+	defer destination.Close()
+
+	stale := genarator.NewLaptop()
+	require.NoError(t, destination.Laptops.Save(stale))
+
+	destinationClient := startTestBackupClient(t, startTestBackupServer(t, destination))
+
+	res, err := restoreTestBackup(destinationClient, pb.RestoreInfo_REPLACE, archive.Bytes())
+	require.NoError(t, err)
+	require.Equal(t, uint32(3), res.GetLaptops())
+	require.Equal(t, uint32(3), res.GetRatings())
+	require.Equal(t, uint32(1), res.GetUsers())
+	require.Equal(t, uint32(3), res.GetImages())
+
+	migration := &Migration{Source: source, Destination: destination}
+	_, err = migration.Verify(context.Background())
+	require.NoError(t, err)
+
+	other, err := destination.Laptops.Find(stale.GetId())
+	require.NoError(t, err)
+	require.Nil(t, other)
+
+	other, err = destination.Laptops.Find(laptopIds[0])
+	require.NoError(t, err)
+	require.Equal(t, "acme", other.GetTenant())
+	require.Equal(t, "admin", destination.Users.Find("admin1").Role)
+
+	// merging keeps what the destination has on top of the archive
+	require.NoError(t, destination.Laptops.Save(stale))
+	res, err = restoreTestBackup(destinationClient, pb.RestoreInfo_MERGE, archive.Bytes())
+	require.NoError(t, err)
+	require.Zero(t, res.GetLaptops())
+
+	other, err = destination.Laptops.Find(stale.GetId())
+	require.NoError(t, err)
+	require.NotNil(t, other)
+
+	_, err = restoreTestBackup(destinationClient, pb.RestoreInfo_UNKNOWN, archive.Bytes())
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// a damaged archive is rejected before anything is restored
+	damaged := append([]byte{}, archive.Bytes()...)
+	damaged[len(damaged)/2] ^= 0xff
+	_, err = restoreTestBackup(destinationClient, pb.RestoreInfo_REPLACE, damaged)
+	require.Equal(t, codes.DataLoss, status.Code(err))
+
+	other, err = destination.Laptops.Find(stale.GetId())
+	require.NoError(t, err)
+	require.NotNil(t, other)
+}
+
+func TestReadBackupDamaged(t *testing.T) {
+	t.Parallel()
+
+	source := newTestBackupStores(t)
+	for i := 0; i < 3; i++ {
+		require.NoError(t, source.Laptops.Save(genarator.NewLaptop()))
+	}
+
+	archive := bytes.Buffer{}
+	require.NoError(t, WriteBackup(context.Background(), &archive, source, nil))
+
+	// offsets of the record boundaries: header, 3 laptops, trailer
+	offsets := []int64{0}
+	reader := bufio.NewReader(bytes.NewReader(archive.Bytes()))
+	for {
+		_, n, err := readRecordPayload(reader)
+		if err != nil {
+			break
+		}
+		offsets = append(offsets, offsets[len(offsets)-1]+n)
+	}
+	require.Len(t, offsets, 6)
+
+	testCases := []struct {
+		name   string
+		damage func(data []byte) []byte
+		err    error
+	}{
+		{
+			name: "intact",
+			damage: func(data []byte) []byte {
+				return data
+			},
+		},
+		{
+			name: "truncated",
+			damage: func(data []byte) []byte {
+				return data[:offsets[4]]
+			},
+			err: ErrInvalidBackup,
+		},
+		{
+			name: "bit_flip",
+			damage: func(data []byte) []byte {
+				data[offsets[2]+8] ^= 0xff
+				return data
+			},
+			err: ErrInvalidBackup,
+		},
+		{
+			name: "record_dropped",
+			damage: func(data []byte) []byte {
+				return append(data[:offsets[2]:offsets[2]], data[offsets[3]:]...)
+			},
+			err: ErrChecksumMismatch,
+		},
+		{
+			name: "trailing_data",
+			damage: func(data []byte) []byte {
+				return append(data, data[offsets[1]:offsets[2]]...)
+			},
+			err: ErrInvalidBackup,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			data := tc.damage(append([]byte{}, archive.Bytes()...))
+			err := ReadBackup(context.Background(), bytes.NewReader(data), newTestBackupStores(t))
+			if tc.err == nil {
+				require.NoError(t, err)
+			} else {
+				require.ErrorIs(t, err, tc.err)
+			}
+		})
+	}
+}
+
+func TestServerBackupTenant(t *testing.T) {
+	t.Parallel()
+
+	require.NoError(t, authorizeBackup(ContextWithUserClaims(context.Background(), &UserClaims{Role: "admin"})))
+	require.NoError(t, authorizeBackup(ContextWithUserClaims(context.Background(), &UserClaims{Role: SuperAdminRole})))
+
+	err := authorizeBackup(ContextWithUserClaims(context.Background(), &UserClaims{Role: "admin", Tenant: "acme"}))
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+func newTestBackupStores(t *testing.T) *Stores {
+	stores, err := OpenStores(StoreOptions{Type: "memory", ImageFolder: t.TempDir()})
+	require.NoError(t, err)
+
+	return stores
+}
+
+func restoreTestBackup(client pb.BackupServiceClient, mode pb.RestoreInfo_Mode, archive []byte) (*pb.RestoreResponse, error) {
+	stream, err := client.Restore(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	err = stream.Send(&pb.RestoreRequest{Data: &pb.RestoreRequest_Info{Info: &pb.RestoreInfo{Mode: mode}}})
+	for len(archive) > 0 && err == nil {
+		n := len(archive)
+		if n > 1000 {
+			n = 1000
+		}
+		err = stream.Send(&pb.RestoreRequest{Data: &pb.RestoreRequest_ChunkData{ChunkData: archive[:n]}})
+		archive = archive[n:]
+	}
+
+	return stream.CloseAndRecv()
+}
+
+func startTestBackupServer(t *testing.T, stores *Stores) string {
+	backupServer := NewBackupServer(stores, NewWriteBarrier())
+
+	grpcServer := grpc.NewServer()
+	pb.RegisterBackupServiceServer(grpcServer, backupServer)
+
+	listener, err := net.Listen("tcp", ":0")
+	require.NoError(t, err)
+
+	go grpcServer.Serve(listener)
+
+	return listener.Addr().String()
+}
+
+func startTestBackupClient(t *testing.T, address string) pb.BackupServiceClient {
+	conn, err := grpc.Dial(address, grpc.WithInsecure())
+	require.NoError(t, err)
+
+	return pb.NewBackupServiceClient(conn)
+}
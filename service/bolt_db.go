@@ -0,0 +1,39 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	boltLaptopsBucket = []byte("laptops")
+	boltRatingsBucket = []byte("ratings")
+	boltUsersBucket   = []byte("users")
+)
+
+// OpenBoltDB opens the bbolt file shared by the bolt stores and creates their
+// buckets. Every write is committed with an fsync, and bbolt recovers from an
+// unclean shutdown by falling back to the last valid meta page on open.
+func OpenBoltDB(path string) (*bolt.DB, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("cannot open bolt database: %v", err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, bucket := range [][]byte{boltLaptopsBucket, boltRatingsBucket, boltUsersBucket} {
+			if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+				return fmt.Errorf("cannot create bucket %s: %v", bucket, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
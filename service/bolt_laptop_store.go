@@ -0,0 +1,141 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+
+	pb "github.com/orkhanrustamli/pcbook/pcbook_proto/go"
+	bolt "go.etcd.io/bbolt"
+	"google.golang.org/protobuf/proto"
+)
+
+type BoltLaptopStore struct {
+	db *bolt.DB
+}
+
+func NewBoltLaptopStore(db *bolt.DB) *BoltLaptopStore {
+	return &BoltLaptopStore{db}
+}
+
+func (store *BoltLaptopStore) Save(laptop *pb.Laptop) error {
+	data, err := proto.Marshal(laptop)
+	if err != nil {
+		return fmt.Errorf("cannot marshal laptop: %v", err)
+	}
+
+	return store.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltLaptopsBucket)
+		if bucket.Get([]byte(laptop.GetId())) != nil {
+			return ErrAlreadyExists
+		}
+
+		return bucket.Put([]byte(laptop.GetId()), data)
+	})
+}
+
+func (store *BoltLaptopStore) Find(id string) (*pb.Laptop, error) {
+	var laptop *pb.Laptop
+	err := store.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(boltLaptopsBucket).Get([]byte(id))
+		if data == nil {
+			return nil
+		}
+
+		var err error
+		laptop, err = unmarshalLaptop(data)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return laptop, nil
+}
+
+func (store *BoltLaptopStore) Delete(id string) error {
+	return store.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltLaptopsBucket)
+		if bucket.Get([]byte(id)) == nil {
+			return ErrNotFound
+		}
+
+		return bucket.Delete([]byte(id))
+	})
+}
+
+// boltSearchBatchSize is how many matching laptops Search copies out of one
+// read-only transaction before handing them to found
+const boltSearchBatchSize = 256
+
+// Search reads the bucket in batches, each in its own read-only transaction,
+// and calls found only after the transaction is closed. A slow receiver thus
+// never holds a transaction open, but writes committed between two batches
+// may or may not be seen.
+func (store *BoltLaptopStore) Search(
+	ctx context.Context,
+	filter *pb.Filter,
+	found func(laptop *pb.Laptop) error,
+) (SearchStats, error) {
+	stats := SearchStats{}
+	var last []byte
+	for {
+		batch := make([]*pb.Laptop, 0, boltSearchBatchSize)
+		done := true
+		err := store.db.View(func(tx *bolt.Tx) error {
+			cursor := tx.Bucket(boltLaptopsBucket).Cursor()
+			key, data := cursor.First()
+			if last != nil {
+				key, data = cursor.Seek(last)
+				if key != nil && bytes.Equal(key, last) {
+					key, data = cursor.Next()
+				}
+			}
+
+			for ; key != nil; key, data = cursor.Next() {
+				if err := ctx.Err(); err != nil {
+					log.Println("Context is cancelled or timed out!")
+					return err
+				}
+
+				laptop, err := unmarshalLaptop(data)
+				if err != nil {
+					return err
+				}
+
+				stats.Scanned++
+				if isQualified(filter, laptop) {
+					batch = append(batch, laptop)
+				}
+
+				if len(batch) == boltSearchBatchSize {
+					// the key is only valid for the life of the transaction
+					last = append(last[:0], key...)
+					done = false
+					return nil
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return stats, err
+		}
+
+		for _, laptop := range batch {
+			if err := ctx.Err(); err != nil {
+				log.Println("Context is cancelled or timed out!")
+				return stats, err
+			}
+
+			stats.Matched++
+			if err := found(laptop); err != nil {
+				return stats, err
+			}
+		}
+
+		if done {
+			return stats, nil
+		}
+	}
+}
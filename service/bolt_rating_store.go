@@ -0,0 +1,125 @@
+package service
+
+import (
+	"fmt"
+	"log"
+
+	pb "github.com/orkhanrustamli/pcbook/pcbook_proto/go"
+	bolt "go.etcd.io/bbolt"
+	"google.golang.org/protobuf/proto"
+)
+
+type BoltRatingStore struct {
+	db *bolt.DB
+}
+
+func NewBoltRatingStore(db *bolt.DB) *BoltRatingStore {
+	return &BoltRatingStore{db}
+}
+
+func (store *BoltRatingStore) Rate(laptopId string, score float64) (*Rating, error) {
+	return store.update(laptopId, 1, score)
+}
+
+func (store *BoltRatingStore) Unrate(laptopId string, score float64) (*Rating, error) {
+	return store.update(laptopId, -1, -score)
+}
+
+// update adds count and sum to the rating of the laptop in one transaction,
+// and removes the rating once no score is left in it
+func (store *BoltRatingStore) update(laptopId string, count int, sum float64) (*Rating, error) {
+	record := &pb.RatingRecord{}
+	err := store.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltRatingsBucket)
+		if data := bucket.Get([]byte(laptopId)); data != nil {
+			if err := proto.Unmarshal(data, record); err != nil {
+				return fmt.Errorf("cannot unmarshal rating: %v", err)
+			}
+		}
+
+		if int(record.Count)+count <= 0 {
+			record.Count = 0
+			return bucket.Delete([]byte(laptopId))
+		}
+		record.Count = uint32(int(record.Count) + count)
+		record.Sum += sum
+
+		data, err := proto.Marshal(record)
+		if err != nil {
+			return fmt.Errorf("cannot marshal rating: %v", err)
+		}
+
+		return bucket.Put([]byte(laptopId), data)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if record.Count == 0 {
+		return nil, nil
+	}
+
+	return NewRating(int(record.Count), record.Sum), nil
+}
+
+func (store *BoltRatingStore) Set(laptopId string, rating *Rating) error {
+	data, err := proto.Marshal(&pb.RatingRecord{
+		Count: uint32(rating.count),
+		Sum:   rating.sum,
+	})
+	if err != nil {
+		return fmt.Errorf("cannot marshal rating: %v", err)
+	}
+
+	return store.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltRatingsBucket).Put([]byte(laptopId), data)
+	})
+}
+
+func (store *BoltRatingStore) Delete(laptopId string) error {
+	return store.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltRatingsBucket)
+		if bucket.Get([]byte(laptopId)) == nil {
+			return ErrNotFound
+		}
+
+		return bucket.Delete([]byte(laptopId))
+	})
+}
+
+// List runs in a read-only transaction, keys come out of bbolt in order
+func (store *BoltRatingStore) List(found func(laptopId string, rating *Rating) error) error {
+	return store.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltRatingsBucket).ForEach(func(key, data []byte) error {
+			record := &pb.RatingRecord{}
+			if err := proto.Unmarshal(data, record); err != nil {
+				return fmt.Errorf("cannot unmarshal rating: %v", err)
+			}
+
+			return found(string(key), NewRating(int(record.Count), record.Sum))
+		})
+	})
+}
+
+func (store *BoltRatingStore) Find(laptopId string) *Rating {
+	var rating *Rating
+	err := store.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(boltRatingsBucket).Get([]byte(laptopId))
+		if data == nil {
+			return nil
+		}
+
+		record := &pb.RatingRecord{}
+		if err := proto.Unmarshal(data, record); err != nil {
+			return fmt.Errorf("cannot unmarshal rating: %v", err)
+		}
+
+		rating = NewRating(int(record.Count), record.Sum)
+		return nil
+	})
+	if err != nil {
+		log.Printf("Cannot find rating of laptop %s: %v", laptopId, err)
+		return nil
+	}
+
+	return rating
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/orkhanrustamli/pcbook/genarator"
+	pb "github.com/orkhanrustamli/pcbook/pcbook_proto/go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBoltStoresReopen(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "pcbook.bolt")
+
+	db, err := OpenBoltDB(path)
+	require.NoError(t, err)
+
+	laptop := genarator.NewLaptop()
+	require.NoError(t, NewBoltLaptopStore(db).Save(laptop))
+	require.ErrorIs(t, NewBoltLaptopStore(db).Save(laptop), ErrAlreadyExists)
+
+	ratingStore := NewBoltRatingStore(db)
+	ratingStore.Rate(laptop.GetId(), 8)
+	rating, err := ratingStore.Rate(laptop.GetId(), 6)
+	require.NoError(t, err)
+	require.Equal(t, 2, rating.count)
+	require.Equal(t, 14.0, rating.sum)
+
+	user, err := NewUser("user1", "secret", "user", "")
+	require.NoError(t, err)
+	require.NoError(t, NewBoltUserStore(db).Save(user))
+	require.ErrorIs(t, NewBoltUserStore(db).Save(user), ErrAlreadyExists)
+
+	require.NoError(t, db.Close())
+
+	// a failed write is reported instead of looking like a missing rating
+	_, err = ratingStore.Rate(laptop.GetId(), 1)
+	require.Error(t, err)
+
+	db, err = OpenBoltDB(path)
+	require.NoError(t, err)
+	defer db.Close()
+
+	other, err := NewBoltLaptopStore(db).Find(laptop.GetId())
+	require.NoError(t, err)
+	requireSameLaptop(t, laptop, other)
+
+	stats, err := NewBoltLaptopStore(db).Search(context.Background(), nil, func(laptop *pb.Laptop) error {
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, stats.Matched)
+
+	rating = NewBoltRatingStore(db).Find(laptop.GetId())
+	require.NotNil(t, rating)
+	require.Equal(t, 2, rating.count)
+	require.Nil(t, NewBoltRatingStore(db).Find("unknown"))
+
+	otherUser := NewBoltUserStore(db).Find("user1")
+	require.NotNil(t, otherUser)
+	require.Equal(t, "user", otherUser.Role)
+	require.True(t, otherUser.IsCorrectPassword("secret"))
+	require.Nil(t, NewBoltUserStore(db).Find("unknown"))
+}
+
+func TestBoltLaptopStoreSearchBatches(t *testing.T) {
+	t.Parallel()
+
+	db, err := OpenBoltDB(filepath.Join(t.TempDir(), "pcbook.bolt"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	store := NewBoltLaptopStore(db)
+	n := 2*boltSearchBatchSize + 1
+	for i := 0; i < n; i++ {
+		require.NoError(t, store.Save(genarator.NewLaptop()))
+	}
+
+	seen := make(map[string]bool)
+	stats, err := store.Search(context.Background(), nil, func(laptop *pb.Laptop) error {
+		require.False(t, seen[laptop.GetId()])
+		seen[laptop.GetId()] = true
+
+		// no read transaction is open while found runs, so writing is safe
+		if len(seen) == boltSearchBatchSize {
+			return store.Delete(laptop.GetId())
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, n, stats.Matched)
+	require.Len(t, seen, n)
+}
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"fmt"
+	"log"
+
+	pb "github.com/orkhanrustamli/pcbook/pcbook_proto/go"
+	bolt "go.etcd.io/bbolt"
+	"google.golang.org/protobuf/proto"
+)
+
+type BoltUserStore struct {
+	db *bolt.DB
+}
+
+func NewBoltUserStore(db *bolt.DB) *BoltUserStore {
+	return &BoltUserStore{db}
+}
+
+func (store *BoltUserStore) Save(user *User) error {
+	data, err := proto.Marshal(&pb.UserRecord{
+		Username:     user.Username,
+		PasswordHash: user.Password,
+		Role:         user.Role,
+		Tenant:       user.Tenant,
+	})
+	if err != nil {
+		return fmt.Errorf("cannot marshal user: %v", err)
+	}
+
+	return store.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltUsersBucket)
+		if bucket.Get([]byte(user.Username)) != nil {
+			return ErrAlreadyExists
+		}
+
+		return bucket.Put([]byte(user.Username), data)
+	})
+}
+
+func (store *BoltUserStore) Find(username string) *User {
+	var user *User
+	err := store.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(boltUsersBucket).Get([]byte(username))
+		if data == nil {
+			return nil
+		}
+
+		var err error
+		user, err = unmarshalUser(data)
+		return err
+	})
+	if err != nil {
+		log.Printf("Cannot find user %s: %v", username, err)
+		return nil
+	}
+
+	return user
+}
+
+func (store *BoltUserStore) List(found func(user *User) error) error {
+	return store.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltUsersBucket).ForEach(func(key, data []byte) error {
+			user, err := unmarshalUser(data)
+			if err != nil {
+				return err
+			}
+
+			return found(user)
+		})
+	})
+}
+
+func (store *BoltUserStore) Delete(username string) error {
+	return store.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltUsersBucket)
+		if bucket.Get([]byte(username)) == nil {
+			return ErrNotFound
+		}
+
+		return bucket.Delete([]byte(username))
+	})
+}
+
+func unmarshalUser(data []byte) (*User, error) {
+	record := &pb.UserRecord{}
+	if err := proto.Unmarshal(data, record); err != nil {
+		return nil, fmt.Errorf("cannot unmarshal user: %v", err)
+	}
+
+	return &User{
+		Username: record.GetUsername(),
+		Password: record.GetPasswordHash(),
+		Role:     record.GetRole(),
+		Tenant:   record.GetTenant(),
+	}, nil
+}
@@ -0,0 +1,151 @@
+package service
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	pb "github.com/orkhanrustamli/pcbook/pcbook_proto/go"
+	"google.golang.org/protobuf/proto"
+)
+
+type CacheStats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+}
+
+// CachedLaptopStore keeps the most recently found laptops of another store
+// in a size-bounded LRU. Entries expire after ttl, and every write through
+// the cache invalidates the laptop it touches. Search is not cached.
+type CachedLaptopStore struct {
+	LaptopStore
+
+	mutex   sync.Mutex
+	size    int
+	ttl     time.Duration
+	now     func() time.Time
+	order   *list.List
+	entries map[string]*list.Element
+	version uint64
+	stats   CacheStats
+}
+
+type cacheEntry struct {
+	id        string
+	laptop    *pb.Laptop
+	expiresAt time.Time
+}
+
+// NewCachedLaptopStore caches up to size laptops of store. A ttl of 0 keeps
+// entries until they are evicted or invalidated.
+func NewCachedLaptopStore(store LaptopStore, size int, ttl time.Duration) *CachedLaptopStore {
+	return &CachedLaptopStore{
+		LaptopStore: store,
+		size:        size,
+		ttl:         ttl,
+		now:         time.Now,
+		order:       list.New(),
+		entries:     make(map[string]*list.Element),
+	}
+}
+
+func (store *CachedLaptopStore) Save(laptop *pb.Laptop) error {
+	defer store.invalidate(laptop.GetId())
+
+	return store.LaptopStore.Save(laptop)
+}
+
+func (store *CachedLaptopStore) Delete(id string) error {
+	defer store.invalidate(id)
+	return store.LaptopStore.Delete(id)
+}
+
+func (store *CachedLaptopStore) Find(id string) (*pb.Laptop, error) {
+	store.mutex.Lock()
+	if laptop := store.get(id); laptop != nil {
+		store.stats.Hits++
+		store.mutex.Unlock()
+		return proto.Clone(laptop).(*pb.Laptop), nil
+	}
+	store.stats.Misses++
+	version := store.version
+	store.mutex.Unlock()
+
+	laptop, err := store.LaptopStore.Find(id)
+	if err != nil || laptop == nil {
+		return laptop, err
+	}
+
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	// a write that happened while the laptop was loaded may have made it stale
+	if store.version == version {
+		store.put(id, proto.Clone(laptop).(*pb.Laptop))
+	}
+
+	return laptop, nil
+}
+
+func (store *CachedLaptopStore) Stats() CacheStats {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	return store.stats
+}
+
+func (store *CachedLaptopStore) invalidate(id string) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	store.version++
+	if element := store.entries[id]; element != nil {
+		store.remove(element)
+	}
+}
+
+func (store *CachedLaptopStore) get(id string) *pb.Laptop {
+	element := store.entries[id]
+	if element == nil {
+		return nil
+	}
+
+	entry := element.Value.(*cacheEntry)
+	if store.ttl > 0 && !store.now().Before(entry.expiresAt) {
+		store.remove(element)
+		return nil
+	}
+
+	store.order.MoveToFront(element)
+	return entry.laptop
+}
+
+func (store *CachedLaptopStore) put(id string, laptop *pb.Laptop) {
+	if store.size <= 0 {
+		return
+	}
+
+	entry := &cacheEntry{
+		id:        id,
+		laptop:    laptop,
+		expiresAt: store.now().Add(store.ttl),
+	}
+
+	if element := store.entries[id]; element != nil {
+		element.Value = entry
+		store.order.MoveToFront(element)
+		return
+	}
+
+	store.entries[id] = store.order.PushFront(entry)
+	for store.order.Len() > store.size {
+		store.remove(store.order.Back())
+		store.stats.Evictions++
+	}
+}
+
+func (store *CachedLaptopStore) remove(element *list.Element) {
+	entry := store.order.Remove(element).(*cacheEntry)
+	delete(store.entries, entry.id)
+}
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/orkhanrustamli/pcbook/genarator"
+	pb "github.com/orkhanrustamli/pcbook/pcbook_proto/go"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestCachedLaptopStore(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	store := NewCachedLaptopStore(NewInMemoryLaptopStore(), 2, time.Minute)
+	store.now = func() time.Time { return now }
+
+	laptops := make([]*pb.Laptop, 3)
+	for i := range laptops {
+		laptops[i] = genarator.NewLaptop()
+		require.NoError(t, store.Save(laptops[i]))
+	}
+
+	find := func(laptop *pb.Laptop) {
+		other, err := store.Find(laptop.GetId())
+		require.NoError(t, err)
+		require.True(t, proto.Equal(laptop, other))
+	}
+
+	find(laptops[0])
+	find(laptops[0])
+	require.Equal(t, CacheStats{Hits: 1, Misses: 1}, store.Stats())
+
+	// laptops[0] was used more recently than laptops[1], so it stays cached
+	find(laptops[1])
+	find(laptops[0])
+	find(laptops[2])
+	require.Equal(t, CacheStats{Hits: 2, Misses: 3, Evictions: 1}, store.Stats())
+	find(laptops[0])
+	find(laptops[1])
+	require.Equal(t, CacheStats{Hits: 3, Misses: 4, Evictions: 2}, store.Stats())
+
+	now = now.Add(time.Minute)
+	find(laptops[1])
+	require.Equal(t, CacheStats{Hits: 3, Misses: 5, Evictions: 2}, store.Stats())
+
+	require.ErrorIs(t, store.Save(laptops[1]), ErrAlreadyExists)
+	find(laptops[1])
+	require.Equal(t, CacheStats{Hits: 3, Misses: 6, Evictions: 2}, store.Stats())
+
+	missing, err := store.Find(genarator.NewLaptop().GetId())
+	require.NoError(t, err)
+	require.Nil(t, missing)
+}
@@ -0,0 +1,616 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+
+	pb "github.com/orkhanrustamli/pcbook/pcbook_proto/go"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// ClusterForwardedKey marks a request sent by another node of the cluster,
+// which the receiving node always handles by itself
+const ClusterForwardedKey = "cluster-forwarded"
+
+// ClusterConfig is the static membership of a cluster, shared by all of its
+// nodes
+type ClusterConfig struct {
+	VirtualNodes int           `json:"virtual_nodes"`
+	Nodes        []ClusterNode `json:"nodes"`
+}
+
+type ClusterNode struct {
+	Id      string `json:"id"`
+	Address string `json:"address"`
+}
+
+// LoadClusterConfig reads a cluster config from a JSON file
+func LoadClusterConfig(path string) (*ClusterConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read cluster config: %w", err)
+	}
+
+	config := &ClusterConfig{}
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("cannot parse cluster config: %w", err)
+	}
+
+	return config, nil
+}
+
+func (config *ClusterConfig) validate() error {
+	if len(config.Nodes) == 0 {
+		return errors.New("cluster has no nodes")
+	}
+
+	ids := make(map[string]bool)
+	for _, node := range config.Nodes {
+		if node.Id == "" || node.Address == "" {
+			return fmt.Errorf("cluster node %q needs both an id and an address", node.Id)
+		}
+		if ids[node.Id] {
+			return fmt.Errorf("cluster node %q is listed twice", node.Id)
+		}
+		ids[node.Id] = true
+	}
+
+	return nil
+}
+
+// Cluster spreads laptops over the nodes of a ClusterConfig by consistent
+// hashing of their ID. A node forwards the requests about laptops it does
+// not own to their owner, and searches every node for the requests that
+// filter the whole catalog.
+type Cluster struct {
+	self  string
+	ring  *HashRing
+	nodes []*clusterPeer
+	peers map[string]*clusterPeer
+}
+
+type clusterPeer struct {
+	id     string
+	conn   *grpc.ClientConn
+	client pb.LaptopServiceClient
+}
+
+// NewCluster connects to every node of config, self included, and acts as
+// the node with the ID self
+func NewCluster(config *ClusterConfig, self string, options ...grpc.DialOption) (*Cluster, error) {
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+
+	cluster := &Cluster{
+		self:  self,
+		peers: make(map[string]*clusterPeer),
+	}
+	ids := []string{}
+	for _, node := range config.Nodes {
+		conn, err := grpc.Dial(node.Address, options...)
+		if err != nil {
+			cluster.Close()
+			return nil, fmt.Errorf("cannot dial cluster node %s: %w", node.Id, err)
+		}
+
+		peer := &clusterPeer{
+			id:     node.Id,
+			conn:   conn,
+			client: pb.NewLaptopServiceClient(conn),
+		}
+		cluster.nodes = append(cluster.nodes, peer)
+		cluster.peers[node.Id] = peer
+		ids = append(ids, node.Id)
+	}
+
+	if cluster.peers[self] == nil {
+		cluster.Close()
+		return nil, fmt.Errorf("node %s is not in the cluster", self)
+	}
+	cluster.ring = NewHashRing(ids, config.VirtualNodes)
+
+	return cluster, nil
+}
+
+func (cluster *Cluster) Close() error {
+	var err error
+	for _, peer := range cluster.nodes {
+		if closeErr := peer.conn.Close(); err == nil {
+			err = closeErr
+		}
+	}
+
+	return err
+}
+
+// Owner returns the ID of the node that owns the laptop
+func (cluster *Cluster) Owner(laptopId string) string {
+	return cluster.ring.Owner(laptopId)
+}
+
+// forward returns the node a request about the laptop has to be sent to, or
+// false if this node handles it. It is safe to call on a nil cluster.
+func (cluster *Cluster) forward(ctx context.Context, laptopId string) (*clusterPeer, bool) {
+	if cluster == nil || isForwarded(ctx) {
+		return nil, false
+	}
+
+	owner := cluster.Owner(laptopId)
+	if owner == cluster.self {
+		return nil, false
+	}
+
+	return cluster.peers[owner], true
+}
+
+// scatter tells if a request over the whole catalog has to be sent to every
+// node. It is safe to call on a nil cluster.
+func (cluster *Cluster) scatter(ctx context.Context) bool {
+	return cluster != nil && !isForwarded(ctx)
+}
+
+// coordinated tells if the request was forwarded by the node that merges
+// the results of every node. It is safe to call on a nil cluster.
+func (cluster *Cluster) coordinated(ctx context.Context) bool {
+	return cluster != nil && isForwarded(ctx)
+}
+
+func isForwarded(ctx context.Context) bool {
+	md, ok := metadata.FromIncomingContext(ctx)
+	return ok && len(md.Get(ClusterForwardedKey)) > 0
+}
+
+// forwardContext carries the access token of the incoming request, and the
+// tenant a superadmin acts as, over to the node it is forwarded to, so that
+// it is authorized and scoped alike
+func forwardContext(ctx context.Context) context.Context {
+	md := metadata.Pairs(ClusterForwardedKey, "true")
+	if incoming, ok := metadata.FromIncomingContext(ctx); ok {
+		for _, key := range []string{"authorization", tenantMetadataKey} {
+			if values := incoming.Get(key); len(values) > 0 {
+				md.Set(key, values...)
+			}
+		}
+	}
+
+	return metadata.NewOutgoingContext(ctx, md)
+}
+
+// forwardUploadImage sends the info already received and every chunk left in
+// stream to the owner of the laptop
+func (cluster *Cluster) forwardUploadImage(
+	peer *clusterPeer,
+	info *pb.UploadImageRequest,
+	stream pb.LaptopService_UploadImageServer,
+) error {
+	log.Printf("Forwarding upload-image request for laptop %s to node %s", info.GetInfo().GetLaptopId(), peer.id)
+
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+
+	upload, err := peer.client.UploadImage(forwardContext(ctx))
+	if err != nil {
+		return logAndReturnError(status.Errorf(codes.Unavailable, "Cannot forward image to node %s: %v", peer.id, err))
+	}
+
+	req := info
+	for {
+		if err := upload.Send(req); err != nil {
+			// the owner rejected the upload, its status tells why
+			break
+		}
+
+		req, err = stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return logAndReturnError(status.Errorf(codes.Unknown, "Cannot receive image chunk: %v", err))
+		}
+	}
+
+	res, err := upload.CloseAndRecv()
+	if err != nil {
+		return err
+	}
+
+	return stream.SendAndClose(res)
+}
+
+// forwardDownloadImage asks the other nodes for an image this node does not
+// have, until one of them has it. The ID of an image does not tell which
+// node owns it.
+func (cluster *Cluster) forwardDownloadImage(
+	req *pb.DownloadImageRequest,
+	stream pb.LaptopService_DownloadImageServer,
+) error {
+	for _, peer := range cluster.nodes {
+		if peer.id == cluster.self {
+			continue
+		}
+
+		found, err := cluster.downloadImageFrom(peer, req, stream)
+		if found || err != nil {
+			return err
+		}
+	}
+
+	return logAndReturnError(status.Errorf(codes.NotFound, "Image %s does not exists", req.GetImageId()))
+}
+
+// downloadImageFrom relays the image from peer, it returns false if the peer
+// does not have it
+func (cluster *Cluster) downloadImageFrom(
+	peer *clusterPeer,
+	req *pb.DownloadImageRequest,
+	stream pb.LaptopService_DownloadImageServer,
+) (bool, error) {
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+
+	download, err := peer.client.DownloadImage(forwardContext(ctx), req)
+	if err != nil {
+		return false, logAndReturnError(status.Errorf(codes.Unavailable, "Cannot download image from node %s: %v", peer.id, err))
+	}
+
+	res, err := download.Recv()
+	if status.Code(err) == codes.NotFound {
+		return false, nil
+	}
+
+	for {
+		if err == io.EOF {
+			return true, nil
+		}
+		if err != nil {
+			return true, err
+		}
+
+		if err := stream.Send(res); err != nil {
+			return true, logAndReturnError(status.Errorf(codes.Unknown, "Cannot send image: %v", err))
+		}
+
+		res, err = download.Recv()
+	}
+}
+
+// forwardDeleteImage asks the other nodes to delete an image this node does
+// not have, until one of them has it
+func (cluster *Cluster) forwardDeleteImage(
+	ctx context.Context,
+	req *pb.DeleteImageRequest,
+) (*pb.DeleteImageResponse, error) {
+	for _, peer := range cluster.nodes {
+		if peer.id == cluster.self {
+			continue
+		}
+
+		res, err := peer.client.DeleteImage(forwardContext(ctx), req)
+		if status.Code(err) != codes.NotFound {
+			return res, err
+		}
+	}
+
+	return nil, logAndReturnError(status.Errorf(codes.NotFound, "Image %s does not exists", req.GetImageId()))
+}
+
+// findUpload asks the other nodes which one keeps an upload session, since
+// its ID does not tell. It returns nil if none of them does.
+func (cluster *Cluster) findUpload(ctx context.Context, sessionId string) (*clusterPeer, error) {
+	for _, peer := range cluster.nodes {
+		if peer.id == cluster.self {
+			continue
+		}
+
+		_, err := peer.client.QueryUpload(forwardContext(ctx), &pb.QueryUploadRequest{SessionId: sessionId})
+		if err == nil {
+			return peer, nil
+		}
+		if status.Code(err) != codes.NotFound {
+			return nil, err
+		}
+	}
+
+	return nil, nil
+}
+
+// forwardUploadChunk relays the chunks of an upload session to the node that
+// keeps it
+func (cluster *Cluster) forwardUploadChunk(
+	peer *clusterPeer,
+	first *pb.UploadChunkRequest,
+	stream pb.LaptopService_UploadChunkServer,
+) error {
+	log.Printf("Forwarding upload-chunk request for session %s to node %s", first.GetSessionId(), peer.id)
+
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+
+	upload, err := peer.client.UploadChunk(forwardContext(ctx))
+	if err != nil {
+		return logAndReturnError(status.Errorf(codes.Unavailable, "Cannot forward chunk to node %s: %v", peer.id, err))
+	}
+
+	req := first
+	for {
+		if err := upload.Send(req); err != nil {
+			// the owner rejected the chunk, its status tells why
+			break
+		}
+
+		req, err = stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return logAndReturnError(status.Errorf(codes.Unknown, "Cannot receive chunk: %v", err))
+		}
+	}
+
+	res, err := upload.CloseAndRecv()
+	if err != nil {
+		return err
+	}
+
+	return stream.SendAndClose(res)
+}
+
+// ratingForwarder keeps one RateLaptop stream open to each node that owns
+// some of the laptops rated over a single incoming stream
+type ratingForwarder struct {
+	ctx     context.Context
+	streams map[string]pb.LaptopService_RateLaptopClient
+}
+
+func newRatingForwarder(ctx context.Context) *ratingForwarder {
+	return &ratingForwarder{
+		ctx:     forwardContext(ctx),
+		streams: make(map[string]pb.LaptopService_RateLaptopClient),
+	}
+}
+
+// rate sends req to the owner of the laptop and waits for its answer, so
+// that answers come back in the order of the requests
+func (forwarder *ratingForwarder) rate(peer *clusterPeer, req *pb.RateLaptopRequest) (*pb.RateLaptopResponse, error) {
+	stream, ok := forwarder.streams[peer.id]
+	if !ok {
+		var err error
+		stream, err = peer.client.RateLaptop(forwarder.ctx)
+		if err != nil {
+			return nil, status.Errorf(codes.Unavailable, "Cannot forward rating to node %s: %v", peer.id, err)
+		}
+		forwarder.streams[peer.id] = stream
+	}
+
+	if err := stream.Send(req); err != nil && err != io.EOF {
+		return nil, status.Errorf(codes.Unavailable, "Cannot forward rating to node %s: %v", peer.id, err)
+	}
+
+	return stream.Recv()
+}
+
+func (forwarder *ratingForwarder) close() {
+	for _, stream := range forwarder.streams {
+		stream.CloseSend()
+	}
+}
+
+type clusterSearchResult struct {
+	peer    *clusterPeer
+	res     *pb.SearchLaptopResponse
+	trailer metadata.MD
+	err     error
+}
+
+// search sends req to every node and merges their matches into stream. For
+// a ranked search the nodes only send their candidates along with their
+// ratings, which are ranked here once every node is done, since the scores
+// of each node would be normalized against its own part of the catalog.
+func (cluster *Cluster) search(req *pb.SearchLaptopRequest, stream pb.LaptopService_SearchLaptopServer) error {
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+
+	results := make(chan *clusterSearchResult)
+	for _, peer := range cluster.nodes {
+		go cluster.searchNode(ctx, peer, req, results)
+	}
+
+	var stats SearchStats
+	var stopErr error
+	var suggestion *pb.SearchLaptopResponse
+	candidates := []*pb.SearchLaptopResponse{}
+
+	for remaining := len(cluster.nodes); remaining > 0; {
+		result := <-results
+
+		switch {
+		case result.err != nil:
+			st := status.Convert(result.err)
+			return logAndReturnError(status.Errorf(st.Code(), "Cannot search node %s: %s", result.peer.id, st.Message()))
+		case result.res == nil:
+			remaining--
+
+			nodeStats, nodeErr := parseSearchTrailer(result.trailer)
+			stats.Scanned += nodeStats.Scanned
+			stats.Matched += nodeStats.Matched
+			if stopErr == nil {
+				stopErr = nodeErr
+			}
+		case result.res.GetDidYouMean() != nil:
+			if suggestion == nil {
+				suggestion = result.res
+			}
+		case req.GetRanking() != nil:
+			candidates = append(candidates, result.res)
+		default:
+			if err := stream.Send(result.res); err != nil {
+				return err
+			}
+		}
+	}
+
+	if req.GetRanking() != nil {
+		laptops := make([]*pb.Laptop, len(candidates))
+		ratings := make([]*Rating, len(candidates))
+		for i, res := range candidates {
+			laptops[i] = res.GetLaptop()
+			if rating := res.GetRating(); rating != nil {
+				ratings[i] = NewRating(int(rating.GetCount()), rating.GetSum())
+			}
+		}
+
+		for _, ranked := range rankLaptops(laptops, ratings, req.GetFilter(), req.GetRanking()) {
+			res := &pb.SearchLaptopResponse{
+				Laptop: ranked.Laptop,
+				Score:  ranked.Score,
+			}
+			if err := stream.Send(res); err != nil {
+				return err
+			}
+		}
+	}
+
+	stream.SetTrailer(searchTrailer(stats, stopErr))
+
+	if stats.Matched == 0 && suggestion != nil {
+		return stream.Send(suggestion)
+	}
+
+	return nil
+}
+
+// collect searches the laptops of every node, this one included, and calls
+// found with each match. Unlike scatter it also runs for a forwarded request,
+// since the nodes it asks only search their own part of the catalog.
+func (cluster *Cluster) collect(ctx context.Context, filter *pb.Filter, found func(laptop *pb.Laptop) error) error {
+	for _, peer := range cluster.nodes {
+		stream, err := peer.client.SearchLaptop(forwardContext(ctx), &pb.SearchLaptopRequest{Filter: filter})
+		if err != nil {
+			return fmt.Errorf("cannot search node %s: %w", peer.id, err)
+		}
+
+		for {
+			res, err := stream.Recv()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return fmt.Errorf("cannot search node %s: %w", peer.id, err)
+			}
+
+			// a node without matches may send a suggestion
+			if res.GetLaptop() == nil {
+				continue
+			}
+			if err := found(res.GetLaptop()); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// searchBatch sends req to every node in turn and relays their batches
+func (cluster *Cluster) searchBatch(req *pb.SearchLaptopBatchRequest, stream pb.LaptopService_SearchLaptopBatchServer) error {
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+
+	for _, peer := range cluster.nodes {
+		batches, err := peer.client.SearchLaptopBatch(forwardContext(ctx), req)
+		if err != nil {
+			return logAndReturnError(status.Errorf(codes.Unavailable, "Cannot search node %s: %v", peer.id, err))
+		}
+
+		for {
+			res, err := batches.Recv()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				st := status.Convert(err)
+				return logAndReturnError(status.Errorf(st.Code(), "Cannot search node %s: %s", peer.id, st.Message()))
+			}
+
+			if err := stream.Send(res); err != nil {
+				return logAndReturnError(status.Errorf(codes.Unknown, "cannot send batch: %v", err))
+			}
+		}
+	}
+
+	return nil
+}
+
+// searchNode sends every response of the node to results, followed by a
+// result with its trailer or with the error that stopped it
+func (cluster *Cluster) searchNode(
+	ctx context.Context,
+	peer *clusterPeer,
+	req *pb.SearchLaptopRequest,
+	results chan<- *clusterSearchResult,
+) {
+	send := func(result *clusterSearchResult) bool {
+		result.peer = peer
+
+		select {
+		case results <- result:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	stream, err := peer.client.SearchLaptop(forwardContext(ctx), req)
+	if err != nil {
+		send(&clusterSearchResult{err: err})
+		return
+	}
+
+	for {
+		res, err := stream.Recv()
+		if err == io.EOF {
+			send(&clusterSearchResult{trailer: stream.Trailer()})
+			return
+		}
+		if err != nil {
+			send(&clusterSearchResult{err: err})
+			return
+		}
+
+		if !send(&clusterSearchResult{res: res}) {
+			return
+		}
+	}
+}
+
+// parseSearchTrailer reads back what searchTrailer wrote
+func parseSearchTrailer(trailer metadata.MD) (SearchStats, error) {
+	stats := SearchStats{}
+	if values := trailer.Get(SearchScannedTrailer); len(values) > 0 {
+		stats.Scanned, _ = strconv.Atoi(values[0])
+	}
+	if values := trailer.Get(SearchMatchedTrailer); len(values) > 0 {
+		stats.Matched, _ = strconv.Atoi(values[0])
+	}
+
+	if values := trailer.Get(SearchStopReasonTrailer); len(values) > 0 {
+		switch values[0] {
+		case SearchDeadlineExceeded:
+			return stats, context.DeadlineExceeded
+		case SearchCanceled:
+			return stats, context.Canceled
+		}
+	}
+
+	return stats, nil
+}
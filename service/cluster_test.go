@@ -0,0 +1,534 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/orkhanrustamli/pcbook/genarator"
+	pb "github.com/orkhanrustamli/pcbook/pcbook_proto/go"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// testClusterSecret signs the tokens the nodes of a test cluster accept
+const testClusterSecret = "cluster-secret"
+
+func TestHashRing(t *testing.T) {
+	t.Parallel()
+
+	keys := make([]string, 3000)
+	for i := range keys {
+		keys[i] = uuid.NewString()
+	}
+
+	ring := NewHashRing([]string{"node1", "node2", "node3"}, 0)
+	reordered := NewHashRing([]string{"node3", "node1", "node2"}, 0)
+
+	owned := make(map[string]int)
+	for _, key := range keys {
+		owner := ring.Owner(key)
+		require.Equal(t, owner, reordered.Owner(key))
+		owned[owner]++
+	}
+	for _, node := range []string{"node1", "node2", "node3"} {
+		require.Greater(t, owned[node], len(keys)/6, node)
+	}
+
+	// a new node only takes keys, it never moves them between the others
+	grown := NewHashRing([]string{"node1", "node2", "node3", "node4"}, 0)
+	moved := 0
+	for _, key := range keys {
+		if owner := grown.Owner(key); owner != ring.Owner(key) {
+			require.Equal(t, "node4", owner)
+			moved++
+		}
+	}
+	require.Greater(t, moved, 0)
+	require.Less(t, moved, len(keys)/2)
+
+	require.Empty(t, NewHashRing(nil, 0).Owner("key"))
+}
+
+func TestClusterConfig(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name   string
+		config string
+		err    string
+	}{
+		{
+			name:   "valid",
+			config: `{"nodes": [{"id": "node1", "address": "0.0.0.0:8080"}, {"id": "node2", "address": "0.0.0.0:8081"}]}`,
+		},
+		{
+			name:   "no nodes",
+			config: `{"nodes": []}`,
+			err:    "cluster has no nodes",
+		},
+		{
+			name:   "missing address",
+			config: `{"nodes": [{"id": "node1"}]}`,
+			err:    "needs both an id and an address",
+		},
+		{
+			name:   "duplicate node",
+			config: `{"nodes": [{"id": "node1", "address": "a:1"}, {"id": "node1", "address": "b:1"}]}`,
+			err:    "listed twice",
+		},
+		{
+			name:   "unknown self",
+			config: `{"nodes": [{"id": "node2", "address": "0.0.0.0:8081"}]}`,
+			err:    "not in the cluster",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), "cluster.json")
+			require.NoError(t, os.WriteFile(path, []byte(tc.config), 0600))
+
+			config, err := LoadClusterConfig(path)
+			require.NoError(t, err)
+
+			cluster, err := NewCluster(config, "node1", grpc.WithInsecure())
+			if tc.err != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.NoError(t, cluster.Close())
+		})
+	}
+}
+
+type testClusterNode struct {
+	id          string
+	address     string
+	store       LaptopStore
+	imageStore  ImageStore
+	ratingStore RatingStore
+	uploads     *UploadSessionStore
+}
+
+func TestCluster(t *testing.T) {
+	t.Parallel()
+
+	nodes, cluster := startTestCluster(t, 3)
+	clients := make([]pb.LaptopServiceClient, len(nodes))
+	for i, node := range nodes {
+		clients[i] = startTestLaptopClient(t, node.address)
+	}
+
+	laptops := make(map[string]*pb.Laptop)
+	for i := 0; i < 30; i++ {
+		laptop := genarator.NewLaptop()
+		if i%2 == 0 {
+			laptop.Id = ""
+		}
+
+		res, err := clients[0].CreateLaptop(context.Background(), &pb.CreateLaptopRequest{Laptop: laptop})
+		require.NoError(t, err)
+		laptops[res.GetId()] = laptop
+	}
+
+	// each laptop is stored by its owner only, and can be read from any node
+	for id := range laptops {
+		owner := cluster.Owner(id)
+		for _, node := range nodes {
+			found, err := node.store.Find(id)
+			require.NoError(t, err)
+			require.Equal(t, node.id == owner, found != nil)
+		}
+
+		res, err := clients[1].GetLaptop(context.Background(), &pb.GetLaptopRequest{Id: id})
+		require.NoError(t, err)
+		require.Equal(t, id, res.GetLaptop().GetId())
+	}
+
+	stream, err := clients[2].SearchLaptop(context.Background(), &pb.SearchLaptopRequest{})
+	require.NoError(t, err)
+	found := make(map[string]bool)
+	for {
+		res, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		require.False(t, found[res.GetLaptop().GetId()])
+		found[res.GetLaptop().GetId()] = true
+	}
+	require.Len(t, found, len(laptops))
+	require.Equal(t, []string{strconv.Itoa(len(laptops))}, stream.Trailer().Get(SearchMatchedTrailer))
+	require.Equal(t, []string{"true"}, stream.Trailer().Get(SearchCompleteTrailer))
+
+	ranked, err := clients[1].SearchLaptop(context.Background(), &pb.SearchLaptopRequest{Ranking: &pb.RankingOptions{}})
+	require.NoError(t, err)
+	previous := maxLaptopScore * 5.0
+	count := 0
+	for {
+		res, err := ranked.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		require.LessOrEqual(t, res.GetScore(), previous)
+		previous = res.GetScore()
+		count++
+	}
+	require.Equal(t, len(laptops), count)
+
+	rate, err := clients[0].RateLaptop(context.Background())
+	require.NoError(t, err)
+	for id := range laptops {
+		require.NoError(t, rate.Send(&pb.RateLaptopRequest{LaptopId: id, Score: 8}))
+		res, err := rate.Recv()
+		require.NoError(t, err)
+		require.Equal(t, id, res.GetLaptopId())
+		require.Equal(t, uint32(1), res.GetRatingCount())
+	}
+	require.NoError(t, rate.Send(&pb.RateLaptopRequest{LaptopId: uuid.NewString(), Score: 8}))
+	_, err = rate.Recv()
+	require.Error(t, err)
+
+	download, err := clients[1].DownloadImage(context.Background(), &pb.DownloadImageRequest{ImageId: "unknown"})
+	require.NoError(t, err)
+	_, err = download.Recv()
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	for id := range laptops {
+		for _, node := range nodes {
+			require.Equal(t, node.id == cluster.Owner(id), node.ratingStore.Find(id) != nil)
+		}
+	}
+
+	imageData := encodeTestImage(t, "image/png", 4, 3)
+	for id := range laptops {
+		upload, err := clients[2].UploadImage(context.Background())
+		require.NoError(t, err)
+		require.NoError(t, upload.Send(&pb.UploadImageRequest{
+			Data: &pb.UploadImageRequest_Info{Info: &pb.ImageInfo{LaptopId: id, ImageType: ".png"}},
+		}))
+		require.NoError(t, upload.Send(&pb.UploadImageRequest{
+			Data: &pb.UploadImageRequest_ChunkData{ChunkData: imageData},
+		}))
+		res, err := upload.CloseAndRecv()
+		require.NoError(t, err)
+		require.EqualValues(t, len(imageData), res.GetSize())
+
+		for _, node := range nodes {
+			images := 0
+			require.NoError(t, node.imageStore.List(func(image *ImageInfo) error {
+				if image.Id == res.GetId() {
+					require.Equal(t, id, image.LaptopId)
+					images++
+				}
+				return nil
+			}))
+			require.Equal(t, node.id == cluster.Owner(id), images == 1)
+		}
+
+		// any node finds the image on its owner
+		download, err := clients[0].DownloadImage(context.Background(), &pb.DownloadImageRequest{ImageId: res.GetId()})
+		require.NoError(t, err)
+		metadata, err := download.Recv()
+		require.NoError(t, err)
+		require.Equal(t, id, metadata.GetMetadata().GetLaptopId())
+		chunk, err := download.Recv()
+		require.NoError(t, err)
+		require.Equal(t, imageData, chunk.GetChunkData())
+		_, err = download.Recv()
+		require.Equal(t, io.EOF, err)
+
+		list, err := clients[1].ListImages(context.Background(), &pb.ListImagesRequest{LaptopId: id})
+		require.NoError(t, err)
+		require.Len(t, list.GetImages(), 1)
+		require.Equal(t, res.GetId(), list.GetImages()[0].GetId())
+
+		_, err = clients[1].DeleteImage(context.Background(), &pb.DeleteImageRequest{ImageId: res.GetId()})
+		require.NoError(t, err)
+		list, err = clients[0].ListImages(context.Background(), &pb.ListImagesRequest{LaptopId: id})
+		require.NoError(t, err)
+		require.Empty(t, list.GetImages())
+	}
+
+	// an upload session lives on the owner, any node finds it
+	for id := range laptops {
+		started, err := clients[0].StartUpload(context.Background(), &pb.StartUploadRequest{
+			Info: &pb.ImageInfo{LaptopId: id},
+			Size: uint64(len(imageData)),
+		})
+		require.NoError(t, err)
+		sessionId := started.GetSessionId()
+
+		for _, node := range nodes {
+			session, err := node.uploads.Find(sessionId)
+			require.NoError(t, err)
+			require.Equal(t, node.id == cluster.Owner(id), session != nil)
+		}
+
+		upload, err := clients[1].UploadChunk(context.Background())
+		require.NoError(t, err)
+		require.NoError(t, upload.Send(&pb.UploadChunkRequest{SessionId: sessionId, ChunkData: imageData}))
+		res, err := upload.CloseAndRecv()
+		require.NoError(t, err)
+		require.EqualValues(t, len(imageData), res.GetCommittedOffset())
+
+		query, err := clients[2].QueryUpload(context.Background(), &pb.QueryUploadRequest{SessionId: sessionId})
+		require.NoError(t, err)
+		require.EqualValues(t, len(imageData), query.GetCommittedOffset())
+
+		saved, err := clients[2].FinishUpload(context.Background(), &pb.FinishUploadRequest{SessionId: sessionId})
+		require.NoError(t, err)
+
+		list, err := clients[1].ListImages(context.Background(), &pb.ListImagesRequest{LaptopId: id})
+		require.NoError(t, err)
+		require.Len(t, list.GetImages(), 1)
+		require.Equal(t, saved.GetId(), list.GetImages()[0].GetId())
+	}
+
+	_, err = clients[1].QueryUpload(context.Background(), &pb.QueryUploadRequest{SessionId: "unknown"})
+	require.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestClusterTenant(t *testing.T) {
+	t.Parallel()
+
+	nodes, cluster := startTestCluster(t, 3)
+	client := startTestLaptopClient(t, nodes[0].address)
+
+	accessToken, err := NewJWTManager(testClusterSecret, time.Minute).Generate("root", SuperAdminRole, "")
+	require.NoError(t, err)
+	asTenant := func(tenant string) context.Context {
+		return metadata.AppendToOutgoingContext(context.Background(), "authorization", accessToken, "tenant", tenant)
+	}
+
+	// enough laptops that most of them are owned by other nodes
+	tenants := make(map[string]string)
+	for i := 0; i < 20; i++ {
+		tenant := "acme"
+		if i%2 == 1 {
+			tenant = "globex"
+		}
+
+		laptop := genarator.NewLaptop()
+		laptop.Tenant = "initech"
+		res, err := client.CreateLaptop(asTenant(tenant), &pb.CreateLaptopRequest{Laptop: laptop})
+		require.NoError(t, err)
+		tenants[res.GetId()] = tenant
+	}
+
+	for id, tenant := range tenants {
+		var owner *testClusterNode
+		for _, node := range nodes {
+			if node.id == cluster.Owner(id) {
+				owner = node
+			}
+		}
+
+		laptop, err := owner.store.Find(id)
+		require.NoError(t, err)
+		require.Equal(t, tenant, laptop.GetTenant())
+	}
+
+	stream, err := client.SearchLaptop(asTenant("acme"), &pb.SearchLaptopRequest{})
+	require.NoError(t, err)
+	found := 0
+	for {
+		res, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		require.Equal(t, "acme", tenants[res.GetLaptop().GetId()])
+		found++
+	}
+	require.Equal(t, 10, found)
+}
+
+// TestClusterCatalog checks that the RPCs over the whole catalog see the
+// laptops of every node, as a single node holding all of them would
+func TestClusterCatalog(t *testing.T) {
+	t.Parallel()
+
+	nodes, _ := startTestCluster(t, 3)
+	client := startTestLaptopClient(t, nodes[1].address)
+
+	ids := []string{}
+	for i := 0; i < 30; i++ {
+		laptop := genarator.NewLaptop()
+		laptop.PriceUsd = float64(1000 + 50*i)
+		res, err := client.CreateLaptop(context.Background(), &pb.CreateLaptopRequest{Laptop: laptop})
+		require.NoError(t, err)
+		ids = append(ids, res.GetId())
+
+		rate, err := client.RateLaptop(context.Background())
+		require.NoError(t, err)
+		for j := 0; j <= i%4; j++ {
+			require.NoError(t, rate.Send(&pb.RateLaptopRequest{LaptopId: res.GetId(), Score: float64(i % 10)}))
+			_, err := rate.Recv()
+			require.NoError(t, err)
+		}
+		require.NoError(t, rate.CloseSend())
+	}
+
+	single := NewInMemoryLaptopStore()
+	ratings := NewInMemoryRatingStore()
+	for _, node := range nodes {
+		_, err := node.store.Search(context.Background(), nil, func(laptop *pb.Laptop) error {
+			return single.Save(laptop)
+		})
+		require.NoError(t, err)
+		require.NoError(t, node.ratingStore.List(func(laptopId string, rating *Rating) error {
+			return ratings.Set(laptopId, rating)
+		}))
+	}
+
+	batches, err := client.SearchLaptopBatch(context.Background(), &pb.SearchLaptopBatchRequest{MaxBatchSize: 4})
+	require.NoError(t, err)
+	batched := 0
+	for {
+		res, err := batches.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		require.LessOrEqual(t, len(res.GetLaptops()), 4)
+		batched += len(res.GetLaptops())
+	}
+	require.Equal(t, len(ids), batched)
+
+	// the scores are normalized against the whole catalog
+	laptops := []*pb.Laptop{}
+	_, err = single.Search(context.Background(), nil, func(laptop *pb.Laptop) error {
+		laptops = append(laptops, laptop)
+		return nil
+	})
+	require.NoError(t, err)
+	expected := make(map[string]float64)
+	for _, ranked := range RankLaptops(laptops, nil, ratings, &pb.RankingOptions{}) {
+		expected[ranked.Laptop.GetId()] = ranked.Score
+	}
+
+	ranked, err := client.SearchLaptop(context.Background(), &pb.SearchLaptopRequest{Ranking: &pb.RankingOptions{}})
+	require.NoError(t, err)
+	previous := maxLaptopScore * 5.0
+	count := 0
+	for {
+		res, err := ranked.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		require.InDelta(t, expected[res.GetLaptop().GetId()], res.GetScore(), 1e-9)
+		require.LessOrEqual(t, res.GetScore(), previous)
+		require.Nil(t, res.GetRating())
+		previous = res.GetScore()
+		count++
+	}
+	require.Equal(t, len(ids), count)
+
+	for _, id := range ids[:5] {
+		reference, err := single.Find(id)
+		require.NoError(t, err)
+
+		res, err := client.SimilarLaptops(context.Background(), &pb.SimilarLaptopsRequest{LaptopId: id, K: 5})
+		require.NoError(t, err)
+
+		expected := []string{}
+		for _, similar := range FindSimilarLaptops(reference, laptops, nil, 5) {
+			expected = append(expected, similar.Laptop.GetId())
+		}
+		require.Equal(t, expected, similarLaptopIDs(res))
+	}
+
+	accessToken, err := NewJWTManager(testClusterSecret, time.Minute).Generate("alice", "user", "")
+	require.NoError(t, err)
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", accessToken)
+
+	savedSearchClient := startTestSavedSearchClient(t, nodes[2].address)
+	saved, err := savedSearchClient.SaveSearch(ctx, &pb.SaveSearchRequest{
+		Name: "cheap",
+		Filter: &pb.Filter{
+			MaxPriceUsd: 1975,
+			MinRam:      &pb.Memory{Value: 1, Unit: pb.Memory_BIT},
+		},
+	})
+	require.NoError(t, err)
+	require.ElementsMatch(t, ids[:20], runTestSavedSearch(t, ctx, savedSearchClient, saved.GetSavedSearch().GetId(), false))
+}
+
+// startTestCluster serves a node of the cluster on each of n listeners, and
+// returns the nodes along with the cluster as seen by the first of them
+func startTestCluster(t *testing.T, n int) ([]*testClusterNode, *Cluster) {
+	config := &ClusterConfig{}
+	listeners := make([]net.Listener, n)
+	for i := range listeners {
+		listener, err := net.Listen("tcp", ":0")
+		require.NoError(t, err)
+
+		listeners[i] = listener
+		config.Nodes = append(config.Nodes, ClusterNode{
+			Id:      fmt.Sprintf("node%d", i+1),
+			Address: listener.Addr().String(),
+		})
+	}
+
+	nodes := make([]*testClusterNode, n)
+	clusters := make([]*Cluster, n)
+	for i, listener := range listeners {
+		node := &testClusterNode{
+			id:          config.Nodes[i].Id,
+			address:     config.Nodes[i].Address,
+			store:       NewInMemoryLaptopStore(),
+			imageStore:  NewDiskImageStore(t.TempDir()),
+			ratingStore: NewInMemoryRatingStore(),
+		}
+		nodes[i] = node
+
+		uploads, err := NewUploadSessionStore(t.TempDir(), time.Hour)
+		require.NoError(t, err)
+		node.uploads = uploads
+
+		cluster, err := NewCluster(config, node.id, grpc.WithInsecure())
+		require.NoError(t, err)
+		t.Cleanup(func() { cluster.Close() })
+		clusters[i] = cluster
+
+		laptopServer := NewLaptopServer(node.store, node.imageStore, node.ratingStore)
+		laptopServer.Cluster = cluster
+		laptopServer.Uploads = node.uploads
+
+		// every RPC is public, a token only scopes the request
+		authInterceptor := NewAuthInterceptor(NewJWTManager(testClusterSecret, time.Minute), nil)
+		grpcServer := grpc.NewServer(
+			grpc.UnaryInterceptor(authInterceptor.Unary()),
+			grpc.StreamInterceptor(authInterceptor.Stream()),
+		)
+		pb.RegisterLaptopServiceServer(grpcServer, laptopServer)
+
+		savedSearchServer := NewSavedSearchServer(node.store, NewInMemorySavedSearchStore())
+		savedSearchServer.Cluster = cluster
+		pb.RegisterSavedSearchServiceServer(grpcServer, savedSearchServer)
+
+		go grpcServer.Serve(listener)
+		t.Cleanup(grpcServer.Stop)
+	}
+
+	return nodes, clusters[0]
+}
@@ -0,0 +1,60 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/orkhanrustamli/pcbook/memory"
+	pb "github.com/orkhanrustamli/pcbook/pcbook_proto/go"
+)
+
+func validateFilter(filter *pb.Filter) error {
+	if len([]rune(filter.GetBrand())) > maxFuzzyQueryLength {
+		return fmt.Errorf("brand is longer than %d characters", maxFuzzyQueryLength)
+	}
+	if len([]rune(filter.GetName())) > maxFuzzyQueryLength {
+		return fmt.Errorf("name is longer than %d characters", maxFuzzyQueryLength)
+	}
+
+	if filter.GetMinRam() != nil {
+		if err := memory.Validate(filter.GetMinRam()); err != nil {
+			return fmt.Errorf("invalid min ram: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func isQualified(filter *pb.Filter, laptop *pb.Laptop) bool {
+	if filter == nil {
+		return true
+	}
+
+	if laptop.PriceUsd > filter.MaxPriceUsd {
+		return false
+	}
+
+	if laptop.Cpu.NumberCores < filter.MinCpuCores {
+		return false
+	}
+
+	if laptop.Cpu.MinGhz < filter.MinCpuGhz {
+		return false
+	}
+
+	if filter.MinRam != nil {
+		cmp, err := memory.Compare(laptop.Ram, filter.MinRam)
+		if err != nil || cmp < 0 {
+			return false
+		}
+	}
+
+	if !matchesBrand(filter, laptop) || !matchesName(filter, laptop) {
+		return false
+	}
+
+	if filter.CreatedAfter != nil && !laptop.GetCreatedAt().AsTime().After(filter.CreatedAfter.AsTime()) {
+		return false
+	}
+
+	return true
+}
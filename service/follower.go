@@ -0,0 +1,216 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	pb "github.com/orkhanrustamli/pcbook/pcbook_proto/go"
+	"google.golang.org/grpc"
+)
+
+// Follower keeps local stores in sync with a leader. Every time it connects
+// it replaces the stores with a snapshot of the leader, then applies the
+// mutations that follow in order. Nothing else may write to the stores.
+type Follower struct {
+	client        pb.ReplicationServiceClient
+	leaderAddress string
+	stores        *Stores
+	now           func() time.Time
+
+	mutex          sync.Mutex
+	connected      bool
+	applied        uint64
+	leaderSequence uint64
+	caughtUpAt     time.Time
+}
+
+func NewFollower(conn *grpc.ClientConn, leaderAddress string, stores *Stores) *Follower {
+	return &Follower{
+		client:        pb.NewReplicationServiceClient(conn),
+		leaderAddress: leaderAddress,
+		stores:        stores,
+		now:           time.Now,
+	}
+}
+
+// Run replicates until ctx is done, and connects again retryInterval after
+// the stream breaks
+func (follower *Follower) Run(ctx context.Context, retryInterval time.Duration) {
+	for {
+		err := follower.replicate(ctx)
+
+		follower.mutex.Lock()
+		follower.connected = false
+		follower.mutex.Unlock()
+
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("Replication from %s stopped: %v", follower.leaderAddress, err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retryInterval):
+		}
+	}
+}
+
+func (follower *Follower) Status() *pb.ReplicationStatusResponse {
+	follower.mutex.Lock()
+	defer follower.mutex.Unlock()
+
+	res := &pb.ReplicationStatusResponse{
+		Role:            pb.ReplicationStatusResponse_FOLLOWER,
+		LeaderAddress:   follower.leaderAddress,
+		Connected:       follower.connected,
+		AppliedSequence: follower.applied,
+		LeaderSequence:  follower.leaderSequence,
+	}
+	if follower.leaderSequence > follower.applied {
+		res.LagSequences = follower.leaderSequence - follower.applied
+	}
+	if (res.LagSequences > 0 || !follower.connected) && !follower.caughtUpAt.IsZero() {
+		res.LagSeconds = follower.now().Sub(follower.caughtUpAt).Seconds()
+	}
+
+	return res
+}
+
+func (follower *Follower) replicate(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stream, err := follower.client.Replicate(ctx, &pb.ReplicateRequest{})
+	if err != nil {
+		return fmt.Errorf("cannot start replication: %v", err)
+	}
+
+	res, err := stream.Recv()
+	if err != nil {
+		return fmt.Errorf("cannot receive snapshot: %v", err)
+	}
+	if _, ok := res.GetData().(*pb.ReplicateResponse_SnapshotSequence); !ok {
+		return fmt.Errorf("replication does not start with a snapshot")
+	}
+
+	reader := &snapshotStreamReader{stream: stream}
+	if err := follower.loadSnapshot(ctx, reader); err != nil {
+		return err
+	}
+	applied := res.GetSnapshotSequence()
+	leaderSequence := applied
+	follower.update(applied, leaderSequence)
+	log.Printf("Loaded snapshot at sequence %d from %s", applied, follower.leaderAddress)
+
+	res = reader.next
+	for {
+		if res == nil {
+			res, err = stream.Recv()
+			if err != nil {
+				return fmt.Errorf("cannot receive mutation: %v", err)
+			}
+		}
+
+		switch data := res.GetData().(type) {
+		case *pb.ReplicateResponse_Mutation:
+			mutation := data.Mutation
+			if mutation.GetSequence() != applied+1 {
+				return fmt.Errorf("received mutation %d instead of %d", mutation.GetSequence(), applied+1)
+			}
+			if err := applyMutation(follower.stores, mutation); err != nil {
+				return fmt.Errorf("cannot apply mutation %d: %v", mutation.GetSequence(), err)
+			}
+
+			applied = mutation.GetSequence()
+			if applied > leaderSequence {
+				leaderSequence = applied
+			}
+		case *pb.ReplicateResponse_Heartbeat:
+			if data.Heartbeat.GetSequence() > leaderSequence {
+				leaderSequence = data.Heartbeat.GetSequence()
+			}
+		default:
+			return fmt.Errorf("unexpected replication message: %v", res)
+		}
+
+		follower.update(applied, leaderSequence)
+		res = nil
+	}
+}
+
+// loadSnapshot checks the whole snapshot before it replaces the stores
+func (follower *Follower) loadSnapshot(ctx context.Context, reader io.Reader) error {
+	imageFolder, err := os.MkdirTemp("", "pcbook-snapshot-*")
+	if err != nil {
+		return fmt.Errorf("cannot create image folder: %v", err)
+	}
+	defer os.RemoveAll(imageFolder)
+
+	snapshot, err := OpenStores(StoreOptions{Type: "memory", ImageFolder: imageFolder})
+	if err != nil {
+		return err
+	}
+	defer snapshot.Close()
+
+	if err := ReadBackup(ctx, reader, snapshot); err != nil {
+		return fmt.Errorf("cannot read snapshot: %w", err)
+	}
+
+	if _, err := RestoreBackup(ctx, snapshot, follower.stores, nil, true); err != nil {
+		return fmt.Errorf("cannot load snapshot: %w", err)
+	}
+
+	return nil
+}
+
+// update records the last sequence applied and the latest sequence known to
+// the leader, over a working connection
+func (follower *Follower) update(applied uint64, leaderSequence uint64) {
+	follower.mutex.Lock()
+	defer follower.mutex.Unlock()
+
+	follower.connected = true
+	follower.applied = applied
+	follower.leaderSequence = leaderSequence
+	if applied >= leaderSequence {
+		follower.caughtUpAt = follower.now()
+	}
+}
+
+// snapshotStreamReader reads the snapshot chunks at the start of a stream.
+// It keeps the first message after them in next.
+type snapshotStreamReader struct {
+	stream pb.ReplicationService_ReplicateClient
+	chunk  []byte
+	next   *pb.ReplicateResponse
+}
+
+func (reader *snapshotStreamReader) Read(p []byte) (int, error) {
+	for len(reader.chunk) == 0 {
+		if reader.next != nil {
+			return 0, io.EOF
+		}
+
+		res, err := reader.stream.Recv()
+		if err != nil {
+			return 0, err
+		}
+
+		chunk, ok := res.GetData().(*pb.ReplicateResponse_SnapshotChunk)
+		if !ok {
+			reader.next = res
+			return 0, io.EOF
+		}
+		reader.chunk = chunk.SnapshotChunk
+	}
+
+	n := copy(p, reader.chunk)
+	reader.chunk = reader.chunk[n:]
+	return n, nil
+}
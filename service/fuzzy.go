@@ -0,0 +1,163 @@
+package service
+
+import (
+	"context"
+	"math"
+	"strings"
+
+	pb "github.com/orkhanrustamli/pcbook/pcbook_proto/go"
+)
+
+// maxFuzzyQueryLength caps the brand and name a filter may hold, since every
+// laptop the filter is compared with costs their length times its own
+const maxFuzzyQueryLength = 64
+
+func matchesBrand(filter *pb.Filter, laptop *pb.Laptop) bool {
+	if len(filter.GetBrand()) == 0 {
+		return true
+	}
+
+	return brandDistance(filter.GetBrand(), laptop.GetBrand()) <= int(filter.GetMaxEditDistance())
+}
+
+func matchesName(filter *pb.Filter, laptop *pb.Laptop) bool {
+	if len(filter.GetName()) == 0 {
+		return true
+	}
+
+	return nameDistance(filter.GetName(), laptop.GetName()) <= int(filter.GetMaxEditDistance())
+}
+
+func brandDistance(query, brand string) int {
+	return editDistance(strings.ToLower(query), strings.ToLower(brand))
+}
+
+// nameDistance compares the words of the query with every run of the same
+// number of consecutive words in the name, so "Macbok" is 1 away from
+// "Macbook Pro" and "thinkpad x1" is an exact match of "Thinkpad X1"
+func nameDistance(query, name string) int {
+	queryWords := strings.Fields(strings.ToLower(query))
+	nameWords := strings.Fields(strings.ToLower(name))
+
+	if len(queryWords) >= len(nameWords) {
+		return editDistance(strings.Join(queryWords, " "), strings.Join(nameWords, " "))
+	}
+
+	best := math.MaxInt32
+	for i := 0; i+len(queryWords) <= len(nameWords); i++ {
+		distance := editDistance(
+			strings.Join(queryWords, " "),
+			strings.Join(nameWords[i:i+len(queryWords)], " "),
+		)
+		if distance < best {
+			best = distance
+		}
+	}
+
+	return best
+}
+
+// editDistance returns the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	source, target := []rune(a), []rune(b)
+
+	previous := make([]int, len(target)+1)
+	current := make([]int, len(target)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(source); i++ {
+		current[0] = i
+		for j := 1; j <= len(target); j++ {
+			cost := 1
+			if source[i-1] == target[j-1] {
+				cost = 0
+			}
+
+			current[j] = minInt(
+				previous[j]+1,
+				current[j-1]+1,
+				previous[j-1]+cost,
+			)
+		}
+		previous, current = current, previous
+	}
+
+	return previous[len(target)]
+}
+
+// SuggestSearch builds a "did you mean" suggestion for the brand and name of
+// the filter from the laptops present in the store. It returns nil when the
+// filter has no brand or name, or when nothing in the store is close enough
+// to them or something matches them exactly.
+func SuggestSearch(ctx context.Context, store LaptopStore, filter *pb.Filter) (*pb.SearchSuggestion, error) {
+	if len(filter.GetBrand()) == 0 && len(filter.GetName()) == 0 {
+		return nil, nil
+	}
+
+	brands := make(map[string]bool)
+	names := make(map[string]bool)
+
+	_, err := store.Search(
+		ctx,
+		nil,
+		func(laptop *pb.Laptop) error {
+			brands[laptop.GetBrand()] = true
+			names[laptop.GetName()] = true
+			return nil
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	brand := closest(filter.GetBrand(), brands, brandDistance)
+	name := closest(filter.GetName(), names, nameDistance)
+	if len(brand) == 0 && len(name) == 0 {
+		return nil, nil
+	}
+
+	return &pb.SearchSuggestion{
+		Brand: brand,
+		Name:  name,
+	}, nil
+}
+
+// closest returns the candidate nearest to the query, as long as it is within
+// half the length of the query. It returns nothing when a candidate matches
+// the query exactly, since suggesting the query back would not help.
+func closest(query string, candidates map[string]bool, distance func(query, candidate string) int) string {
+	if len(query) == 0 || len([]rune(query)) > maxFuzzyQueryLength {
+		return ""
+	}
+
+	limit := len([]rune(query)) / 2
+	if limit == 0 {
+		limit = 1
+	}
+
+	best, bestDistance := "", limit+1
+	for candidate := range candidates {
+		d := distance(query, candidate)
+		if d == 0 {
+			return ""
+		}
+		if d < bestDistance || (d == bestDistance && candidate < best) {
+			best, bestDistance = candidate, d
+		}
+	}
+
+	return best
+}
+
+func minInt(first int, others ...int) int {
+	min := first
+	for _, other := range others {
+		if other < min {
+			min = other
+		}
+	}
+
+	return min
+}
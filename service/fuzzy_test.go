@@ -0,0 +1,33 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	pb "github.com/orkhanrustamli/pcbook/pcbook_proto/go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEditDistance(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, 0, editDistance("lenovo", "lenovo"))
+	require.Equal(t, 1, editDistance("lenvo", "lenovo"))
+	require.Equal(t, 3, editDistance("kitten", "sitting"))
+	require.Equal(t, 4, editDistance("", "dell"))
+
+	require.Equal(t, 1, brandDistance("Lenvo", "Lenovo"))
+	require.Equal(t, 1, nameDistance("Macbok", "Macbook Pro"))
+	require.Equal(t, 0, nameDistance("thinkpad x1", "Thinkpad X1"))
+	require.Equal(t, 4, nameDistance("Macbook Pro Max", "Macbook Pro"))
+}
+
+func TestFuzzyQueryLength(t *testing.T) {
+	t.Parallel()
+
+	longest := strings.Repeat("a", maxFuzzyQueryLength)
+	require.NoError(t, validateFilter(&pb.Filter{Brand: longest, Name: longest}))
+	require.Error(t, validateFilter(&pb.Filter{Brand: longest + "a"}))
+	require.Error(t, validateFilter(&pb.Filter{Name: longest + "a"}))
+	require.Empty(t, closest(longest+"a", map[string]bool{longest: true}, brandDistance))
+}
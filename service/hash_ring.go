@@ -0,0 +1,75 @@
+package service
+
+import (
+	"hash/fnv"
+	"sort"
+	"strconv"
+)
+
+const defaultVirtualNodes = 64
+
+// HashRing assigns keys to nodes by consistent hashing. Each node is placed
+// at several points of the ring and owns the keys that hash up to each of
+// them, so keys spread evenly and adding or removing a node only moves the
+// keys next to its own points.
+type HashRing struct {
+	points []uint32
+	owners map[uint32]string
+}
+
+// NewHashRing places every node at virtualNodes points, or at
+// defaultVirtualNodes if virtualNodes is not positive
+func NewHashRing(nodes []string, virtualNodes int) *HashRing {
+	if virtualNodes <= 0 {
+		virtualNodes = defaultVirtualNodes
+	}
+
+	ring := &HashRing{
+		owners: make(map[uint32]string),
+	}
+	for _, node := range nodes {
+		for i := 0; i < virtualNodes; i++ {
+			point := hashKey(node + "#" + strconv.Itoa(i))
+
+			// on a collision the same node must win whatever the order of nodes
+			owner, taken := ring.owners[point]
+			if taken && owner < node {
+				continue
+			}
+			if !taken {
+				ring.points = append(ring.points, point)
+			}
+			ring.owners[point] = node
+		}
+	}
+	sort.Slice(ring.points, func(i, j int) bool {
+		return ring.points[i] < ring.points[j]
+	})
+
+	return ring
+}
+
+// Owner returns the node that owns key, or an empty string if the ring has
+// no nodes
+func (ring *HashRing) Owner(key string) string {
+	if len(ring.points) == 0 {
+		return ""
+	}
+
+	hash := hashKey(key)
+	i := sort.Search(len(ring.points), func(i int) bool {
+		return ring.points[i] >= hash
+	})
+	if i == len(ring.points) {
+		i = 0
+	}
+
+	return ring.owners[ring.points[i]]
+}
+
+func hashKey(key string) uint32 {
+	hash := fnv.New32a()
+	hash.Write([]byte(key))
+
+	return hash.Sum32()
+}
@@ -0,0 +1,199 @@
+package service
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const resizedJpegQuality = 85
+
+// maxDecodedImagePixels bounds the images DecodeImage accepts. A small file
+// can declare huge dimensions, and decoding it would allocate them all.
+const maxDecodedImagePixels = 64 * 1024 * 1024
+
+var ErrImageTooLarge = errors.New("image is too large")
+
+// ImageVariant is a rendition of every uploaded image that fits in
+// MaxWidth x MaxHeight, with the aspect ratio of the original
+type ImageVariant struct {
+	Name      string
+	MaxWidth  int
+	MaxHeight int
+}
+
+// ParseImageVariants parses a comma separated list of variants such as
+// "small=160x160,large=1024x768"
+func ParseImageVariants(value string) ([]ImageVariant, error) {
+	variants := []ImageVariant{}
+	names := make(map[string]bool)
+
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+
+		parts := strings.SplitN(field, "=", 2)
+		if len(parts) != 2 || !isVariantName(parts[0]) {
+			return nil, fmt.Errorf("invalid image variant %q, want name=WIDTHxHEIGHT", field)
+		}
+		name, size := parts[0], parts[1]
+		if names[name] {
+			return nil, fmt.Errorf("image variant %q is listed twice", name)
+		}
+		names[name] = true
+
+		bounds := strings.SplitN(size, "x", 2)
+		if len(bounds) != 2 {
+			return nil, fmt.Errorf("invalid image variant size %q, want WIDTHxHEIGHT", size)
+		}
+		maxWidth, widthErr := strconv.Atoi(bounds[0])
+		maxHeight, heightErr := strconv.Atoi(bounds[1])
+		if widthErr != nil || heightErr != nil || maxWidth <= 0 || maxHeight <= 0 {
+			return nil, fmt.Errorf("invalid image variant size %q, want WIDTHxHEIGHT", size)
+		}
+
+		variants = append(variants, ImageVariant{Name: name, MaxWidth: maxWidth, MaxHeight: maxHeight})
+	}
+
+	return variants, nil
+}
+
+// isVariantName only accepts names that are safe in a file name
+func isVariantName(name string) bool {
+	if name == "" {
+		return false
+	}
+
+	for _, r := range name {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '_' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// DecodeImage decodes a JPEG or PNG image, the only types that can be
+// resized. It reads the dimensions the image declares first, and returns
+// ErrImageTooLarge without decoding it if they exceed maxDecodedImagePixels.
+func DecodeImage(imageType string, imageData io.Reader) (image.Image, error) {
+	var decodeConfig func(io.Reader) (image.Config, error)
+	var decode func(io.Reader) (image.Image, error)
+	switch imageType {
+	case "image/jpeg":
+		decodeConfig, decode = jpeg.DecodeConfig, jpeg.Decode
+	case "image/png":
+		decodeConfig, decode = png.DecodeConfig, png.Decode
+	default:
+		return nil, fmt.Errorf("%w: cannot resize %s", ErrUnsupportedImageType, imageType)
+	}
+
+	// the header read for the config is replayed to decode
+	header := bytes.Buffer{}
+	config, err := decodeConfig(io.TeeReader(imageData, &header))
+	if err != nil {
+		return nil, err
+	}
+	if int64(config.Width)*int64(config.Height) > maxDecodedImagePixels {
+		return nil, fmt.Errorf("%w: %dx%d", ErrImageTooLarge, config.Width, config.Height)
+	}
+
+	return decode(io.MultiReader(&header, imageData))
+}
+
+// EncodeImage encodes the image in the type it was decoded from
+func EncodeImage(imageType string, img image.Image) ([]byte, error) {
+	buffer := bytes.Buffer{}
+
+	var err error
+	switch imageType {
+	case "image/jpeg":
+		err = jpeg.Encode(&buffer, img, &jpeg.Options{Quality: resizedJpegQuality})
+	case "image/png":
+		err = png.Encode(&buffer, img)
+	default:
+		err = fmt.Errorf("%w: cannot encode %s", ErrUnsupportedImageType, imageType)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// ResizeImage scales the image down to fit in the variant, keeping its
+// aspect ratio. An image that already fits keeps its size.
+func ResizeImage(img image.Image, variant ImageVariant) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= variant.MaxWidth && height <= variant.MaxHeight {
+		return img
+	}
+
+	// the side that overflows the most decides the scale
+	var resizedWidth, resizedHeight int
+	if width*variant.MaxHeight > height*variant.MaxWidth {
+		resizedWidth, resizedHeight = variant.MaxWidth, height*variant.MaxWidth/width
+	} else {
+		resizedWidth, resizedHeight = width*variant.MaxHeight/height, variant.MaxHeight
+	}
+	if resizedWidth < 1 {
+		resizedWidth = 1
+	}
+	if resizedHeight < 1 {
+		resizedHeight = 1
+	}
+
+	return scaleDown(img, resizedWidth, resizedHeight)
+}
+
+// scaleDown averages the pixels of the image that fall into each pixel of
+// the result, which keeps thin details from vanishing the way they do when
+// pixels are only sampled
+func scaleDown(img image.Image, width, height int) *image.RGBA {
+	bounds := img.Bounds()
+	resized := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	for y := 0; y < height; y++ {
+		y0 := bounds.Min.Y + y*bounds.Dy()/height
+		y1 := bounds.Min.Y + (y+1)*bounds.Dy()/height
+		if y1 == y0 {
+			y1 = y0 + 1
+		}
+
+		for x := 0; x < width; x++ {
+			x0 := bounds.Min.X + x*bounds.Dx()/width
+			x1 := bounds.Min.X + (x+1)*bounds.Dx()/width
+			if x1 == x0 {
+				x1 = x0 + 1
+			}
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					sr, sg, sb, sa := img.At(sx, sy).RGBA()
+					r, g, b, a = r+uint64(sr), g+uint64(sg), b+uint64(sb), a+uint64(sa)
+					n++
+				}
+			}
+
+			resized.SetRGBA64(x, y, color.RGBA64{
+				R: uint16(r / n),
+				G: uint16(g / n),
+				B: uint16(b / n),
+				A: uint16(a / n),
+			})
+		}
+	}
+
+	return resized
+}
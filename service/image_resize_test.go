@@ -0,0 +1,269 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/orkhanrustamli/pcbook/genarator"
+	pb "github.com/orkhanrustamli/pcbook/pcbook_proto/go"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestParseImageVariants(t *testing.T) {
+	t.Parallel()
+
+	variants, err := ParseImageVariants("small=160x120, large=1024x768")
+	require.NoError(t, err)
+	require.Equal(t, []ImageVariant{
+		{Name: "small", MaxWidth: 160, MaxHeight: 120},
+		{Name: "large", MaxWidth: 1024, MaxHeight: 768},
+	}, variants)
+
+	variants, err = ParseImageVariants("")
+	require.NoError(t, err)
+	require.Empty(t, variants)
+
+	for _, value := range []string{
+		"small",
+		"small=160",
+		"small=0x160",
+		"small=ax160",
+		"../small=160x160",
+		"Small=160x160",
+		"small=160x160,small=320x320",
+	} {
+		_, err := ParseImageVariants(value)
+		require.Error(t, err, value)
+	}
+}
+
+func TestResizeImage(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name          string
+		width, height int
+		variant       ImageVariant
+		resized       image.Point
+	}{
+		{
+			name:    "landscape",
+			width:   400,
+			height:  200,
+			variant: ImageVariant{MaxWidth: 160, MaxHeight: 160},
+			resized: image.Pt(160, 80),
+		},
+		{
+			name:    "portrait",
+			width:   200,
+			height:  400,
+			variant: ImageVariant{MaxWidth: 160, MaxHeight: 160},
+			resized: image.Pt(80, 160),
+		},
+		{
+			name:    "taller bounds",
+			width:   300,
+			height:  300,
+			variant: ImageVariant{MaxWidth: 100, MaxHeight: 200},
+			resized: image.Pt(100, 100),
+		},
+		{
+			name:    "already fits",
+			width:   100,
+			height:  50,
+			variant: ImageVariant{MaxWidth: 160, MaxHeight: 160},
+			resized: image.Pt(100, 50),
+		},
+		{
+			name:    "thin",
+			width:   1000,
+			height:  2,
+			variant: ImageVariant{MaxWidth: 100, MaxHeight: 100},
+			resized: image.Pt(100, 1),
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			img := image.NewRGBA(image.Rect(0, 0, tc.width, tc.height))
+			resized := ResizeImage(img, tc.variant)
+			require.Equal(t, tc.resized, resized.Bounds().Size())
+		})
+	}
+}
+
+func TestDecodeImageTooLarge(t *testing.T) {
+	t.Parallel()
+
+	imageData := encodeOversizedPNG(t, 100000, 100000)
+	require.Less(t, len(imageData), 1024)
+
+	_, err := DecodeImage("image/png", bytes.NewReader(imageData))
+	require.ErrorIs(t, err, ErrImageTooLarge)
+
+	// an image within the limit still decodes after its config was read
+	img, err := DecodeImage("image/png", bytes.NewReader(encodeTestImage(t, "image/png", 40, 30)))
+	require.NoError(t, err)
+	require.Equal(t, image.Pt(40, 30), img.Bounds().Size())
+}
+
+// encodeOversizedPNG encodes a 1x1 PNG and rewrites its header to declare
+// the given dimensions, which is all a decoder allocates for
+func encodeOversizedPNG(t *testing.T, width, height uint32) []byte {
+	imageData := encodeTestImage(t, "image/png", 1, 1)
+
+	// the IHDR chunk follows the 8 byte signature: length, type, data, crc
+	ihdr := imageData[8:]
+	binary.BigEndian.PutUint32(ihdr[8:], width)
+	binary.BigEndian.PutUint32(ihdr[12:], height)
+	binary.BigEndian.PutUint32(ihdr[21:], crc32.ChecksumIEEE(ihdr[4:21]))
+
+	return imageData
+}
+
+func TestServerImageVariants(t *testing.T) {
+	t.Parallel()
+
+	laptopStore := NewInMemoryLaptopStore()
+	imageStore := NewDiskImageStore(t.TempDir())
+
+	laptop := genarator.NewLaptop()
+	require.NoError(t, laptopStore.Save(laptop))
+
+	laptopServer := NewLaptopServer(laptopStore, imageStore, nil)
+	laptopServer.ImageVariants = []ImageVariant{
+		{Name: "small", MaxWidth: 40, MaxHeight: 40},
+		{Name: "medium", MaxWidth: 200, MaxHeight: 200},
+	}
+	grpcServer := grpc.NewServer()
+	pb.RegisterLaptopServiceServer(grpcServer, laptopServer)
+	listener, err := net.Listen("tcp", ":0")
+	require.NoError(t, err)
+	go grpcServer.Serve(listener)
+	defer grpcServer.Stop()
+
+	client := startTestLaptopClient(t, listener.Addr().String())
+
+	upload := func(imageData []byte) string {
+		stream, err := client.UploadImage(context.Background())
+		require.NoError(t, err)
+		require.NoError(t, stream.Send(&pb.UploadImageRequest{
+			Data: &pb.UploadImageRequest_Info{Info: &pb.ImageInfo{LaptopId: laptop.GetId()}},
+		}))
+		require.NoError(t, stream.Send(&pb.UploadImageRequest{
+			Data: &pb.UploadImageRequest_ChunkData{ChunkData: imageData},
+		}))
+
+		res, err := stream.CloseAndRecv()
+		require.NoError(t, err)
+		return res.GetId()
+	}
+
+	download := func(imageId string, variant string) (*pb.ImageMetadata, []byte, error) {
+		stream, err := client.DownloadImage(context.Background(), &pb.DownloadImageRequest{
+			ImageId: imageId,
+			Variant: variant,
+		})
+		require.NoError(t, err)
+
+		res, err := stream.Recv()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		imageData := bytes.Buffer{}
+		for {
+			res, err := stream.Recv()
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err)
+			imageData.Write(res.GetChunkData())
+		}
+
+		return res.GetMetadata(), imageData.Bytes(), nil
+	}
+
+	pngId := upload(encodeTestImage(t, "image/png", 400, 300))
+	jpegId := upload(encodeTestImage(t, "image/jpeg", 90, 120))
+	gifId := upload(encodeTestImage(t, "image/gif", 400, 300))
+
+	res, err := client.ListImages(context.Background(), &pb.ListImagesRequest{LaptopId: laptop.GetId()})
+	require.NoError(t, err)
+	require.Len(t, res.GetImages(), 3)
+	require.Equal(t, []string{"medium", "small"}, res.GetImages()[0].GetVariants())
+	require.Equal(t, []string{"medium", "small"}, res.GetImages()[1].GetVariants())
+	require.Empty(t, res.GetImages()[2].GetVariants())
+
+	testCases := []struct {
+		imageId string
+		variant string
+		size    image.Point
+	}{
+		{imageId: pngId, variant: "small", size: image.Pt(40, 30)},
+		{imageId: pngId, variant: "medium", size: image.Pt(200, 150)},
+		{imageId: pngId, size: image.Pt(400, 300)},
+		{imageId: jpegId, variant: "small", size: image.Pt(30, 40)},
+		// smaller than the variant, so only encoded again
+		{imageId: jpegId, variant: "medium", size: image.Pt(90, 120)},
+	}
+	for _, tc := range testCases {
+		metadata, imageData, err := download(tc.imageId, tc.variant)
+		require.NoError(t, err)
+		require.EqualValues(t, len(imageData), metadata.GetSize())
+
+		img, err := DecodeImage(metadata.GetImageType(), bytes.NewReader(imageData))
+		require.NoError(t, err)
+		require.Equal(t, tc.size, img.Bounds().Size(), "%s %s", tc.imageId, tc.variant)
+	}
+
+	_, _, err = download(pngId, "huge")
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, _, err = download(gifId, "small")
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	// images restored from a backup have no variants until they are asked for
+	imported := &ImageInfo{
+		Id:         "imported",
+		LaptopId:   laptop.GetId(),
+		Type:       "image/png",
+		UploadedAt: time.Now(),
+	}
+	require.NoError(t, imageStore.Import(imported, bytes.NewReader(encodeTestImage(t, "image/png", 80, 80))))
+
+	metadata, imageData, err := download("imported", "small")
+	require.NoError(t, err)
+	img, err := DecodeImage(metadata.GetImageType(), bytes.NewReader(imageData))
+	require.NoError(t, err)
+	require.Equal(t, image.Pt(40, 40), img.Bounds().Size())
+
+	variant, err := imageStore.FindVariant("imported", "small")
+	require.NoError(t, err)
+	require.NotNil(t, variant)
+
+	// an image declaring more pixels than can be decoded gets no variant
+	oversized := &ImageInfo{
+		Id:         "oversized",
+		LaptopId:   laptop.GetId(),
+		Type:       "image/png",
+		UploadedAt: time.Now(),
+	}
+	require.NoError(t, imageStore.Import(oversized, bytes.NewReader(encodeOversizedPNG(t, 100000, 100000))))
+
+	_, _, err = download("oversized", "small")
+	require.Equal(t, codes.NotFound, status.Code(err))
+}
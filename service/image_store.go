@@ -3,16 +3,44 @@ package service
 import (
 	"bytes"
 	"fmt"
+	"io"
+	"log"
 	"os"
+	"path/filepath"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/google/uuid"
+	pb "github.com/orkhanrustamli/pcbook/pcbook_proto/go"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
+const imageRecordExt = ".meta"
+
 type ImageStore interface {
+	// Save stores the image under the MIME type named by imageType, it
+	// returns ErrUnsupportedImageType if the type is not accepted
 	Save(laptopId string, imageType string, imageData bytes.Buffer) (string, error)
+	// Find returns a copy of the info of the image, or nil if there is none
+	Find(imageId string) (*ImageInfo, error)
+	Delete(imageId string) error
+	// List calls found with every image in the store, ordered by ID
+	List(found func(image *ImageInfo) error) error
+	// Import stores an image under the ID, laptop and upload time of the
+	// given info, it returns ErrAlreadyExists if the ID is taken
+	Import(image *ImageInfo, imageData io.Reader) error
+	// SaveVariant stores a rendition of the image under the variant name,
+	// replacing any previous one, it returns ErrNotFound if there is no image
+	SaveVariant(imageId string, variant string, imageData io.Reader) error
+	// FindVariant returns the info of a rendition of the image, with the
+	// path and size of the rendition, or nil if there is none
+	FindVariant(imageId string, variant string) (*ImageInfo, error)
 }
 
+// DiskImageStore writes every image to imageFolder along with a record of
+// its info, and reloads the records when it is created again
 type DiskImageStore struct {
 	mutex       sync.RWMutex
 	imageFolder string
@@ -20,16 +48,25 @@ type DiskImageStore struct {
 }
 
 type ImageInfo struct {
-	LaptopId string
-	Type     string
-	Path     string
+	Id         string
+	LaptopId   string
+	Type       string
+	Path       string
+	Size       int64
+	UploadedAt time.Time
+	// Variants are the sizes of the renditions stored next to the image, by
+	// variant name. The map is replaced, never modified.
+	Variants map[string]int64
 }
 
 func NewDiskImageStore(imageFolder string) *DiskImageStore {
-	return &DiskImageStore{
+	store := &DiskImageStore{
 		imageFolder: imageFolder,
 		images:      make(map[string]*ImageInfo),
 	}
+	store.load()
+
+	return store
 }
 
 func (store *DiskImageStore) Save(
@@ -37,31 +74,313 @@ func (store *DiskImageStore) Save(
 	imageType string,
 	imageData bytes.Buffer,
 ) (string, error) {
+	imageType, err := CanonicalImageType(imageType)
+	if err != nil {
+		return "", err
+	}
+
 	imageId, err := uuid.NewRandom()
 	if err != nil {
 		return "", fmt.Errorf("cannot generate random id for image: %v", err)
 	}
 
-	imagePath := fmt.Sprintf("%s/%s%s", store.imageFolder, imageId, imageType)
+	image := &ImageInfo{
+		Id:         imageId.String(),
+		LaptopId:   laptopId,
+		Type:       imageType,
+		UploadedAt: time.Now(),
+	}
+	if err := store.write(image, &imageData); err != nil {
+		return "", err
+	}
+
+	return image.Id, nil
+}
+
+func (store *DiskImageStore) Find(imageId string) (*ImageInfo, error) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	image := store.images[imageId]
+	if image == nil {
+		return nil, nil
+	}
+
+	other := *image
+	return &other, nil
+}
+
+func (store *DiskImageStore) Import(image *ImageInfo, imageData io.Reader) error {
+	store.mutex.RLock()
+	exists := store.images[image.Id] != nil
+	store.mutex.RUnlock()
+	if exists {
+		return ErrAlreadyExists
+	}
+
+	imageType, err := CanonicalImageType(image.Type)
+	if err != nil {
+		return err
+	}
+
+	return store.write(&ImageInfo{
+		Id:         image.Id,
+		LaptopId:   image.LaptopId,
+		Type:       imageType,
+		UploadedAt: image.UploadedAt,
+	}, imageData)
+}
+
+func (store *DiskImageStore) SaveVariant(imageId string, variant string, imageData io.Reader) error {
+	if !isVariantName(variant) {
+		return fmt.Errorf("invalid variant name %q", variant)
+	}
+
+	file, size, err := store.writeTemp(imageId, imageData)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file)
+
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	image := store.images[imageId]
+	if image == nil {
+		return ErrNotFound
+	}
+
+	updated := *image
+	updated.Variants = make(map[string]int64, len(image.Variants)+1)
+	for name, size := range image.Variants {
+		updated.Variants[name] = size
+	}
+	updated.Variants[variant] = size
+
+	record, err := store.writeRecordTemp(&updated)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(record)
+
+	if err := os.Rename(file, store.variantPath(image, variant)); err != nil {
+		return fmt.Errorf("cannot move image variant into place: %v", err)
+	}
+	if err := os.Rename(record, store.recordPath(imageId)); err != nil {
+		return fmt.Errorf("cannot move image record into place: %v", err)
+	}
+
+	store.images[imageId] = &updated
+	return nil
+}
+
+func (store *DiskImageStore) FindVariant(imageId string, variant string) (*ImageInfo, error) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	image := store.images[imageId]
+	if image == nil {
+		return nil, nil
+	}
+	size, ok := image.Variants[variant]
+	if !ok {
+		return nil, nil
+	}
+
+	other := *image
+	other.Path = store.variantPath(image, variant)
+	other.Size = size
+	return &other, nil
+}
+
+func (store *DiskImageStore) Delete(imageId string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	image := store.images[imageId]
+	if image == nil {
+		return ErrNotFound
+	}
+
+	// without its record the image is no longer part of the store, even if
+	// removing the file fails afterwards
+	if err := os.Remove(store.recordPath(imageId)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("cannot remove image record: %v", err)
+	}
+	delete(store.images, imageId)
+
+	for variant := range image.Variants {
+		if err := os.Remove(store.variantPath(image, variant)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("cannot remove image variant: %v", err)
+		}
+	}
+	if err := os.Remove(image.Path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("cannot remove image file: %v", err)
+	}
+
+	return nil
+}
+
+func (store *DiskImageStore) List(found func(image *ImageInfo) error) error {
+	store.mutex.RLock()
+	images := make([]*ImageInfo, 0, len(store.images))
+	for _, image := range store.images {
+		other := *image
+		images = append(images, &other)
+	}
+	store.mutex.RUnlock()
+
+	sort.Slice(images, func(i, j int) bool {
+		return images[i].Id < images[j].Id
+	})
+
+	for _, image := range images {
+		if err := found(image); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
 
-	file, err := os.Create(imagePath)
+// write stores the image and its record in temporary files first and renames
+// them into place, so a failed write never leaves a half-written image behind
+func (store *DiskImageStore) write(image *ImageInfo, imageData io.Reader) error {
+	file, size, err := store.writeTemp(image.Id, imageData)
 	if err != nil {
-		return "", fmt.Errorf("cannot create file: %v", err)
+		return err
 	}
+	defer os.Remove(file)
+	image.Size = size
 
-	_, err = imageData.WriteTo(file)
+	record, err := store.writeRecordTemp(image)
 	if err != nil {
-		return "", fmt.Errorf("cannot write image to file: %v", err)
+		return err
 	}
+	defer os.Remove(record)
 
 	store.mutex.Lock()
 	defer store.mutex.Unlock()
 
-	store.images[imageId.String()] = &ImageInfo{
-		LaptopId: laptopId,
-		Type:     imageType,
-		Path:     imagePath,
+	if store.images[image.Id] != nil {
+		return ErrAlreadyExists
+	}
+
+	image.Path = store.imagePath(image.Id, imageExtension(image.Type))
+	if err := os.Rename(file, image.Path); err != nil {
+		return fmt.Errorf("cannot move image into place: %v", err)
+	}
+	if err := os.Rename(record, store.recordPath(image.Id)); err != nil {
+		os.Remove(image.Path)
+		return fmt.Errorf("cannot move image record into place: %v", err)
+	}
+
+	store.images[image.Id] = image
+	return nil
+}
+
+// writeTemp copies the data to a temporary file and returns its name, which
+// the caller removes if it does not rename it
+func (store *DiskImageStore) writeTemp(imageId string, imageData io.Reader) (string, int64, error) {
+	file, err := os.CreateTemp(store.imageFolder, imageId+"-*.tmp")
+	if err != nil {
+		return "", 0, fmt.Errorf("cannot create file: %v", err)
+	}
+
+	size, err := io.Copy(file, imageData)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return "", 0, fmt.Errorf("cannot write image to file: %v", err)
+	}
+
+	return file.Name(), size, nil
+}
+
+func (store *DiskImageStore) writeRecordTemp(image *ImageInfo) (string, error) {
+	record := &pb.ImageRecord{
+		Id:         image.Id,
+		LaptopId:   image.LaptopId,
+		ImageType:  image.Type,
+		Size:       uint64(image.Size),
+		UploadedAt: timestamppb.New(image.UploadedAt),
+	}
+	for name, size := range image.Variants {
+		record.Variants = append(record.Variants, &pb.ImageVariantRecord{Name: name, Size: uint64(size)})
+	}
+	sort.Slice(record.Variants, func(i, j int) bool {
+		return record.Variants[i].GetName() < record.Variants[j].GetName()
+	})
+
+	data, err := proto.Marshal(record)
+	if err != nil {
+		return "", fmt.Errorf("cannot marshal image record: %v", err)
+	}
+
+	file, _, err := store.writeTemp(image.Id, bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("cannot write image record: %v", err)
+	}
+
+	return file, nil
+}
+
+func (store *DiskImageStore) load() {
+	paths, err := filepath.Glob(filepath.Join(store.imageFolder, "*"+imageRecordExt))
+	if err != nil {
+		log.Printf("Cannot list image records: %v", err)
+		return
 	}
 
-	return imageId.String(), nil
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			log.Printf("Cannot read image record %s: %v", path, err)
+			continue
+		}
+
+		record := &pb.ImageRecord{}
+		if err := proto.Unmarshal(data, record); err != nil {
+			log.Printf("Cannot unmarshal image record %s: %v", path, err)
+			continue
+		}
+
+		// older records name the type by the extension the client sent
+		imageType, err := CanonicalImageType(record.GetImageType())
+		if err != nil {
+			imageType = record.GetImageType()
+		}
+
+		image := &ImageInfo{
+			Id:         record.GetId(),
+			LaptopId:   record.GetLaptopId(),
+			Type:       imageType,
+			Path:       store.imagePath(record.GetId(), imageExtension(record.GetImageType())),
+			Size:       int64(record.GetSize()),
+			UploadedAt: record.GetUploadedAt().AsTime(),
+		}
+		if len(record.GetVariants()) > 0 {
+			image.Variants = make(map[string]int64)
+			for _, variant := range record.GetVariants() {
+				image.Variants[variant.GetName()] = int64(variant.GetSize())
+			}
+		}
+		store.images[record.GetId()] = image
+	}
+}
+
+func (store *DiskImageStore) imagePath(imageId, extension string) string {
+	return fmt.Sprintf("%s/%s%s", store.imageFolder, imageId, extension)
+}
+
+// variantPath keeps the extension of the image, variants are stored in the
+// same type
+func (store *DiskImageStore) variantPath(image *ImageInfo, variant string) string {
+	return fmt.Sprintf("%s/%s-%s%s", store.imageFolder, image.Id, variant, imageExtension(image.Type))
+}
+
+func (store *DiskImageStore) recordPath(imageId string) string {
+	return fmt.Sprintf("%s/%s%s", store.imageFolder, imageId, imageRecordExt)
 }
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+var ErrUnsupportedImageType = errors.New("unsupported image type")
+
+// imageExtensions are the image types accepted, by MIME type, along with the
+// extension their files are stored with
+var imageExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/webp": ".webp",
+	"image/gif":  ".gif",
+}
+
+// imageTypeAliases are the file extensions clients may name a type by
+var imageTypeAliases = map[string]string{
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".png":  "image/png",
+	".webp": "image/webp",
+	".gif":  "image/gif",
+}
+
+// CanonicalImageType returns the MIME type of an accepted image type, named
+// either by its MIME type or by a file extension
+func CanonicalImageType(imageType string) (string, error) {
+	imageType = strings.ToLower(strings.TrimSpace(imageType))
+	if _, ok := imageExtensions[imageType]; ok {
+		return imageType, nil
+	}
+	if mimeType, ok := imageTypeAliases[imageType]; ok {
+		return mimeType, nil
+	}
+
+	return "", fmt.Errorf("%w: %q", ErrUnsupportedImageType, imageType)
+}
+
+// DetectImageType sniffs the MIME type of the image from its magic bytes.
+// If the client claimed a type, the image must be of that type.
+func DetectImageType(claimed string, imageData []byte) (string, error) {
+	detected := http.DetectContentType(imageData)
+	if _, ok := imageExtensions[detected]; !ok {
+		return "", fmt.Errorf("%w: content is %s", ErrUnsupportedImageType, detected)
+	}
+
+	if claimed == "" {
+		return detected, nil
+	}
+
+	mimeType, err := CanonicalImageType(claimed)
+	if err != nil {
+		return "", err
+	}
+	if mimeType != detected {
+		return "", fmt.Errorf("image claims to be %s but its content is %s", mimeType, detected)
+	}
+
+	return detected, nil
+}
+
+// imageExtension returns the extension of the files of an image type.
+// Images stored before types were checked keep the type the client sent,
+// which was used as their extension as is.
+func imageExtension(imageType string) string {
+	if extension, ok := imageExtensions[imageType]; ok {
+		return extension
+	}
+
+	return imageType
+}
@@ -0,0 +1,188 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/orkhanrustamli/pcbook/genarator"
+	pb "github.com/orkhanrustamli/pcbook/pcbook_proto/go"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestDetectImageType(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name      string
+		claimed   string
+		imageData []byte
+		mimeType  string
+	}{
+		{
+			name:      "jpeg",
+			claimed:   ".jpeg",
+			imageData: encodeTestImage(t, "image/jpeg", 2, 2),
+			mimeType:  "image/jpeg",
+		},
+		{
+			name:      "png by mime type",
+			claimed:   "image/png",
+			imageData: encodeTestImage(t, "image/png", 2, 2),
+			mimeType:  "image/png",
+		},
+		{
+			name:      "gif in upper case",
+			claimed:   ".GIF",
+			imageData: encodeTestImage(t, "image/gif", 2, 2),
+			mimeType:  "image/gif",
+		},
+		{
+			name:      "webp",
+			claimed:   ".webp",
+			imageData: encodeTestImage(t, "image/webp", 2, 2),
+			mimeType:  "image/webp",
+		},
+		{
+			name:      "unclaimed",
+			imageData: encodeTestImage(t, "image/png", 2, 2),
+			mimeType:  "image/png",
+		},
+		{
+			name:      "mismatch",
+			claimed:   ".jpg",
+			imageData: encodeTestImage(t, "image/png", 2, 2),
+		},
+		{
+			name:      "executable",
+			claimed:   ".exe",
+			imageData: []byte("MZ\x90\x00\x03\x00\x00\x00"),
+		},
+		{
+			name:      "path",
+			claimed:   "../x",
+			imageData: encodeTestImage(t, "image/png", 2, 2),
+		},
+		{
+			name:      "text",
+			imageData: []byte("image"),
+		},
+		{
+			name:      "svg",
+			claimed:   ".svg",
+			imageData: []byte("<svg xmlns=\"http://www.w3.org/2000/svg\"></svg>"),
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			mimeType, err := DetectImageType(tc.claimed, tc.imageData)
+			if tc.mimeType == "" {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.mimeType, mimeType)
+		})
+	}
+}
+
+func TestServerUploadImageType(t *testing.T) {
+	t.Parallel()
+
+	laptopStore := NewInMemoryLaptopStore()
+	imageStore := NewDiskImageStore(t.TempDir())
+
+	laptop := genarator.NewLaptop()
+	require.NoError(t, laptopStore.Save(laptop))
+
+	serverAddress := startTestLaptopServer(t, laptopStore, imageStore, nil)
+	client := startTestLaptopClient(t, serverAddress)
+
+	upload := func(imageType string, imageData []byte) (*pb.UploadImageResponse, error) {
+		stream, err := client.UploadImage(context.Background())
+		require.NoError(t, err)
+
+		err = stream.Send(&pb.UploadImageRequest{
+			Data: &pb.UploadImageRequest_Info{
+				Info: &pb.ImageInfo{LaptopId: laptop.GetId(), ImageType: imageType},
+			},
+		})
+		require.NoError(t, err)
+
+		// the server may reject the type before it reads any chunk
+		stream.Send(&pb.UploadImageRequest{
+			Data: &pb.UploadImageRequest_ChunkData{ChunkData: imageData},
+		})
+
+		return stream.CloseAndRecv()
+	}
+
+	res, err := upload("", encodeTestImage(t, "image/gif", 2, 2))
+	require.NoError(t, err)
+	require.Equal(t, "image/gif", res.GetImageType())
+
+	image, err := imageStore.Find(res.GetId())
+	require.NoError(t, err)
+	require.Equal(t, "image/gif", image.Type)
+	require.Equal(t, ".gif", image.Path[len(image.Path)-len(".gif"):])
+
+	_, err = upload(".exe", []byte("MZ\x90\x00"))
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = upload("../x", encodeTestImage(t, "image/png", 2, 2))
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = upload(".jpg", encodeTestImage(t, "image/png", 2, 2))
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = upload(".png", []byte("image"))
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	images := 0
+	require.NoError(t, imageStore.List(func(image *ImageInfo) error {
+		images++
+		return nil
+	}))
+	require.Equal(t, 1, images)
+}
+
+// encodeTestImage draws an image of the given size in the given type. The
+// standard library has no WebP encoder, so a WebP image is only its header.
+func encodeTestImage(t *testing.T, mimeType string, width, height int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			img.Set(x, y, color.RGBA{R: uint8(x * 255 / width), G: uint8(y * 255 / height), B: 128, A: 255})
+		}
+	}
+
+	buffer := bytes.Buffer{}
+	var err error
+	switch mimeType {
+	case "image/jpeg":
+		err = jpeg.Encode(&buffer, img, nil)
+	case "image/png":
+		err = png.Encode(&buffer, img)
+	case "image/gif":
+		err = gif.Encode(&buffer, img, nil)
+	case "image/webp":
+		_, err = buffer.WriteString("RIFF\x1a\x00\x00\x00WEBPVP8L\x0d\x00\x00\x00\x2f\x00\x00\x00\x00")
+	default:
+		t.Fatalf("cannot encode %s", mimeType)
+	}
+	require.NoError(t, err)
+
+	return buffer.Bytes()
+}
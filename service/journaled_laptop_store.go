@@ -0,0 +1,510 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	pb "github.com/orkhanrustamli/pcbook/pcbook_proto/go"
+	"google.golang.org/protobuf/proto"
+)
+
+type FsyncPolicy int
+
+const (
+	FsyncAlways FsyncPolicy = iota
+	FsyncInterval
+	FsyncNever
+)
+
+const (
+	journalFileName  = "journal.log"
+	snapshotFileName = "snapshot.pb"
+
+	maxJournalRecordSize = 16 << 20
+)
+
+var (
+	errCorruptRecord = errors.New("corrupt record")
+	crcTable         = crc32.MakeTable(crc32.Castagnoli)
+)
+
+func ParseFsyncPolicy(policy string) (FsyncPolicy, error) {
+	switch policy {
+	case "always":
+		return FsyncAlways, nil
+	case "interval":
+		return FsyncInterval, nil
+	case "never":
+		return FsyncNever, nil
+	default:
+		return 0, fmt.Errorf("unknown fsync policy: %s", policy)
+	}
+}
+
+type JournalOptions struct {
+	Dir              string
+	Fsync            FsyncPolicy
+	FsyncInterval    time.Duration
+	SnapshotInterval time.Duration
+}
+
+// JournaledLaptopStore serves reads from an InMemoryLaptopStore and makes
+// every mutation durable by appending it to a journal before applying it.
+// Snapshots compact the journal into a single file of length-prefixed laptops.
+type JournaledLaptopStore struct {
+	*InMemoryLaptopStore
+
+	mutex   sync.Mutex
+	options JournalOptions
+	journal *os.File
+	dirty   bool
+	// broken is set once a failed append could not be rolled back, after
+	// which the journal takes no more records
+	broken error
+	done   chan struct{}
+	wait   sync.WaitGroup
+}
+
+func NewJournaledLaptopStore(options JournalOptions) (*JournaledLaptopStore, error) {
+	if err := os.MkdirAll(options.Dir, 0755); err != nil {
+		return nil, fmt.Errorf("cannot create journal directory: %v", err)
+	}
+
+	store := &JournaledLaptopStore{
+		InMemoryLaptopStore: NewInMemoryLaptopStore(),
+		options:             options,
+		done:                make(chan struct{}),
+	}
+
+	if err := store.loadSnapshot(); err != nil {
+		return nil, err
+	}
+
+	journal, err := store.replayJournal()
+	if err != nil {
+		return nil, err
+	}
+	store.journal = journal
+
+	store.wait.Add(1)
+	go store.background()
+
+	return store, nil
+}
+
+func (store *JournaledLaptopStore) Save(laptop *pb.Laptop) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	existing, err := store.InMemoryLaptopStore.Find(laptop.GetId())
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return ErrAlreadyExists
+	}
+
+	entry := &pb.JournalEntry{
+		Op:     pb.JournalEntry_SAVE,
+		Laptop: laptop,
+	}
+	if err := store.append(entry); err != nil {
+		return err
+	}
+
+	return store.InMemoryLaptopStore.Save(laptop)
+}
+
+func (store *JournaledLaptopStore) Delete(id string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	existing, err := store.InMemoryLaptopStore.Find(id)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		return ErrNotFound
+	}
+
+	entry := &pb.JournalEntry{
+		Op:       pb.JournalEntry_DELETE,
+		LaptopId: id,
+	}
+	if err := store.append(entry); err != nil {
+		return err
+	}
+
+	return store.InMemoryLaptopStore.Delete(id)
+}
+
+// Snapshot writes every laptop to a new snapshot file and starts an empty
+// journal. The snapshot is renamed into place only once it is fully synced,
+// so a crash at any point leaves either the old or the new state on disk.
+func (store *JournaledLaptopStore) Snapshot() error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	tmpPath := filepath.Join(store.options.Dir, snapshotFileName+".tmp")
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return fmt.Errorf("cannot create snapshot: %v", err)
+	}
+
+	writer := bufio.NewWriter(file)
+	count := 0
+	_, err = store.InMemoryLaptopStore.Search(context.Background(), nil, func(laptop *pb.Laptop) error {
+		count++
+		return writeRecord(writer, laptop)
+	})
+	if err == nil {
+		err = writer.Flush()
+	}
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("cannot write snapshot: %v", err)
+	}
+
+	if err := os.Rename(tmpPath, store.path(snapshotFileName)); err != nil {
+		return fmt.Errorf("cannot rename snapshot: %v", err)
+	}
+	if err := syncDir(store.options.Dir); err != nil {
+		return err
+	}
+
+	if err := store.journal.Truncate(0); err != nil {
+		return fmt.Errorf("cannot truncate journal: %v", err)
+	}
+	if _, err := store.journal.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("cannot truncate journal: %v", err)
+	}
+	if err := store.journal.Sync(); err != nil {
+		return fmt.Errorf("cannot sync journal: %v", err)
+	}
+	store.dirty = false
+
+	log.Printf("Wrote snapshot with %d laptops", count)
+	return nil
+}
+
+func (store *JournaledLaptopStore) Close() error {
+	close(store.done)
+	store.wait.Wait()
+
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if err := store.journal.Sync(); err != nil {
+		store.journal.Close()
+		return fmt.Errorf("cannot sync journal: %v", err)
+	}
+
+	return store.journal.Close()
+}
+
+func (store *JournaledLaptopStore) append(entry *pb.JournalEntry) error {
+	if store.broken != nil {
+		return store.broken
+	}
+
+	offset, err := store.journal.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return fmt.Errorf("cannot append to journal: %v", err)
+	}
+
+	if err := writeRecord(store.journal, entry); err != nil {
+		return store.rollback(offset, fmt.Errorf("cannot append to journal: %v", err))
+	}
+
+	if store.options.Fsync == FsyncAlways {
+		if err := store.journal.Sync(); err != nil {
+			return store.rollback(offset, fmt.Errorf("cannot sync journal: %v", err))
+		}
+		return nil
+	}
+
+	store.dirty = true
+	return nil
+}
+
+// rollback cuts off what a failed append left after offset, so that the
+// next record does not follow a partial one that replay would stop at. It
+// returns err.
+func (store *JournaledLaptopStore) rollback(offset int64, err error) error {
+	truncateErr := store.journal.Truncate(offset)
+	if truncateErr == nil {
+		_, truncateErr = store.journal.Seek(offset, io.SeekStart)
+	}
+	if truncateErr != nil {
+		store.broken = fmt.Errorf("journal is broken: %v, and cannot be rolled back: %v", err, truncateErr)
+		return store.broken
+	}
+
+	return err
+}
+
+func (store *JournaledLaptopStore) background() {
+	defer store.wait.Done()
+
+	var fsync, snapshot <-chan time.Time
+	if store.options.Fsync == FsyncInterval {
+		interval := store.options.FsyncInterval
+		if interval <= 0 {
+			interval = time.Second
+		}
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		fsync = ticker.C
+	}
+	if store.options.SnapshotInterval > 0 {
+		ticker := time.NewTicker(store.options.SnapshotInterval)
+		defer ticker.Stop()
+		snapshot = ticker.C
+	}
+
+	for {
+		select {
+		case <-store.done:
+			return
+		case <-fsync:
+			store.mutex.Lock()
+			if store.dirty {
+				if err := store.journal.Sync(); err != nil {
+					log.Printf("Cannot sync journal: %v", err)
+				} else {
+					store.dirty = false
+				}
+			}
+			store.mutex.Unlock()
+		case <-snapshot:
+			if err := store.Snapshot(); err != nil {
+				log.Printf("Cannot write snapshot: %v", err)
+			}
+		}
+	}
+}
+
+func (store *JournaledLaptopStore) loadSnapshot() error {
+	file, err := os.Open(store.path(snapshotFileName))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("cannot open snapshot: %v", err)
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	for {
+		laptop := &pb.Laptop{}
+		_, err := readRecord(reader, laptop)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("cannot read snapshot: %v", err)
+		}
+
+		if err := store.InMemoryLaptopStore.Save(laptop); err != nil {
+			return fmt.Errorf("cannot load snapshot: %v", err)
+		}
+	}
+}
+
+// replayJournal applies every valid record of the journal and cuts off a
+// torn last record, which is what a crash in the middle of an append leaves
+// behind. A bad record followed by more data is corruption, and fails the
+// replay rather than losing the records after it.
+func (store *JournaledLaptopStore) replayJournal() (*os.File, error) {
+	journal, err := os.OpenFile(store.path(journalFileName), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open journal: %v", err)
+	}
+
+	reader := bufio.NewReader(journal)
+	var offset int64
+	replayed := 0
+	for {
+		entry := &pb.JournalEntry{}
+		size, err := readRecord(reader, entry)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			torn, tornErr := isTornRecord(journal, offset)
+			if tornErr == nil && !torn {
+				tornErr = fmt.Errorf("record at offset %d is followed by more data: %v", offset, err)
+			}
+			if tornErr != nil {
+				journal.Close()
+				return nil, fmt.Errorf("cannot replay corrupt journal: %v", tornErr)
+			}
+
+			log.Printf("Discarding torn journal record after %d records at offset %d: %v", replayed, offset, err)
+			break
+		}
+
+		if err := store.apply(entry); err != nil {
+			journal.Close()
+			return nil, fmt.Errorf("cannot replay journal: %v", err)
+		}
+
+		offset += size
+		replayed++
+	}
+
+	if err := journal.Truncate(offset); err != nil {
+		journal.Close()
+		return nil, fmt.Errorf("cannot truncate journal: %v", err)
+	}
+	if _, err := journal.Seek(offset, io.SeekStart); err != nil {
+		journal.Close()
+		return nil, fmt.Errorf("cannot seek journal: %v", err)
+	}
+
+	log.Printf("Replayed %d journal records", replayed)
+	return journal, nil
+}
+
+// isTornRecord tells if the bad record at offset runs up to the end of the
+// file. A header that cannot be read is torn if a full one would not fit.
+func isTornRecord(journal *os.File, offset int64) (bool, error) {
+	info, err := journal.Stat()
+	if err != nil {
+		return false, fmt.Errorf("cannot stat journal: %v", err)
+	}
+	remaining := info.Size() - offset
+
+	length, err := binary.ReadUvarint(bufio.NewReader(io.NewSectionReader(journal, offset, remaining)))
+	if err != nil {
+		return remaining <= binary.MaxVarintLen64+4, nil
+	}
+
+	if length >= uint64(remaining) {
+		return true, nil
+	}
+
+	var header [binary.MaxVarintLen64]byte
+	size := int64(binary.PutUvarint(header[:], length)) + 4 + int64(length)
+	return size >= remaining, nil
+}
+
+func (store *JournaledLaptopStore) apply(entry *pb.JournalEntry) error {
+	switch entry.GetOp() {
+	case pb.JournalEntry_SAVE:
+		// The laptop may already be part of the snapshot if the process died
+		// between writing the snapshot and truncating the journal
+		err := store.InMemoryLaptopStore.Save(entry.GetLaptop())
+		if err != nil && !errors.Is(err, ErrAlreadyExists) {
+			return err
+		}
+		return nil
+	case pb.JournalEntry_DELETE:
+		err := store.InMemoryLaptopStore.Delete(entry.GetLaptopId())
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return err
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown journal operation: %v", entry.GetOp())
+	}
+}
+
+func (store *JournaledLaptopStore) path(name string) string {
+	return filepath.Join(store.options.Dir, name)
+}
+
+// writeRecord frames message as a uvarint length, a CRC-32C checksum of the
+// payload and the payload itself
+func writeRecord(writer io.Writer, message proto.Message) error {
+	payload, err := proto.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("cannot marshal record: %v", err)
+	}
+
+	return writeRecordPayload(writer, payload)
+}
+
+func writeRecordPayload(writer io.Writer, payload []byte) error {
+	header := make([]byte, binary.MaxVarintLen64+4)
+	n := binary.PutUvarint(header, uint64(len(payload)))
+	binary.LittleEndian.PutUint32(header[n:], crc32.Checksum(payload, crcTable))
+
+	record := append(header[:n+4], payload...)
+	_, err := writer.Write(record)
+	return err
+}
+
+// readRecord returns the number of bytes consumed, or io.EOF if the reader is
+// exhausted exactly at a record boundary
+func readRecord(reader *bufio.Reader, message proto.Message) (int64, error) {
+	payload, n, err := readRecordPayload(reader)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := proto.Unmarshal(payload, message); err != nil {
+		return 0, fmt.Errorf("%w: %v", errCorruptRecord, err)
+	}
+
+	return n, nil
+}
+
+// readRecordPayload returns the checked payload of the next record and the
+// number of bytes consumed
+func readRecordPayload(reader *bufio.Reader) ([]byte, int64, error) {
+	length, err := binary.ReadUvarint(reader)
+	if err == io.EOF {
+		return nil, 0, io.EOF
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("%w: %v", errCorruptRecord, err)
+	}
+	if length > maxJournalRecordSize {
+		return nil, 0, fmt.Errorf("%w: record of %d bytes", errCorruptRecord, length)
+	}
+
+	buffer := make([]byte, 4+length)
+	if _, err := io.ReadFull(reader, buffer); err != nil {
+		return nil, 0, fmt.Errorf("%w: %v", errCorruptRecord, err)
+	}
+
+	payload := buffer[4:]
+	if crc32.Checksum(payload, crcTable) != binary.LittleEndian.Uint32(buffer) {
+		return nil, 0, fmt.Errorf("%w: checksum mismatch", errCorruptRecord)
+	}
+
+	var header [binary.MaxVarintLen64]byte
+	return payload, int64(binary.PutUvarint(header[:], length)) + int64(len(buffer)), nil
+}
+
+func syncDir(dir string) error {
+	file, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("cannot open directory: %v", err)
+	}
+	defer file.Close()
+
+	if err := file.Sync(); err != nil {
+		return fmt.Errorf("cannot sync directory: %v", err)
+	}
+
+	return nil
+}
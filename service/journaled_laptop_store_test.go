@@ -0,0 +1,192 @@
+package service
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/orkhanrustamli/pcbook/genarator"
+	pb "github.com/orkhanrustamli/pcbook/pcbook_proto/go"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestJournaledLaptopStoreReopen(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	options := JournalOptions{Dir: dir, Fsync: FsyncAlways}
+
+	store, err := NewJournaledLaptopStore(options)
+	require.NoError(t, err)
+
+	laptop1 := genarator.NewLaptop()
+	laptop2 := genarator.NewLaptop()
+	require.NoError(t, store.Save(laptop1))
+	require.NoError(t, store.Snapshot())
+	require.NoError(t, store.Save(laptop2))
+	require.ErrorIs(t, store.Save(laptop1), ErrAlreadyExists)
+
+	laptop3 := genarator.NewLaptop()
+	require.NoError(t, store.Save(laptop3))
+	require.NoError(t, store.Delete(laptop3.GetId()))
+	require.NoError(t, store.Close())
+
+	store, err = NewJournaledLaptopStore(options)
+	require.NoError(t, err)
+	defer store.Close()
+
+	for _, laptop := range []*pb.Laptop{laptop1, laptop2} {
+		other, err := store.Find(laptop.GetId())
+		require.NoError(t, err)
+		require.True(t, proto.Equal(laptop, other))
+	}
+	require.ErrorIs(t, store.Save(laptop2), ErrAlreadyExists)
+
+	other, err := store.Find(laptop3.GetId())
+	require.NoError(t, err)
+	require.Nil(t, other)
+}
+
+func TestJournaledLaptopStoreCorruptTail(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name    string
+		corrupt func(data []byte) []byte
+	}{
+		{
+			name: "truncated",
+			corrupt: func(data []byte) []byte {
+				return data[:len(data)-3]
+			},
+		},
+		{
+			name: "bit_flip",
+			corrupt: func(data []byte) []byte {
+				data[len(data)-1] ^= 0xff
+				return data
+			},
+		},
+		{
+			name: "garbage",
+			corrupt: func(data []byte) []byte {
+				return append(data, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			options := JournalOptions{Dir: dir, Fsync: FsyncNever}
+
+			store, err := NewJournaledLaptopStore(options)
+			require.NoError(t, err)
+
+			kept := genarator.NewLaptop()
+			lost := genarator.NewLaptop()
+			require.NoError(t, store.Save(kept))
+			require.NoError(t, store.Save(lost))
+			require.NoError(t, store.Close())
+
+			path := filepath.Join(dir, journalFileName)
+			data, err := os.ReadFile(path)
+			require.NoError(t, err)
+			require.NoError(t, os.WriteFile(path, tc.corrupt(data), 0644))
+
+			store, err = NewJournaledLaptopStore(options)
+			require.NoError(t, err)
+
+			other, err := store.Find(kept.GetId())
+			require.NoError(t, err)
+			require.NotNil(t, other)
+
+			if tc.name != "garbage" {
+				other, err = store.Find(lost.GetId())
+				require.NoError(t, err)
+				require.Nil(t, other)
+			}
+
+			// new records are appended after the last valid one
+			laptop := genarator.NewLaptop()
+			require.NoError(t, store.Save(laptop))
+			require.NoError(t, store.Close())
+
+			store, err = NewJournaledLaptopStore(options)
+			require.NoError(t, err)
+			defer store.Close()
+
+			other, err = store.Find(laptop.GetId())
+			require.NoError(t, err)
+			require.NotNil(t, other)
+		})
+	}
+}
+
+func TestJournaledLaptopStoreCorruptMiddle(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	options := JournalOptions{Dir: dir, Fsync: FsyncNever}
+
+	store, err := NewJournaledLaptopStore(options)
+	require.NoError(t, err)
+	require.NoError(t, store.Save(genarator.NewLaptop()))
+	require.NoError(t, store.Save(genarator.NewLaptop()))
+	require.NoError(t, store.Close())
+
+	path := filepath.Join(dir, journalFileName)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	data[10] ^= 0xff
+	require.NoError(t, os.WriteFile(path, data, 0644))
+
+	// the records after the bad one are kept for someone to look at
+	_, err = NewJournaledLaptopStore(options)
+	require.Error(t, err)
+
+	other, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, data, other)
+}
+
+func TestJournaledLaptopStoreRollback(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	options := JournalOptions{Dir: dir, Fsync: FsyncAlways}
+
+	store, err := NewJournaledLaptopStore(options)
+	require.NoError(t, err)
+
+	kept := genarator.NewLaptop()
+	require.NoError(t, store.Save(kept))
+
+	// what a write cut short by a full disk leaves behind
+	offset, err := store.journal.Seek(0, io.SeekCurrent)
+	require.NoError(t, err)
+	_, err = store.journal.Write([]byte{0x80, 0x01, 0xde, 0xad})
+	require.NoError(t, err)
+	failed := errors.New("no space left on device")
+	require.Equal(t, failed, store.rollback(offset, failed))
+
+	laptop := genarator.NewLaptop()
+	require.NoError(t, store.Save(laptop))
+	require.NoError(t, store.Close())
+
+	store, err = NewJournaledLaptopStore(options)
+	require.NoError(t, err)
+	defer store.Close()
+
+	for _, id := range []string{kept.GetId(), laptop.GetId()} {
+		other, err := store.Find(id)
+		require.NoError(t, err)
+		require.NotNil(t, other)
+	}
+}
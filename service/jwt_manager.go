@@ -16,6 +16,7 @@ type UserClaims struct {
 	jwt.StandardClaims
 	Username string
 	Role     string
+	Tenant   string
 }
 
 func NewJWTManager(secretKey string, tokenDuration time.Duration) *JWTManager {
@@ -25,13 +26,14 @@ func NewJWTManager(secretKey string, tokenDuration time.Duration) *JWTManager {
 	}
 }
 
-func (jwtManager *JWTManager) Generate(username string, role string) (string, error) {
+func (jwtManager *JWTManager) Generate(username string, role string, tenant string) (string, error) {
 	claims := UserClaims{
 		StandardClaims: jwt.StandardClaims{
 			ExpiresAt: time.Now().Add(jwtManager.TokenDuration).Unix(),
 		},
 		Username: username,
 		Role:     role,
+		Tenant:   tenant,
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
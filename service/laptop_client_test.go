@@ -2,224 +2,847 @@ package service
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"fmt"
 	"io"
+	"math"
 	"net"
 	"os"
 	"path/filepath"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/orkhanrustamli/pcbook/genarator"
+	"github.com/orkhanrustamli/pcbook/memory"
 	pb "github.com/orkhanrustamli/pcbook/pcbook_proto/go"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 func TestClientCreatelaptop(t *testing.T) {
 	t.Parallel()
 
-	store := NewInMemoryLaptopStore()
+	runWithLaptopStores(t, func(t *testing.T, newStore func(t *testing.T) LaptopStore) {
+		store := newStore(t)
 
-	address := startTestLaptopServer(t, store, nil, nil)
-	laptopClient := startTestLaptopClient(t, address)
+		address := startTestLaptopServer(t, store, nil, nil)
+		laptopClient := startTestLaptopClient(t, address)
 
-	laptop := genarator.NewLaptop()
-	laptopId := laptop.Id
-	laptopRequest := &pb.CreateLaptopRequest{
-		Laptop: laptop,
-	}
-
-	res, err := laptopClient.CreateLaptop(context.Background(), laptopRequest)
-	require.NoError(t, err)
-	require.NotNil(t, res)
-	require.NotEmpty(t, res.Id)
-	require.Equal(t, res.Id, laptopId)
+		laptop := genarator.NewLaptop()
+		laptopId := laptop.Id
+		laptopRequest := &pb.CreateLaptopRequest{
+			Laptop: laptop,
+		}
 
-	other, err := store.Find(laptopId)
-	require.NoError(t, err)
-	require.NotNil(t, other)
+		res, err := laptopClient.CreateLaptop(context.Background(), laptopRequest)
+		require.NoError(t, err)
+		require.NotNil(t, res)
+		require.NotEmpty(t, res.Id)
+		require.Equal(t, res.Id, laptopId)
 
-	requireSameLaptop(t, laptop, other)
+		other, err := store.Find(laptopId)
+		require.NoError(t, err)
+		require.NotNil(t, other)
+		require.NotNil(t, other.CreatedAt)
 
+		laptop.CreatedAt = other.CreatedAt
+		requireSameLaptop(t, laptop, other)
+	})
 }
 
 func TestClientSearchLaptop(t *testing.T) {
 	t.Parallel()
 
-	filter := &pb.Filter{
-		MaxPriceUsd: 2000,
-		MinCpuCores: 4,
-		MinCpuGhz:   2.2,
-		MinRam:      &pb.Memory{Value: 8, Unit: pb.Memory_GIGABYTE},
-	}
+	runWithLaptopStores(t, func(t *testing.T, newStore func(t *testing.T) LaptopStore) {
+		filter := &pb.Filter{
+			MaxPriceUsd: 2000,
+			MinCpuCores: 4,
+			MinCpuGhz:   2.2,
+			MinRam:      &pb.Memory{Value: 8, Unit: pb.Memory_GIGABYTE},
+		}
+
+		store := newStore(t)
+		expectedIDs := make(map[string]bool)
+
+		for i := 0; i < 6; i++ {
+			newLaptop := genarator.NewLaptop()
+			switch i {
+			case 0:
+				newLaptop.PriceUsd = 3000
+			case 1:
+				newLaptop.Cpu.NumberCores = 3
+			case 3:
+				newLaptop.Cpu.MinGhz = 2.0
+			case 4:
+				newLaptop.Ram = &pb.Memory{Value: 7, Unit: pb.Memory_GIGABYTE}
+			case 5:
+				newLaptop.PriceUsd = 1900
+				newLaptop.Cpu.NumberCores = 5
+				newLaptop.Cpu.MinGhz = 2.5
+				newLaptop.Ram = &pb.Memory{Value: 9, Unit: pb.Memory_GIGABYTE}
+				expectedIDs[newLaptop.GetId()] = true
+			}
+
+			err := store.Save(newLaptop)
+			require.NoError(t, err)
+		}
 
-	store := NewInMemoryLaptopStore()
-	expectedIDs := make(map[string]bool)
-
-	for i := 0; i < 6; i++ {
-		newLaptop := genarator.NewLaptop()
-		switch i {
-		case 0:
-			newLaptop.PriceUsd = 3000
-		case 1:
-			newLaptop.Cpu.NumberCores = 3
-		case 3:
-			newLaptop.Cpu.MinGhz = 2.0
-		case 4:
-			newLaptop.Ram = &pb.Memory{Value: 7, Unit: pb.Memory_GIGABYTE}
-		case 5:
-			newLaptop.PriceUsd = 1900
-			newLaptop.Cpu.NumberCores = 5
-			newLaptop.Cpu.MinGhz = 2.5
-			newLaptop.Ram = &pb.Memory{Value: 9, Unit: pb.Memory_GIGABYTE}
-			expectedIDs[newLaptop.GetId()] = true
-		}
-
-		err := store.Save(newLaptop)
+		address := startTestLaptopServer(t, store, nil, nil)
+		client := startTestLaptopClient(t, address)
+
+		req := &pb.SearchLaptopRequest{Filter: filter}
+		stream, err := client.SearchLaptop(context.Background(), req)
 		require.NoError(t, err)
-	}
 
-	address := startTestLaptopServer(t, store, nil, nil)
-	client := startTestLaptopClient(t, address)
+		found := 0
+		for {
+			res, err := stream.Recv()
+			if err == io.EOF {
+				break
+			}
 
-	req := &pb.SearchLaptopRequest{Filter: filter}
-	stream, err := client.SearchLaptop(context.Background(), req)
-	require.NoError(t, err)
+			require.NoError(t, err)
+			require.Contains(t, expectedIDs, res.Laptop.GetId())
 
-	found := 0
-	for {
-		res, err := stream.Recv()
-		if err == io.EOF {
-			break
+			found++
+		}
+
+		require.Equal(t, len(expectedIDs), found)
+	})
+}
+
+func TestClientSearchLaptopMemoryUnits(t *testing.T) {
+	t.Parallel()
+
+	runWithLaptopStores(t, func(t *testing.T, newStore func(t *testing.T) LaptopStore) {
+		store := newStore(t)
+		expectedIDs := make(map[string]bool)
+
+		rams := []*pb.Memory{
+			memory.Terabytes(3_000_000),
+			memory.Gibibytes(8),
+			memory.Gigabytes(8),
+			memory.Megabytes(7999),
+			{Value: 64, Unit: pb.Memory_UNKNOWN},
+		}
+		for i, ram := range rams {
+			laptop := genarator.NewLaptop()
+			laptop.Ram = ram
+			require.NoError(t, store.Save(laptop))
+
+			if i < 3 {
+				expectedIDs[laptop.GetId()] = true
+			}
 		}
 
+		serverAddress := startTestLaptopServer(t, store, nil, nil)
+		client := startTestLaptopClient(t, serverAddress)
+
+		filter := &pb.Filter{MaxPriceUsd: 5000, MinRam: memory.Gigabytes(8)}
+		stream, err := client.SearchLaptop(context.Background(), &pb.SearchLaptopRequest{Filter: filter})
 		require.NoError(t, err)
-		require.Contains(t, expectedIDs, res.Laptop.GetId())
 
-		found++
-	}
+		found := make(map[string]bool)
+		for {
+			res, err := stream.Recv()
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err)
+			found[res.GetLaptop().GetId()] = true
+		}
+		require.Equal(t, expectedIDs, found)
 
-	require.Equal(t, len(expectedIDs), found)
+		filter = &pb.Filter{MaxPriceUsd: 5000, MinRam: &pb.Memory{Value: 8}}
+		stream, err = client.SearchLaptop(context.Background(), &pb.SearchLaptopRequest{Filter: filter})
+		require.NoError(t, err)
+
+		_, err = stream.Recv()
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
 }
 
-func TestClientUploadImage(t *testing.T) {
+func TestClientSearchLaptopDeadline(t *testing.T) {
 	t.Parallel()
 
-	imageFolder := "../tmp"
-	imagePath := fmt.Sprintf("%s/laptop.jpg", imageFolder)
-	imageType := filepath.Ext(imagePath)
+	runWithLaptopStores(t, func(t *testing.T, newStore func(t *testing.T) LaptopStore) {
+		store := &slowLaptopStore{
+			LaptopStore: newStore(t),
+			delay:       50 * time.Millisecond,
+		}
+		for i := 0; i < 20; i++ {
+			laptop := genarator.NewLaptop()
+			laptop.PriceUsd = 2000
+			require.NoError(t, store.Save(laptop))
+		}
 
-	laptopStore := NewInMemoryLaptopStore()
-	imageStore := NewDiskImageStore(imageFolder)
+		serverAddress := startTestLaptopServer(t, store, nil, nil)
+		client := startTestLaptopClient(t, serverAddress)
+
+		filter := &pb.Filter{MaxPriceUsd: 3000}
+
+		t.Run("complete", func(t *testing.T) {
+			stream, err := client.SearchLaptop(context.Background(), &pb.SearchLaptopRequest{Filter: &pb.Filter{MaxPriceUsd: 1000}})
+			require.NoError(t, err)
+
+			_, err = stream.Recv()
+			require.Equal(t, io.EOF, err)
+
+			trailer := stream.Trailer()
+			require.Equal(t, []string{"true"}, trailer.Get(SearchCompleteTrailer))
+			// SQLite only returns the rows its WHERE clause lets through
+			scanned := "20"
+			if _, ok := store.LaptopStore.(*SQLiteLaptopStore); ok {
+				scanned = "0"
+			}
+			require.Equal(t, []string{scanned}, trailer.Get(SearchScannedTrailer))
+			require.Equal(t, []string{"0"}, trailer.Get(SearchMatchedTrailer))
+			require.Equal(t, []string{SearchCompleted}, trailer.Get(SearchStopReasonTrailer))
+		})
+
+		t.Run("partial", func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+			defer cancel()
+
+			stream, err := client.SearchLaptop(ctx, &pb.SearchLaptopRequest{Filter: filter})
+			require.NoError(t, err)
+
+			found := 0
+			for {
+				_, err := stream.Recv()
+				if err == io.EOF {
+					break
+				}
+				require.NoError(t, err)
+				found++
+			}
+
+			trailer := stream.Trailer()
+			require.Equal(t, []string{"false"}, trailer.Get(SearchCompleteTrailer))
+			require.Equal(t, []string{strconv.Itoa(found)}, trailer.Get(SearchMatchedTrailer))
+			require.Equal(t, []string{SearchDeadlineExceeded}, trailer.Get(SearchStopReasonTrailer))
+			require.Less(t, found, 20)
+		})
+
+		t.Run("fail_on_deadline", func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+			defer cancel()
+
+			req := &pb.SearchLaptopRequest{Filter: filter, FailOnDeadline: true}
+			stream, err := client.SearchLaptop(ctx, req)
+			require.NoError(t, err)
+
+			for {
+				_, err = stream.Recv()
+				if err != nil {
+					break
+				}
+			}
+			require.Equal(t, codes.DeadlineExceeded, status.Code(err))
+		})
+	})
+}
 
-	laptop := genarator.NewLaptop()
-	laptopStore.Save(laptop)
+func TestClientSearchRankedLaptop(t *testing.T) {
+	t.Parallel()
 
-	serverAddress := startTestLaptopServer(t, laptopStore, imageStore, nil)
-	client := startTestLaptopClient(t, serverAddress)
+	runWithLaptopStores(t, func(t *testing.T, newStore func(t *testing.T) LaptopStore) {
+		filter := &pb.Filter{
+			MaxPriceUsd: 5000,
+			MinRam:      &pb.Memory{Value: 1, Unit: pb.Memory_GIGABYTE},
+		}
 
-	image, err := os.Open(imagePath)
-	require.NoError(t, err)
-	defer image.Close()
+		laptopStore := newStore(t)
+		ratingStore := NewInMemoryRatingStore()
 
-	stream, err := client.UploadImage(context.Background())
-	require.NoError(t, err)
+		scores := []float64{3, 9, 6}
+		expectedIDs := make([]string, len(scores))
+		for i, score := range scores {
+			laptop := genarator.NewLaptop()
+			require.NoError(t, laptopStore.Save(laptop))
+			ratingStore.Rate(laptop.GetId(), score)
+			expectedIDs[i] = laptop.GetId()
+		}
+		expectedIDs = []string{expectedIDs[1], expectedIDs[2], expectedIDs[0]}
 
-	req := &pb.UploadImageRequest{
-		Data: &pb.UploadImageRequest_Info{
-			Info: &pb.ImageInfo{
-				LaptopId:  laptop.GetId(),
-				ImageType: imageType,
+		serverAddress := startTestLaptopServer(t, laptopStore, nil, ratingStore)
+		client := startTestLaptopClient(t, serverAddress)
+
+		req := &pb.SearchLaptopRequest{
+			Filter:  filter,
+			Ranking: &pb.RankingOptions{AverageRatingWeight: 1},
+		}
+		stream, err := client.SearchLaptop(context.Background(), req)
+		require.NoError(t, err)
+
+		foundIDs := []string{}
+		foundScores := []float64{}
+		for {
+			res, err := stream.Recv()
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err)
+
+			foundIDs = append(foundIDs, res.GetLaptop().GetId())
+			foundScores = append(foundScores, res.GetScore())
+		}
+
+		require.Equal(t, expectedIDs, foundIDs)
+		require.Equal(t, []float64{0.9, 0.6, 0.3}, foundScores)
+
+		for _, ranking := range []*pb.RankingOptions{
+			{AverageRatingWeight: 1, RecencyWeight: -1},
+			{AverageRatingWeight: 1, RecencyWeight: math.Inf(1)},
+		} {
+			req.Ranking = ranking
+			stream, err = client.SearchLaptop(context.Background(), req)
+			require.NoError(t, err)
+
+			_, err = stream.Recv()
+			require.Equal(t, codes.InvalidArgument, status.Code(err), "%v", ranking)
+		}
+	})
+}
+
+func TestClientSearchLaptopFuzzy(t *testing.T) {
+	t.Parallel()
+
+	runWithLaptopStores(t, func(t *testing.T, newStore func(t *testing.T) LaptopStore) {
+		store := newStore(t)
+		for _, name := range []string{"Macbook Pro", "Thinkpad X1"} {
+			laptop := genarator.NewLaptop()
+			laptop.Brand = map[string]string{"Macbook Pro": "Apple", "Thinkpad X1": "Lenovo"}[name]
+			laptop.Name = name
+			laptop.PriceUsd = 2000
+			require.NoError(t, store.Save(laptop))
+		}
+
+		serverAddress := startTestLaptopServer(t, store, nil, nil)
+		client := startTestLaptopClient(t, serverAddress)
+
+		testCases := []struct {
+			name       string
+			filter     *pb.Filter
+			found      []string
+			didYouMean *pb.SearchSuggestion
+		}{
+			{
+				name:   "exact_name",
+				filter: &pb.Filter{MaxPriceUsd: 3000, Name: "macbook"},
+				found:  []string{"Macbook Pro"},
 			},
-		},
-	}
+			{
+				name:   "fuzzy_brand",
+				filter: &pb.Filter{MaxPriceUsd: 3000, Brand: "Lenvo", MaxEditDistance: 1},
+				found:  []string{"Thinkpad X1"},
+			},
+			{
+				name:   "fuzzy_name",
+				filter: &pb.Filter{MaxPriceUsd: 3000, Name: "Macbok", MaxEditDistance: 2},
+				found:  []string{"Macbook Pro"},
+			},
+			{
+				name:       "did_you_mean",
+				filter:     &pb.Filter{MaxPriceUsd: 3000, Brand: "Lenvo", Name: "Macbok pro"},
+				didYouMean: &pb.SearchSuggestion{Brand: "Lenovo", Name: "Macbook Pro"},
+			},
+			{
+				name:   "no_suggestion",
+				filter: &pb.Filter{MaxPriceUsd: 3000, Brand: "Samsung"},
+			},
+			{
+				name:   "exact_brand_filtered_out",
+				filter: &pb.Filter{MaxPriceUsd: 1000, Brand: "Apple"},
+			},
+			{
+				name:       "exact_brand_fuzzy_name",
+				filter:     &pb.Filter{MaxPriceUsd: 3000, Brand: "Apple", Name: "Thinkpad X2"},
+				didYouMean: &pb.SearchSuggestion{Name: "Thinkpad X1"},
+			},
+		}
 
-	err = stream.Send(req)
-	require.NoError(t, err)
+		for _, tc := range testCases {
+			tc := tc
+			t.Run(tc.name, func(t *testing.T) {
+				stream, err := client.SearchLaptop(context.Background(), &pb.SearchLaptopRequest{Filter: tc.filter})
+				require.NoError(t, err)
+
+				found := []string{}
+				var didYouMean *pb.SearchSuggestion
+				for {
+					res, err := stream.Recv()
+					if err == io.EOF {
+						break
+					}
+					require.NoError(t, err)
+
+					if res.GetDidYouMean() != nil {
+						didYouMean = res.GetDidYouMean()
+						continue
+					}
+					found = append(found, res.GetLaptop().GetName())
+				}
+
+				require.ElementsMatch(t, tc.found, found)
+				if tc.didYouMean == nil {
+					require.Nil(t, didYouMean)
+				} else {
+					require.Equal(t, tc.didYouMean.GetBrand(), didYouMean.GetBrand())
+					require.Equal(t, tc.didYouMean.GetName(), didYouMean.GetName())
+				}
+			})
+		}
+	})
+}
 
-	reader := bufio.NewReader(image)
-	buffer := make([]byte, 1024)
-	size := 0
+func TestClientSearchLaptopBatch(t *testing.T) {
+	t.Parallel()
 
-	for {
-		n, err := reader.Read(buffer)
-		if err == io.EOF {
-			break
+	runWithLaptopStores(t, func(t *testing.T, newStore func(t *testing.T) LaptopStore) {
+		filter := &pb.Filter{MaxPriceUsd: 5000}
+
+		store := newStore(t)
+		laptopSize := 0
+		for i := 0; i < 25; i++ {
+			laptop := genarator.NewLaptop()
+			require.NoError(t, store.Save(laptop))
+
+			if size := proto.Size(laptop); size > laptopSize {
+				laptopSize = size
+			}
 		}
+
+		singleLaptopBatches := make([]int, 25)
+		for i := range singleLaptopBatches {
+			singleLaptopBatches[i] = 1
+		}
+
+		serverAddress := startTestLaptopServer(t, store, nil, nil)
+		client := startTestLaptopClient(t, serverAddress)
+
+		testCases := []struct {
+			name    string
+			req     *pb.SearchLaptopBatchRequest
+			batches []int
+		}{
+			{
+				name:    "by_item_count",
+				req:     &pb.SearchLaptopBatchRequest{Filter: filter, MaxBatchSize: 10},
+				batches: []int{10, 10, 5},
+			},
+			{
+				name:    "by_bytes",
+				req:     &pb.SearchLaptopBatchRequest{Filter: filter, MaxBatchSize: 100, MaxBatchBytes: uint32(laptopSize)},
+				batches: singleLaptopBatches,
+			},
+			{
+				name:    "default",
+				req:     &pb.SearchLaptopBatchRequest{Filter: filter},
+				batches: []int{25},
+			},
+			{
+				name:    "clamped",
+				req:     &pb.SearchLaptopBatchRequest{Filter: filter, MaxBatchSize: math.MaxUint32},
+				batches: []int{25},
+			},
+			{
+				name:    "clamped_bytes",
+				req:     &pb.SearchLaptopBatchRequest{Filter: filter, MaxBatchBytes: math.MaxUint32},
+				batches: []int{25},
+			},
+		}
+
+		for _, tc := range testCases {
+			tc := tc
+			t.Run(tc.name, func(t *testing.T) {
+				stream, err := client.SearchLaptopBatch(context.Background(), tc.req)
+				require.NoError(t, err)
+
+				batches := []int{}
+				found := make(map[string]bool)
+				for {
+					res, err := stream.Recv()
+					if err == io.EOF {
+						break
+					}
+					require.NoError(t, err)
+
+					batches = append(batches, len(res.GetLaptops()))
+					for _, laptop := range res.GetLaptops() {
+						found[laptop.GetId()] = true
+					}
+				}
+
+				require.Equal(t, tc.batches, batches)
+				require.Len(t, found, 25)
+			})
+		}
+	})
+}
+
+func TestClientSimilarLaptops(t *testing.T) {
+	t.Parallel()
+
+	runWithLaptopStores(t, func(t *testing.T, newStore func(t *testing.T) LaptopStore) {
+		store := newStore(t)
+
+		reference := genarator.NewLaptop()
+		reference.PriceUsd = 2000
+		require.NoError(t, store.Save(reference))
+
+		prices := []float64{2050, 1900, 3400, 1500, 2150}
+		ids := make([]string, len(prices))
+		for i, price := range prices {
+			laptop := proto.Clone(reference).(*pb.Laptop)
+			laptop.Id = genarator.NewLaptop().GetId()
+			laptop.PriceUsd = price
+			require.NoError(t, store.Save(laptop))
+			ids[i] = laptop.GetId()
+		}
+
+		serverAddress := startTestLaptopServer(t, store, nil, nil)
+		client := startTestLaptopClient(t, serverAddress)
+
+		res, err := client.SimilarLaptops(context.Background(), &pb.SimilarLaptopsRequest{
+			LaptopId: reference.GetId(),
+			K:        3,
+		})
+		require.NoError(t, err)
+		require.Equal(t, []string{ids[0], ids[1], ids[4]}, similarLaptopIDs(res))
+
+		res, err = client.SimilarLaptops(context.Background(), &pb.SimilarLaptopsRequest{
+			LaptopId: reference.GetId(),
+			K:        3,
+			Filter:   &pb.Filter{MaxPriceUsd: reference.GetPriceUsd()},
+		})
+		require.NoError(t, err)
+		require.Equal(t, []string{ids[1], ids[3]}, similarLaptopIDs(res))
+
+		_, err = client.SimilarLaptops(context.Background(), &pb.SimilarLaptopsRequest{LaptopId: "unknown"})
+		require.Equal(t, codes.NotFound, status.Code(err))
+	})
+}
+
+func similarLaptopIDs(res *pb.SimilarLaptopsResponse) []string {
+	ids := []string{}
+	for _, similar := range res.GetSimilarLaptops() {
+		ids = append(ids, similar.GetLaptop().GetId())
+	}
+
+	return ids
+}
+
+func TestClientUploadImage(t *testing.T) {
+	t.Parallel()
+
+	runWithLaptopStores(t, func(t *testing.T, newStore func(t *testing.T) LaptopStore) {
+		imageFolder := "../tmp"
+		imagePath := fmt.Sprintf("%s/laptop.jpg", imageFolder)
+		imageType := filepath.Ext(imagePath)
+
+		laptopStore := newStore(t)
+		imageStore := NewDiskImageStore(imageFolder)
+
+		laptop := genarator.NewLaptop()
+		laptopStore.Save(laptop)
+
+		serverAddress := startTestLaptopServer(t, laptopStore, imageStore, nil)
+		client := startTestLaptopClient(t, serverAddress)
+
+		image, err := os.Open(imagePath)
+		require.NoError(t, err)
+		defer image.Close()
+
+		stream, err := client.UploadImage(context.Background())
 		require.NoError(t, err)
 
 		req := &pb.UploadImageRequest{
-			Data: &pb.UploadImageRequest_ChunkData{
-				ChunkData: buffer[:n],
+			Data: &pb.UploadImageRequest_Info{
+				Info: &pb.ImageInfo{
+					LaptopId:  laptop.GetId(),
+					ImageType: imageType,
+				},
 			},
 		}
 
 		err = stream.Send(req)
 		require.NoError(t, err)
 
-		size += n
-	}
+		reader := bufio.NewReader(image)
+		buffer := make([]byte, 1024)
+		size := 0
 
-	res, err := stream.CloseAndRecv()
-	require.NoError(t, err)
-	require.NotZero(t, res.GetId())
-	require.EqualValues(t, res.GetSize(), size)
+		for {
+			n, err := reader.Read(buffer)
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err)
+
+			req := &pb.UploadImageRequest{
+				Data: &pb.UploadImageRequest_ChunkData{
+					ChunkData: buffer[:n],
+				},
+			}
+
+			err = stream.Send(req)
+			require.NoError(t, err)
 
-	savedImagePath := fmt.Sprintf("%s/%s%s", imageFolder, res.GetId(), imageType)
-	require.FileExists(t, savedImagePath)
-	require.NoError(t, os.Remove(savedImagePath))
+			size += n
+		}
+
+		res, err := stream.CloseAndRecv()
+		require.NoError(t, err)
+		require.NotZero(t, res.GetId())
+		require.EqualValues(t, res.GetSize(), size)
+
+		savedImagePath := fmt.Sprintf("%s/%s%s", imageFolder, res.GetId(), imageType)
+		require.FileExists(t, savedImagePath)
+		require.NoError(t, imageStore.Delete(res.GetId()))
+		require.NoFileExists(t, savedImagePath)
+	})
 }
 
-func TestClientRateLaptop(t *testing.T) {
+func TestClientDownloadImage(t *testing.T) {
 	t.Parallel()
 
 	laptopStore := NewInMemoryLaptopStore()
-	rateStore := NewInMemoryRatingStore()
+	imageStore := NewDiskImageStore(t.TempDir())
 
 	laptop := genarator.NewLaptop()
-	err := laptopStore.Save(laptop)
+	require.NoError(t, laptopStore.Save(laptop))
+
+	// large enough to take several chunks
+	imageData := bytes.Repeat([]byte("image"), imageChunkSize/2)
+	imageId, err := imageStore.Save(laptop.GetId(), ".png", *bytes.NewBuffer(imageData))
+	require.NoError(t, err)
+
+	// an image whose laptop is gone cannot be downloaded either
+	orphanId, err := imageStore.Save(genarator.NewLaptop().GetId(), ".png", *bytes.NewBufferString("image"))
 	require.NoError(t, err)
 
-	serverAddress := startTestLaptopServer(t, laptopStore, nil, rateStore)
+	serverAddress := startTestLaptopServer(t, laptopStore, imageStore, nil)
 	client := startTestLaptopClient(t, serverAddress)
 
-	stream, err := client.RateLaptop(context.Background())
+	stream, err := client.DownloadImage(context.Background(), &pb.DownloadImageRequest{ImageId: imageId})
 	require.NoError(t, err)
 
-	scores := []float64{8, 7.5, 10}
-	averages := []float64{8, 7.75, 8.5}
+	res, err := stream.Recv()
+	require.NoError(t, err)
+	require.Equal(t, laptop.GetId(), res.GetMetadata().GetLaptopId())
+	require.Equal(t, "image/png", res.GetMetadata().GetImageType())
+	require.EqualValues(t, len(imageData), res.GetMetadata().GetSize())
 
-	n := len(scores)
-	for i := 0; i < n; i++ {
-		req := &pb.RateLaptopRequest{
-			LaptopId: laptop.GetId(),
-			Score:    scores[i],
+	downloaded := bytes.Buffer{}
+	chunks := 0
+	for {
+		res, err := stream.Recv()
+		if err == io.EOF {
+			break
 		}
+		require.NoError(t, err)
+		require.Nil(t, res.GetMetadata())
+
+		downloaded.Write(res.GetChunkData())
+		chunks++
+	}
+	require.Equal(t, imageData, downloaded.Bytes())
+	require.Greater(t, chunks, 1)
 
-		err := stream.Send(req)
+	for _, id := range []string{"unknown", orphanId} {
+		stream, err := client.DownloadImage(context.Background(), &pb.DownloadImageRequest{ImageId: id})
 		require.NoError(t, err)
+
+		_, err = stream.Recv()
+		require.Equal(t, codes.NotFound, status.Code(err))
 	}
+}
+
+func TestClientListDeleteImages(t *testing.T) {
+	t.Parallel()
+
+	laptopStore := NewInMemoryLaptopStore()
+	imageStore := NewDiskImageStore(t.TempDir())
+
+	laptop := genarator.NewLaptop()
+	require.NoError(t, laptopStore.Save(laptop))
+	other := genarator.NewLaptop()
+	require.NoError(t, laptopStore.Save(other))
 
-	err = stream.CloseSend()
+	imageIds := []string{}
+	for _, imageType := range []string{".jpg", ".png"} {
+		imageId, err := imageStore.Save(laptop.GetId(), imageType, *bytes.NewBufferString("image" + imageType))
+		require.NoError(t, err)
+		imageIds = append(imageIds, imageId)
+	}
+	_, err := imageStore.Save(other.GetId(), ".jpg", *bytes.NewBufferString("image"))
 	require.NoError(t, err)
 
-	for idx := 0; ; idx++ {
-		res, err := stream.Recv()
-		if err == io.EOF {
-			require.Equal(t, n, idx)
-			return
+	serverAddress := startTestLaptopServer(t, laptopStore, imageStore, nil)
+	client := startTestLaptopClient(t, serverAddress)
+
+	res, err := client.ListImages(context.Background(), &pb.ListImagesRequest{LaptopId: laptop.GetId()})
+	require.NoError(t, err)
+	require.Len(t, res.GetImages(), 2)
+	for i, image := range res.GetImages() {
+		require.Equal(t, imageIds[i], image.GetId())
+		require.EqualValues(t, len("image.jpg"), image.GetSize())
+		require.False(t, image.GetUploadedAt().AsTime().IsZero())
+	}
+	require.Equal(t, "image/jpeg", res.GetImages()[0].GetImageType())
+	require.Equal(t, "image/png", res.GetImages()[1].GetImageType())
+
+	deleted, err := imageStore.Find(imageIds[0])
+	require.NoError(t, err)
+	require.FileExists(t, deleted.Path)
+
+	_, err = client.DeleteImage(context.Background(), &pb.DeleteImageRequest{ImageId: imageIds[0]})
+	require.NoError(t, err)
+	require.NoFileExists(t, deleted.Path)
+
+	res, err = client.ListImages(context.Background(), &pb.ListImagesRequest{LaptopId: laptop.GetId()})
+	require.NoError(t, err)
+	require.Len(t, res.GetImages(), 1)
+	require.Equal(t, imageIds[1], res.GetImages()[0].GetId())
+
+	_, err = client.DeleteImage(context.Background(), &pb.DeleteImageRequest{ImageId: imageIds[0]})
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = client.ListImages(context.Background(), &pb.ListImagesRequest{LaptopId: genarator.NewLaptop().GetId()})
+	require.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestClientRateLaptop(t *testing.T) {
+	t.Parallel()
+
+	runWithLaptopStores(t, func(t *testing.T, newStore func(t *testing.T) LaptopStore) {
+		laptopStore := newStore(t)
+		rateStore := NewInMemoryRatingStore()
+
+		laptop := genarator.NewLaptop()
+		err := laptopStore.Save(laptop)
+		require.NoError(t, err)
+
+		serverAddress := startTestLaptopServer(t, laptopStore, nil, rateStore)
+		client := startTestLaptopClient(t, serverAddress)
+
+		stream, err := client.RateLaptop(context.Background())
+		require.NoError(t, err)
+
+		scores := []float64{8, 7.5, 10}
+		averages := []float64{8, 7.75, 8.5}
+
+		n := len(scores)
+		for i := 0; i < n; i++ {
+			req := &pb.RateLaptopRequest{
+				LaptopId: laptop.GetId(),
+				Score:    scores[i],
+			}
+
+			err := stream.Send(req)
+			require.NoError(t, err)
 		}
 
+		err = stream.CloseSend()
 		require.NoError(t, err)
-		require.Equal(t, laptop.GetId(), res.GetLaptopId())
-		require.Equal(t, uint32(idx+1), res.GetRatingCount())
-		require.Equal(t, averages[idx], res.GetAvarageScre())
-	}
+
+		for idx := 0; ; idx++ {
+			res, err := stream.Recv()
+			if err == io.EOF {
+				require.Equal(t, n, idx)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, laptop.GetId(), res.GetLaptopId())
+			require.Equal(t, uint32(idx+1), res.GetRatingCount())
+			require.Equal(t, averages[idx], res.GetAvarageScre())
+		}
+	})
 }
 
 // UTILITES
+type slowLaptopStore struct {
+	LaptopStore
+	delay time.Duration
+}
+
+func (store *slowLaptopStore) Search(
+	ctx context.Context,
+	filter *pb.Filter,
+	found func(laptop *pb.Laptop) error,
+) (SearchStats, error) {
+	return store.LaptopStore.Search(ctx, filter, func(laptop *pb.Laptop) error {
+		// the delay ends with the search, so that the server still answers
+		// before the caller's deadline
+		select {
+		case <-time.After(store.delay):
+		case <-ctx.Done():
+		}
+		return found(laptop)
+	})
+}
+
+// testLaptopStores lists every LaptopStore implementation the tests run against
+var testLaptopStores = []struct {
+	name     string
+	newStore func(t *testing.T) LaptopStore
+}{
+	{
+		name: "memory",
+		newStore: func(t *testing.T) LaptopStore {
+			return NewInMemoryLaptopStore()
+		},
+	},
+	{
+		name: "journal",
+		newStore: func(t *testing.T) LaptopStore {
+			store, err := NewJournaledLaptopStore(JournalOptions{Dir: t.TempDir(), Fsync: FsyncNever})
+			require.NoError(t, err)
+			t.Cleanup(func() { store.Close() })
+
+			return store
+		},
+	},
+	{
+		name: "sqlite",
+		newStore: func(t *testing.T) LaptopStore {
+			store, err := NewSQLiteLaptopStore(filepath.Join(t.TempDir(), "pcbook.db"))
+			require.NoError(t, err)
+			t.Cleanup(func() { store.Close() })
+
+			return store
+		},
+	},
+	{
+		name: "bbolt",
+		newStore: func(t *testing.T) LaptopStore {
+			db, err := OpenBoltDB(filepath.Join(t.TempDir(), "pcbook.bolt"))
+			require.NoError(t, err)
+			t.Cleanup(func() { db.Close() })
+
+			return NewBoltLaptopStore(db)
+		},
+	},
+}
+
+func runWithLaptopStores(t *testing.T, test func(t *testing.T, newStore func(t *testing.T) LaptopStore)) {
+	for _, tc := range testLaptopStores {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			test(t, tc.newStore)
+		})
+	}
+}
+
 func startTestLaptopServer(t *testing.T, store LaptopStore, imageStore ImageStore, ratingStore RatingStore) string {
 	laptopServer := NewLaptopServer(store, imageStore, ratingStore)
 
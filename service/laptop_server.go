@@ -4,24 +4,46 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"image"
 	"io"
 	"log"
+	"os"
+	"sort"
 
 	"github.com/google/uuid"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
 
 	pb "github.com/orkhanrustamli/pcbook/pcbook_proto/go"
 )
 
 const (
-	maxImageSize = 1 << 20
+	maxImageSize   = 1 << 20
+	imageChunkSize = 64 << 10
+
+	defaultBatchSize  = 100
+	maxBatchSize      = 1000
+	defaultBatchBytes = 1 << 20
+	maxBatchBytes     = 3 << 20
 )
 
 type LaptopServer struct {
 	Store LaptopStore
 	ImageStore
 	RatingStore
+	// Barrier, if set, is shared with a BackupServer so that backups and
+	// restores never see a write half done
+	Barrier *WriteBarrier
+	// ImageVariants are the renditions made of every JPEG or PNG image
+	// uploaded, none are made if it is empty
+	ImageVariants []ImageVariant
+	// Cluster, if set, forwards the requests about laptops owned by other
+	// nodes and spreads searches over all of them
+	Cluster *Cluster
+	// Uploads, if set, keeps the sessions of resumable uploads
+	Uploads *UploadSessionStore
 	pb.UnimplementedLaptopServiceServer
 }
 
@@ -33,6 +55,22 @@ func NewLaptopServer(laptopStore LaptopStore, imageStore ImageStore, ratingStore
 	}
 }
 
+func (server *LaptopServer) commit(uow *UnitOfWork) (*UnitOfWorkResult, error) {
+	var result *UnitOfWorkResult
+	err := server.Barrier.Write(func() error {
+		var err error
+		result, err = uow.Commit()
+		return err
+	})
+
+	return result, err
+}
+
+// store returns the laptop store as seen by the tenant of the request
+func (server *LaptopServer) store(ctx context.Context) LaptopStore {
+	return ScopeLaptopStore(ctx, server.Store)
+}
+
 func (server *LaptopServer) CreateLaptop(
 	ctx context.Context,
 	req *pb.CreateLaptopRequest,
@@ -53,6 +91,11 @@ func (server *LaptopServer) CreateLaptop(
 		laptop.Id = id.String()
 	}
 
+	if peer, ok := server.Cluster.forward(ctx, laptop.Id); ok {
+		log.Printf("Forwarding create-laptop request for laptop %s to node %s", laptop.Id, peer.id)
+		return peer.client.CreateLaptop(forwardContext(ctx), req)
+	}
+
 	// Emulate the context timeout and cancel
 	if ctx.Err() == context.Canceled {
 		return nil, logAndReturnError(status.Errorf(codes.Canceled, "Request is cancelled"))
@@ -62,7 +105,12 @@ func (server *LaptopServer) CreateLaptop(
 		return nil, logAndReturnError(status.Errorf(codes.DeadlineExceeded, "Deadline exceeded!"))
 	}
 
-	if err := server.Store.Save(laptop); err != nil {
+	laptop.CreatedAt = timestamppb.Now()
+
+	err := server.Barrier.Write(func() error {
+		return server.store(ctx).Save(laptop)
+	})
+	if err != nil {
 		code := codes.Internal
 		if errors.Is(err, ErrAlreadyExists) {
 			code = codes.AlreadyExists
@@ -79,6 +127,27 @@ func (server *LaptopServer) CreateLaptop(
 	return res, nil
 }
 
+func (server *LaptopServer) GetLaptop(
+	ctx context.Context,
+	req *pb.GetLaptopRequest,
+) (*pb.GetLaptopResponse, error) {
+	log.Printf("Received a get-laptop request with id: %s", req.GetId())
+
+	if peer, ok := server.Cluster.forward(ctx, req.GetId()); ok {
+		return peer.client.GetLaptop(forwardContext(ctx), req)
+	}
+
+	laptop, err := server.store(ctx).Find(req.GetId())
+	if err != nil {
+		return nil, logAndReturnError(status.Errorf(codes.Internal, "Cannot find laptop: %v", err))
+	}
+	if laptop == nil {
+		return nil, logAndReturnError(status.Errorf(codes.NotFound, "Laptop %s does not exists", req.GetId()))
+	}
+
+	return &pb.GetLaptopResponse{Laptop: laptop}, nil
+}
+
 func (server *LaptopServer) SearchLaptop(
 	req *pb.SearchLaptopRequest,
 	stream pb.LaptopService_SearchLaptopServer,
@@ -86,27 +155,281 @@ func (server *LaptopServer) SearchLaptop(
 	filter := req.GetFilter()
 	log.Printf("Received a search-laptop request with filter: %v", filter)
 
-	err := server.Store.Search(
-		stream.Context(),
+	if err := validateFilter(filter); err != nil {
+		return logAndReturnError(status.Errorf(codes.InvalidArgument, "Invalid filter: %v", err))
+	}
+
+	if err := validateRankingOptions(req.GetRanking()); err != nil {
+		return logAndReturnError(status.Errorf(codes.InvalidArgument, "Invalid ranking: %v", err))
+	}
+
+	if server.Cluster.scatter(stream.Context()) {
+		return server.Cluster.search(req, stream)
+	}
+
+	ctx, cancel := searchContext(stream.Context())
+	defer cancel()
+
+	var stats SearchStats
+	var err error
+	if req.GetRanking() != nil {
+		stats, err = server.searchRankedLaptop(ctx, filter, req.GetRanking(), stream)
+	} else {
+		stats, err = server.store(ctx).Search(
+			ctx,
+			filter,
+			func(laptop *pb.Laptop) error {
+				res := &pb.SearchLaptopResponse{Laptop: laptop}
+
+				if err := stream.Send(res); err != nil {
+					return err
+				}
+
+				log.Printf("Sent laptop with id: %s", laptop.GetId())
+				return nil
+			},
+		)
+	}
+
+	if streamErr := stream.Context().Err(); streamErr != nil {
+		return logAndReturnError(status.FromContextError(streamErr).Err())
+	}
+	if err != nil && !isContextError(err) {
+		return status.Errorf(codes.Internal, "unexpected error: %v", err)
+	}
+
+	stream.SetTrailer(searchTrailer(stats, err))
+
+	if err != nil {
+		log.Printf("Search stopped after scanning %d laptops: %s", stats.Scanned, searchStopReason(err))
+
+		if req.GetFailOnDeadline() {
+			return logAndReturnError(status.FromContextError(err).Err())
+		}
+		return nil
+	}
+
+	if stats.Matched > 0 {
+		return nil
+	}
+
+	suggestion, err := SuggestSearch(stream.Context(), server.store(stream.Context()), filter)
+	if err != nil {
+		return status.Errorf(codes.Internal, "cannot build search suggestion: %v", err)
+	}
+	if suggestion == nil {
+		return nil
+	}
+
+	log.Printf("Sent search suggestion: %v", suggestion)
+	return stream.Send(&pb.SearchLaptopResponse{DidYouMean: suggestion})
+}
+
+// searchRankedLaptop ranks whatever was found before ctx expired, so a
+// truncated search still sends its best matches
+func (server *LaptopServer) searchRankedLaptop(
+	ctx context.Context,
+	filter *pb.Filter,
+	ranking *pb.RankingOptions,
+	stream pb.LaptopService_SearchLaptopServer,
+) (SearchStats, error) {
+	laptops := []*pb.Laptop{}
+	stats, searchErr := server.store(ctx).Search(
+		ctx,
 		filter,
 		func(laptop *pb.Laptop) error {
-			res := &pb.SearchLaptopResponse{Laptop: laptop}
+			laptops = append(laptops, laptop)
+			return nil
+		},
+	)
+	if searchErr != nil && !isContextError(searchErr) {
+		return stats, searchErr
+	}
 
-			if err := stream.Send(res); err != nil {
-				return err
+	if server.Cluster.coordinated(ctx) {
+		return stats, server.sendRankingCandidates(laptops, stream, searchErr)
+	}
+
+	for _, ranked := range RankLaptops(laptops, filter, server.RatingStore, ranking) {
+		res := &pb.SearchLaptopResponse{
+			Laptop: ranked.Laptop,
+			Score:  ranked.Score,
+		}
+		if err := stream.Send(res); err != nil {
+			return stats, err
+		}
+
+		log.Printf("Sent laptop with id: %s, score: %v", ranked.Laptop.GetId(), ranked.Score)
+	}
+
+	return stats, searchErr
+}
+
+// sendRankingCandidates sends the laptops unranked along with their ratings,
+// for the node that scattered the search to rank them with the candidates
+// of every other node
+func (server *LaptopServer) sendRankingCandidates(
+	laptops []*pb.Laptop,
+	stream pb.LaptopService_SearchLaptopServer,
+	searchErr error,
+) error {
+	for _, laptop := range laptops {
+		res := &pb.SearchLaptopResponse{Laptop: laptop}
+		if server.RatingStore != nil {
+			if rating := server.RatingStore.Find(laptop.GetId()); rating != nil {
+				res.Rating = &pb.RatingRecord{Count: uint32(rating.count), Sum: rating.sum}
+			}
+		}
+
+		if err := stream.Send(res); err != nil {
+			return err
+		}
+	}
+
+	return searchErr
+}
+
+// SearchLaptopBatch sends each batch as soon as it is full, so that only
+// one batch of matches is held in memory at a time
+func (server *LaptopServer) SearchLaptopBatch(
+	req *pb.SearchLaptopBatchRequest,
+	stream pb.LaptopService_SearchLaptopBatchServer,
+) error {
+	filter := req.GetFilter()
+	log.Printf("Received a search-laptop-batch request with filter: %v", filter)
+
+	if err := validateFilter(filter); err != nil {
+		return logAndReturnError(status.Errorf(codes.InvalidArgument, "Invalid filter: %v", err))
+	}
+
+	batchSize, batchBytes := batchLimits(req)
+
+	if server.Cluster.scatter(stream.Context()) {
+		return server.Cluster.searchBatch(req, stream)
+	}
+
+	batch := &pb.SearchLaptopBatchResponse{}
+	size := 0
+	sent := 0
+	var sendErr error
+	send := func() error {
+		if err := stream.Send(batch); err != nil {
+			sendErr = status.Errorf(codes.Unknown, "cannot send batch: %v", err)
+			return sendErr
+		}
+
+		sent += len(batch.Laptops)
+		log.Printf("Sent batch of %d laptops (%d so far)", len(batch.Laptops), sent)
+
+		batch = &pb.SearchLaptopBatchResponse{}
+		size = 0
+		return nil
+	}
+
+	_, err := server.store(stream.Context()).Search(
+		stream.Context(),
+		filter,
+		func(laptop *pb.Laptop) error {
+			laptopSize := proto.Size(laptop)
+			if len(batch.Laptops) > 0 && (len(batch.Laptops) >= batchSize || size+laptopSize > batchBytes) {
+				if err := send(); err != nil {
+					return err
+				}
 			}
 
-			log.Printf("Sent laptop with id: %s", laptop.GetId())
+			batch.Laptops = append(batch.Laptops, laptop)
+			size += laptopSize
 			return nil
 		},
 	)
 	if err != nil {
-		return status.Errorf(codes.Internal, "unexpected error: %v", err)
+		if sendErr != nil && stream.Context().Err() == nil {
+			return logAndReturnError(sendErr)
+		}
+		return searchError(stream.Context(), err)
+	}
+
+	if len(batch.Laptops) > 0 {
+		if err := send(); err != nil {
+			return logAndReturnError(err)
+		}
 	}
 
 	return nil
 }
 
+// batchLimits returns the batch size and bytes of the request, with unset
+// limits replaced by their default and limits above the maximum clamped to it
+func batchLimits(req *pb.SearchLaptopBatchRequest) (int, int) {
+	batchSize := int(req.GetMaxBatchSize())
+	if batchSize == 0 {
+		batchSize = defaultBatchSize
+	}
+	if batchSize > maxBatchSize {
+		batchSize = maxBatchSize
+	}
+
+	batchBytes := int(req.GetMaxBatchBytes())
+	if batchBytes == 0 {
+		batchBytes = defaultBatchBytes
+	}
+	if batchBytes > maxBatchBytes {
+		batchBytes = maxBatchBytes
+	}
+
+	return batchSize, batchBytes
+}
+
+func (server *LaptopServer) SimilarLaptops(
+	ctx context.Context,
+	req *pb.SimilarLaptopsRequest,
+) (*pb.SimilarLaptopsResponse, error) {
+	laptopId := req.GetLaptopId()
+	log.Printf("Received a similar-laptops request for laptop %s with filter: %v", laptopId, req.GetFilter())
+
+	if err := validateFilter(req.GetFilter()); err != nil {
+		return nil, logAndReturnError(status.Errorf(codes.InvalidArgument, "Invalid filter: %v", err))
+	}
+
+	if peer, ok := server.Cluster.forward(ctx, laptopId); ok {
+		return peer.client.SimilarLaptops(forwardContext(ctx), req)
+	}
+
+	store := server.store(ctx)
+	reference, err := store.Find(laptopId)
+	if err != nil {
+		return nil, logAndReturnError(status.Errorf(codes.Internal, "Cannot find laptop: %v", err))
+	}
+	if reference == nil {
+		return nil, logAndReturnError(status.Errorf(codes.NotFound, "Laptop %s does not exists", laptopId))
+	}
+
+	// the owner of the reference compares it with the catalog of every node
+	catalog := []*pb.Laptop{}
+	collect := func(laptop *pb.Laptop) error {
+		catalog = append(catalog, laptop)
+		return nil
+	}
+	if server.Cluster != nil {
+		err = server.Cluster.collect(ctx, nil, collect)
+	} else {
+		_, err = store.Search(ctx, nil, collect)
+	}
+	if err != nil {
+		return nil, searchError(ctx, err)
+	}
+
+	res := &pb.SimilarLaptopsResponse{}
+	for _, similar := range FindSimilarLaptops(reference, catalog, req.GetFilter(), int(req.GetK())) {
+		res.SimilarLaptops = append(res.SimilarLaptops, &pb.SimilarLaptop{
+			Laptop:   similar.Laptop,
+			Distance: similar.Distance,
+		})
+	}
+
+	return res, nil
+}
+
 func (server *LaptopServer) UploadImage(stream pb.LaptopService_UploadImageServer) error {
 	req, err := stream.Recv()
 	if err != nil {
@@ -117,7 +440,18 @@ func (server *LaptopServer) UploadImage(stream pb.LaptopService_UploadImageServe
 	imageType := req.GetInfo().GetImageType()
 	log.Printf("Received an upload-image request for laptop %s with image type %s", laptopId, imageType)
 
-	laptop, err := server.Store.Find(laptopId)
+	if peer, ok := server.Cluster.forward(stream.Context(), laptopId); ok {
+		return server.Cluster.forwardUploadImage(peer, req, stream)
+	}
+
+	if imageType != "" {
+		if _, err := CanonicalImageType(imageType); err != nil {
+			return logAndReturnError(status.Errorf(codes.InvalidArgument, "Invalid image type: %v", err))
+		}
+	}
+
+	store := server.store(stream.Context())
+	laptop, err := store.Find(laptopId)
 	if err != nil {
 		return logAndReturnError(status.Errorf(codes.Internal, "Cannot find laptop: %v", err))
 	}
@@ -151,25 +485,538 @@ func (server *LaptopServer) UploadImage(stream pb.LaptopService_UploadImageServe
 		}
 	}
 
-	imageId, err := server.ImageStore.Save(laptopId, imageType, imageData)
+	res, err := server.saveImage(store, laptopId, imageType, imageData.Bytes())
+	if err != nil {
+		return err
+	}
+
+	if err := stream.SendAndClose(res); err != nil {
+		return status.Errorf(codes.Unknown, "Cannot send response: %v", err)
+	}
+
+	log.Printf("Saved image with id: %s, id: %d", res.GetId(), imageSize)
+	return nil
+}
+
+// saveImage stores an image received in full, along with its variants
+func (server *LaptopServer) saveImage(
+	store LaptopStore,
+	laptopId string,
+	imageType string,
+	data []byte,
+) (*pb.UploadImageResponse, error) {
+	// the content decides the type, whatever the client claimed
+	imageType, err := DetectImageType(imageType, data)
+	if err != nil {
+		return nil, logAndReturnError(status.Errorf(codes.InvalidArgument, "Invalid image: %v", err))
+	}
+
+	uow := NewUnitOfWork(store, server.ImageStore, server.RatingStore)
+	uow.SaveImage(laptopId, imageType, *bytes.NewBuffer(data))
+	result, err := server.commit(uow)
+	if errors.Is(err, ErrNotFound) {
+		return nil, logAndReturnError(status.Errorf(codes.NotFound, "Laptop %s does not exists", laptopId))
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Cannot save image to the store: %v", err)
+	}
+	imageId := result.ImageIds[0]
+
+	server.saveImageVariants(imageId, imageType, data)
+
+	return &pb.UploadImageResponse{
+		Id:        imageId,
+		Size:      uint32(len(data)),
+		ImageType: imageType,
+	}, nil
+}
+
+func (server *LaptopServer) StartUpload(
+	ctx context.Context,
+	req *pb.StartUploadRequest,
+) (*pb.StartUploadResponse, error) {
+	laptopId := req.GetInfo().GetLaptopId()
+	imageType := req.GetInfo().GetImageType()
+	log.Printf("Received a start-upload request for laptop %s with image type %s and size %d", laptopId, imageType, req.GetSize())
+
+	if peer, ok := server.Cluster.forward(ctx, laptopId); ok {
+		return peer.client.StartUpload(forwardContext(ctx), req)
+	}
+
+	if server.Uploads == nil {
+		return nil, logAndReturnError(status.Errorf(codes.Unimplemented, "Resumable uploads are not enabled"))
+	}
+	if imageType != "" {
+		if _, err := CanonicalImageType(imageType); err != nil {
+			return nil, logAndReturnError(status.Errorf(codes.InvalidArgument, "Invalid image type: %v", err))
+		}
+	}
+	if req.GetSize() == 0 || req.GetSize() > maxImageSize {
+		return nil, logAndReturnError(status.Errorf(codes.InvalidArgument, "Image size must be between 1 byte and 1MB"))
+	}
+
+	laptop, err := server.store(ctx).Find(laptopId)
+	if err != nil {
+		return nil, logAndReturnError(status.Errorf(codes.Internal, "Cannot find laptop: %v", err))
+	}
+	if laptop == nil {
+		return nil, logAndReturnError(status.Errorf(codes.NotFound, "Laptop %s does not exists", laptopId))
+	}
+
+	session, err := server.Uploads.Start(laptopId, imageType, int64(req.GetSize()))
 	if err != nil {
-		return status.Errorf(codes.Internal, "Cannot save image to the store: %v", err)
+		return nil, logAndReturnError(status.Errorf(codes.Internal, "Cannot start upload: %v", err))
+	}
+
+	log.Printf("Started upload session %s for laptop %s", session.Id, laptopId)
+	return &pb.StartUploadResponse{
+		SessionId: session.Id,
+		ExpiresAt: timestamppb.New(session.ExpiresAt),
+	}, nil
+}
+
+func (server *LaptopServer) UploadChunk(stream pb.LaptopService_UploadChunkServer) error {
+	req, err := stream.Recv()
+	if err != nil {
+		return logAndReturnError(status.Errorf(codes.Unknown, "Cannot receive chunk: %v", err))
+	}
+
+	sessionId := req.GetSessionId()
+	log.Printf("Received an upload-chunk request for session %s", sessionId)
+
+	session, err := server.findUpload(stream.Context(), sessionId)
+	if err != nil {
+		return err
+	}
+	if session == nil && server.Cluster.scatter(stream.Context()) {
+		peer, err := server.Cluster.findUpload(stream.Context(), sessionId)
+		if err != nil {
+			return logAndReturnError(err)
+		}
+		if peer != nil {
+			return server.Cluster.forwardUploadChunk(peer, req, stream)
+		}
+	}
+	if session == nil {
+		return logAndReturnError(status.Errorf(codes.NotFound, "Upload session %s does not exists", sessionId))
 	}
 
-	res := &pb.UploadImageResponse{
-		Id:   imageId,
-		Size: uint32(imageSize),
+	committed := session.Committed
+	for {
+		if req.GetSessionId() != sessionId {
+			return logAndReturnError(status.Errorf(codes.InvalidArgument, "All chunks must belong to session %s", sessionId))
+		}
+
+		committed, err = server.Uploads.Write(sessionId, int64(req.GetOffset()), req.GetChunkData())
+		if err != nil {
+			return logAndReturnError(uploadError(sessionId, err))
+		}
+
+		req, err = stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			// the chunks written so far stay committed, the client resumes
+			// from the offset QueryUpload reports
+			return logAndReturnError(status.Errorf(codes.Unknown, "Cannot receive chunk: %v", err))
+		}
 	}
 
+	res := &pb.UploadChunkResponse{CommittedOffset: uint64(committed)}
 	if err := stream.SendAndClose(res); err != nil {
 		return status.Errorf(codes.Unknown, "Cannot send response: %v", err)
 	}
 
-	log.Printf("Saved image with id: %s, id: %d", imageId, imageSize)
 	return nil
 }
 
+func (server *LaptopServer) QueryUpload(
+	ctx context.Context,
+	req *pb.QueryUploadRequest,
+) (*pb.QueryUploadResponse, error) {
+	sessionId := req.GetSessionId()
+	log.Printf("Received a query-upload request for session %s", sessionId)
+
+	session, err := server.findUpload(ctx, sessionId)
+	if err != nil {
+		return nil, err
+	}
+	if session == nil && server.Cluster.scatter(ctx) {
+		peer, err := server.Cluster.findUpload(ctx, sessionId)
+		if err != nil {
+			return nil, logAndReturnError(err)
+		}
+		if peer != nil {
+			return peer.client.QueryUpload(forwardContext(ctx), req)
+		}
+	}
+	if session == nil {
+		return nil, logAndReturnError(status.Errorf(codes.NotFound, "Upload session %s does not exists", sessionId))
+	}
+
+	return &pb.QueryUploadResponse{
+		CommittedOffset: uint64(session.Committed),
+		Size:            uint64(session.Size),
+		ExpiresAt:       timestamppb.New(session.ExpiresAt),
+	}, nil
+}
+
+func (server *LaptopServer) FinishUpload(
+	ctx context.Context,
+	req *pb.FinishUploadRequest,
+) (*pb.UploadImageResponse, error) {
+	sessionId := req.GetSessionId()
+	log.Printf("Received a finish-upload request for session %s", sessionId)
+
+	session, err := server.findUpload(ctx, sessionId)
+	if err != nil {
+		return nil, err
+	}
+	if session == nil && server.Cluster.scatter(ctx) {
+		peer, err := server.Cluster.findUpload(ctx, sessionId)
+		if err != nil {
+			return nil, logAndReturnError(err)
+		}
+		if peer != nil {
+			return peer.client.FinishUpload(forwardContext(ctx), req)
+		}
+	}
+	if session == nil {
+		return nil, logAndReturnError(status.Errorf(codes.NotFound, "Upload session %s does not exists", sessionId))
+	}
+
+	data, err := server.Uploads.Claim(sessionId)
+	if err != nil {
+		return nil, logAndReturnError(uploadError(sessionId, err))
+	}
+
+	res, err := server.saveImage(server.store(ctx), session.LaptopId, session.ImageType, data)
+	if err != nil {
+		server.Uploads.Release(sessionId)
+		return nil, err
+	}
+
+	if err := server.Uploads.Delete(sessionId); err != nil {
+		log.Printf("Cannot remove upload session %s: %v", sessionId, err)
+	}
+
+	log.Printf("Saved image with id: %s from upload session %s", res.GetId(), sessionId)
+	return res, nil
+}
+
+// findUpload returns the upload session, or nil if there is none or if the
+// tenant of the request cannot see its laptop
+func (server *LaptopServer) findUpload(ctx context.Context, sessionId string) (*UploadSession, error) {
+	if server.Uploads == nil {
+		return nil, logAndReturnError(status.Errorf(codes.Unimplemented, "Resumable uploads are not enabled"))
+	}
+
+	session, err := server.Uploads.Find(sessionId)
+	if err != nil {
+		return nil, logAndReturnError(status.Errorf(codes.Internal, "Cannot find upload session: %v", err))
+	}
+	if session == nil {
+		return nil, nil
+	}
+
+	laptop, err := server.store(ctx).Find(session.LaptopId)
+	if err != nil {
+		return nil, logAndReturnError(status.Errorf(codes.Internal, "Cannot find laptop: %v", err))
+	}
+	if laptop == nil {
+		return nil, nil
+	}
+
+	return session, nil
+}
+
+// uploadError maps an error of the upload session store to a status
+func uploadError(sessionId string, err error) error {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return status.Errorf(codes.NotFound, "Upload session %s does not exists", sessionId)
+	case errors.Is(err, ErrUploadGap), errors.Is(err, ErrUploadIncomplete):
+		return status.Errorf(codes.FailedPrecondition, "Upload session %s: %v", sessionId, err)
+	case errors.Is(err, ErrUploadFinishing):
+		return status.Errorf(codes.Aborted, "Upload session %s: %v", sessionId, err)
+	case errors.Is(err, ErrUploadOverflow):
+		return status.Errorf(codes.OutOfRange, "Upload session %s: %v", sessionId, err)
+	default:
+		return status.Errorf(codes.Internal, "Upload session %s: %v", sessionId, err)
+	}
+}
+
+func (server *LaptopServer) DownloadImage(
+	req *pb.DownloadImageRequest,
+	stream pb.LaptopService_DownloadImageServer,
+) error {
+	imageId := req.GetImageId()
+	log.Printf("Received a download-image request for image %s", imageId)
+
+	image, err := server.findImage(stream.Context(), imageId)
+	if err != nil {
+		return logAndReturnError(status.Errorf(codes.Internal, "Cannot find image: %v", err))
+	}
+	if image == nil && server.Cluster.scatter(stream.Context()) {
+		return server.Cluster.forwardDownloadImage(req, stream)
+	}
+	if image == nil {
+		return logAndReturnError(status.Errorf(codes.NotFound, "Image %s does not exists", imageId))
+	}
+
+	if req.GetVariant() != "" {
+		image, err = server.findImageVariant(image, req.GetVariant())
+		if err != nil {
+			return err
+		}
+	}
+
+	file, err := os.Open(image.Path)
+	if os.IsNotExist(err) {
+		return logAndReturnError(status.Errorf(codes.NotFound, "Image %s does not exists", imageId))
+	}
+	if err != nil {
+		return logAndReturnError(status.Errorf(codes.Internal, "Cannot open image: %v", err))
+	}
+	defer file.Close()
+
+	res := &pb.DownloadImageResponse{
+		Data: &pb.DownloadImageResponse_Metadata{
+			Metadata: &pb.ImageMetadata{
+				LaptopId:  image.LaptopId,
+				ImageType: image.Type,
+				Size:      uint64(image.Size),
+			},
+		},
+	}
+	if err := stream.Send(res); err != nil {
+		return logAndReturnError(status.Errorf(codes.Unknown, "Cannot send image metadata: %v", err))
+	}
+
+	buffer := make([]byte, imageChunkSize)
+	for {
+		n, err := file.Read(buffer)
+		if n > 0 {
+			res := &pb.DownloadImageResponse{
+				Data: &pb.DownloadImageResponse_ChunkData{
+					ChunkData: buffer[:n],
+				},
+			}
+			if err := stream.Send(res); err != nil {
+				return logAndReturnError(status.Errorf(codes.Unknown, "Cannot send chunk data: %v", err))
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return logAndReturnError(status.Errorf(codes.Internal, "Cannot read image: %v", err))
+		}
+	}
+
+	log.Printf("Sent image with id: %s, size: %d", imageId, image.Size)
+	return nil
+}
+
+func (server *LaptopServer) ListImages(
+	ctx context.Context,
+	req *pb.ListImagesRequest,
+) (*pb.ListImagesResponse, error) {
+	laptopId := req.GetLaptopId()
+	log.Printf("Received a list-images request for laptop %s", laptopId)
+
+	if peer, ok := server.Cluster.forward(ctx, laptopId); ok {
+		return peer.client.ListImages(forwardContext(ctx), req)
+	}
+
+	laptop, err := server.store(ctx).Find(laptopId)
+	if err != nil {
+		return nil, logAndReturnError(status.Errorf(codes.Internal, "Cannot find laptop: %v", err))
+	}
+	if laptop == nil {
+		return nil, logAndReturnError(status.Errorf(codes.NotFound, "Laptop %s does not exists", laptopId))
+	}
+
+	images := []*ImageInfo{}
+	err = server.ImageStore.List(func(image *ImageInfo) error {
+		if image.LaptopId == laptopId {
+			images = append(images, image)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, logAndReturnError(status.Errorf(codes.Internal, "Cannot list images: %v", err))
+	}
+
+	// the gallery reads in upload order
+	sort.SliceStable(images, func(i, j int) bool {
+		return images[i].UploadedAt.Before(images[j].UploadedAt)
+	})
+
+	res := &pb.ListImagesResponse{}
+	for _, image := range images {
+		res.Images = append(res.Images, &pb.StoredImage{
+			Id:         image.Id,
+			ImageType:  image.Type,
+			Size:       uint64(image.Size),
+			UploadedAt: timestamppb.New(image.UploadedAt),
+			Variants:   imageVariantNames(image),
+		})
+	}
+
+	return res, nil
+}
+
+func (server *LaptopServer) DeleteImage(
+	ctx context.Context,
+	req *pb.DeleteImageRequest,
+) (*pb.DeleteImageResponse, error) {
+	imageId := req.GetImageId()
+	log.Printf("Received a delete-image request for image %s", imageId)
+
+	image, err := server.findImage(ctx, imageId)
+	if err != nil {
+		return nil, logAndReturnError(status.Errorf(codes.Internal, "Cannot find image: %v", err))
+	}
+	if image == nil && server.Cluster.scatter(ctx) {
+		return server.Cluster.forwardDeleteImage(ctx, req)
+	}
+	if image == nil {
+		return nil, logAndReturnError(status.Errorf(codes.NotFound, "Image %s does not exists", imageId))
+	}
+
+	err = server.Barrier.Write(func() error {
+		return server.ImageStore.Delete(imageId)
+	})
+	if errors.Is(err, ErrNotFound) {
+		return nil, logAndReturnError(status.Errorf(codes.NotFound, "Image %s does not exists", imageId))
+	}
+	if err != nil {
+		return nil, logAndReturnError(status.Errorf(codes.Internal, "Cannot delete image: %v", err))
+	}
+
+	log.Printf("Deleted image with id: %s", imageId)
+	return &pb.DeleteImageResponse{}, nil
+}
+
+// saveImageVariants stores every variant of a new image. The upload stands
+// even if this fails, a missing variant is made when it is first downloaded.
+func (server *LaptopServer) saveImageVariants(imageId string, imageType string, imageData []byte) {
+	if len(server.ImageVariants) == 0 {
+		return
+	}
+
+	img, err := DecodeImage(imageType, bytes.NewReader(imageData))
+	if errors.Is(err, ErrUnsupportedImageType) {
+		return
+	}
+	if errors.Is(err, ErrImageTooLarge) {
+		log.Printf("Image %s has no variants: %v", imageId, err)
+		return
+	}
+	if err != nil {
+		log.Printf("Cannot decode image %s: %v", imageId, err)
+		return
+	}
+
+	for _, variant := range server.ImageVariants {
+		if err := server.saveImageVariant(imageId, imageType, img, variant); err != nil {
+			log.Printf("Cannot save %s variant of image %s: %v", variant.Name, imageId, err)
+		}
+	}
+}
+
+func (server *LaptopServer) saveImageVariant(imageId string, imageType string, img image.Image, variant ImageVariant) error {
+	data, err := EncodeImage(imageType, ResizeImage(img, variant))
+	if err != nil {
+		return err
+	}
+
+	return server.ImageStore.SaveVariant(imageId, variant.Name, bytes.NewReader(data))
+}
+
+// findImageVariant returns a rendition of the image, and makes it first if
+// the image came without it, from a backup or a leader
+func (server *LaptopServer) findImageVariant(image *ImageInfo, name string) (*ImageInfo, error) {
+	found, err := server.ImageStore.FindVariant(image.Id, name)
+	if err != nil {
+		return nil, logAndReturnError(status.Errorf(codes.Internal, "Cannot find image variant: %v", err))
+	}
+	if found != nil {
+		return found, nil
+	}
+
+	var variant *ImageVariant
+	for i := range server.ImageVariants {
+		if server.ImageVariants[i].Name == name {
+			variant = &server.ImageVariants[i]
+			break
+		}
+	}
+	if variant == nil {
+		return nil, logAndReturnError(status.Errorf(codes.InvalidArgument, "Unknown image variant %s", name))
+	}
+
+	file, err := os.Open(image.Path)
+	if err != nil {
+		return nil, logAndReturnError(status.Errorf(codes.Internal, "Cannot open image: %v", err))
+	}
+	defer file.Close()
+
+	img, err := DecodeImage(image.Type, file)
+	if errors.Is(err, ErrUnsupportedImageType) || errors.Is(err, ErrImageTooLarge) {
+		return nil, logAndReturnError(status.Errorf(codes.NotFound, "Image %s has no %s variant", image.Id, name))
+	}
+	if err != nil {
+		return nil, logAndReturnError(status.Errorf(codes.Internal, "Cannot decode image: %v", err))
+	}
+
+	if err := server.saveImageVariant(image.Id, image.Type, img, *variant); err != nil {
+		return nil, logAndReturnError(status.Errorf(codes.Internal, "Cannot save image variant: %v", err))
+	}
+
+	found, err = server.ImageStore.FindVariant(image.Id, name)
+	if err != nil {
+		return nil, logAndReturnError(status.Errorf(codes.Internal, "Cannot find image variant: %v", err))
+	}
+	if found == nil {
+		return nil, logAndReturnError(status.Errorf(codes.NotFound, "Image %s does not exists", image.Id))
+	}
+
+	return found, nil
+}
+
+func imageVariantNames(image *ImageInfo) []string {
+	names := make([]string, 0, len(image.Variants))
+	for name := range image.Variants {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// findImage returns the image, or nil if there is none or if the tenant of
+// the request cannot see its laptop
+func (server *LaptopServer) findImage(ctx context.Context, imageId string) (*ImageInfo, error) {
+	image, err := server.ImageStore.Find(imageId)
+	if err != nil || image == nil {
+		return nil, err
+	}
+
+	laptop, err := server.store(ctx).Find(image.LaptopId)
+	if err != nil || laptop == nil {
+		return nil, err
+	}
+
+	return image, nil
+}
+
 func (server *LaptopServer) RateLaptop(stream pb.LaptopService_RateLaptopServer) error {
+	forwarder := newRatingForwarder(stream.Context())
+	defer forwarder.close()
+
 	for {
 		req, err := stream.Recv()
 		if err == io.EOF {
@@ -185,15 +1032,28 @@ func (server *LaptopServer) RateLaptop(stream pb.LaptopService_RateLaptopServer)
 
 		log.Printf("Received rate-laptop request with id: %s, score: %v", laptopId, score)
 
-		laptop, err := server.Store.Find(req.LaptopId)
-		if err != nil {
-			return logAndReturnError(status.Errorf(codes.Internal, "there is no laptop with id:%s in the store %v", laptopId, err))
+		if peer, ok := server.Cluster.forward(stream.Context(), laptopId); ok {
+			res, err := forwarder.rate(peer, req)
+			if err != nil {
+				return logAndReturnError(err)
+			}
+			if err := stream.Send(res); err != nil {
+				return logAndReturnError(status.Errorf(codes.Unknown, "cannot send response: %v", err))
+			}
+			continue
 		}
-		if laptop == nil {
+
+		uow := NewUnitOfWork(server.store(stream.Context()), server.ImageStore, server.RatingStore)
+		uow.RateLaptop(laptopId, score)
+		result, err := server.commit(uow)
+		if errors.Is(err, ErrNotFound) {
 			return logAndReturnError(status.Errorf(codes.NotFound, "there is no laptop with id:%s in the store", laptopId))
 		}
+		if err != nil {
+			return logAndReturnError(status.Errorf(codes.Internal, "cannot rate laptop %s: %v", laptopId, err))
+		}
+		rating := result.Ratings[0]
 
-		rating := server.RatingStore.Rate(laptopId, score)
 		res := &pb.RateLaptopResponse{
 			LaptopId:    laptopId,
 			RatingCount: uint32(rating.count),
@@ -6,6 +6,7 @@ import (
 
 	"github.com/orkhanrustamli/pcbook/genarator"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
@@ -15,70 +16,165 @@ import (
 func TestServerCreateLaptop(t *testing.T) {
 	t.Parallel()
 
-	laptopNoId := genarator.NewLaptop()
-	laptopNoId.Id = ""
+	runWithLaptopStores(t, func(t *testing.T, newStore func(t *testing.T) LaptopStore) {
+		laptopNoId := genarator.NewLaptop()
+		laptopNoId.Id = ""
 
-	laptopInvalidId := genarator.NewLaptop()
-	laptopInvalidId.Id = "invalid-uuid"
+		laptopInvalidId := genarator.NewLaptop()
+		laptopInvalidId.Id = "invalid-uuid"
 
-	laptopDuplicateId := genarator.NewLaptop()
-	storeDuplicateId := NewInMemoryLaptopStore()
-	err := storeDuplicateId.Save(laptopDuplicateId)
-	require.Nil(t, err)
+		laptopDuplicateId := genarator.NewLaptop()
+		storeDuplicateId := newStore(t)
+		err := storeDuplicateId.Save(laptopDuplicateId)
+		require.Nil(t, err)
+
+		testCases := []struct {
+			name   string
+			laptop *pb.Laptop
+			store  LaptopStore
+			code   codes.Code
+		}{
+			{
+				name:   "success_with_id",
+				laptop: genarator.NewLaptop(),
+				store:  newStore(t),
+				code:   codes.OK,
+			},
+			{
+				name:   "success_no_id",
+				laptop: laptopNoId,
+				store:  newStore(t),
+				code:   codes.OK,
+			},
+			{
+				name:   "failure_invalid_id",
+				laptop: laptopInvalidId,
+				store:  newStore(t),
+				code:   codes.InvalidArgument,
+			},
+			{
+				name:   "failure_duplicate_id",
+				laptop: laptopDuplicateId,
+				store:  storeDuplicateId,
+				code:   codes.AlreadyExists,
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				t.Parallel()
+
+				req := &pb.CreateLaptopRequest{Laptop: tc.laptop}
+				server := NewLaptopServer(tc.store, nil, nil)
+				res, err := server.CreateLaptop(context.Background(), req)
+				if tc.code == codes.OK {
+					require.Nil(t, err)
+					require.NotNil(t, res)
+					require.NotEmpty(t, res.Id)
+					if len(tc.laptop.Id) > 0 {
+						require.Equal(t, res.Id, tc.laptop.Id)
+					}
+				} else {
+					require.Error(t, err)
+					require.Nil(t, res)
+					st, ok := status.FromError(err)
+					require.True(t, ok)
+					require.Equal(t, st.Code(), tc.code)
+				}
+			})
+		}
+	})
+}
+
+func TestServerSearchCanceled(t *testing.T) {
+	t.Parallel()
+
+	store := NewInMemoryLaptopStore()
+	laptop := genarator.NewLaptop()
+	require.NoError(t, store.Save(laptop))
+
+	ctx, cancel := context.WithCancel(ContextWithUserClaims(context.Background(), &UserClaims{Username: "alice", Role: "user"}))
+	cancel()
+
+	server := NewLaptopServer(store, nil, nil)
+	err := server.SearchLaptopBatch(&pb.SearchLaptopBatchRequest{}, &testSearchBatchStream{ctx: ctx})
+	require.Equal(t, codes.Canceled, status.Code(err))
+
+	_, err = server.SimilarLaptops(ctx, &pb.SimilarLaptopsRequest{LaptopId: laptop.GetId()})
+	require.Equal(t, codes.Canceled, status.Code(err))
+
+	savedSearchStore := NewInMemorySavedSearchStore()
+	savedSearchServer := NewSavedSearchServer(store, savedSearchStore)
+	res, err := savedSearchServer.SaveSearch(ctx, &pb.SaveSearchRequest{Name: "all", Filter: &pb.Filter{MaxPriceUsd: 10000}})
+	require.NoError(t, err)
+
+	err = savedSearchServer.RunSavedSearch(&pb.RunSavedSearchRequest{Id: res.GetSavedSearch().GetId()}, &testSearchStream{ctx: ctx})
+	require.Equal(t, codes.Canceled, status.Code(err))
+}
+
+func TestBatchLimits(t *testing.T) {
+	t.Parallel()
 
 	testCases := []struct {
-		name   string
-		laptop *pb.Laptop
-		store  LaptopStore
-		code   codes.Code
+		name       string
+		req        *pb.SearchLaptopBatchRequest
+		batchSize  int
+		batchBytes int
 	}{
 		{
-			name:   "success_with_id",
-			laptop: genarator.NewLaptop(),
-			store:  NewInMemoryLaptopStore(),
-			code:   codes.OK,
+			name:       "default",
+			req:        &pb.SearchLaptopBatchRequest{},
+			batchSize:  defaultBatchSize,
+			batchBytes: defaultBatchBytes,
 		},
 		{
-			name:   "success_no_id",
-			laptop: laptopNoId,
-			store:  NewInMemoryLaptopStore(),
-			code:   codes.OK,
+			name:       "within_maximum",
+			req:        &pb.SearchLaptopBatchRequest{MaxBatchSize: 10, MaxBatchBytes: 1024},
+			batchSize:  10,
+			batchBytes: 1024,
 		},
 		{
-			name:   "failure_invalid_id",
-			laptop: laptopInvalidId,
-			store:  NewInMemoryLaptopStore(),
-			code:   codes.InvalidArgument,
-		},
-		{
-			name:   "failure_duplicate_id",
-			laptop: laptopDuplicateId,
-			store:  storeDuplicateId,
-			code:   codes.AlreadyExists,
+			name:       "clamped",
+			req:        &pb.SearchLaptopBatchRequest{MaxBatchSize: maxBatchSize + 1, MaxBatchBytes: maxBatchBytes + 1},
+			batchSize:  maxBatchSize,
+			batchBytes: maxBatchBytes,
 		},
 	}
 
 	for _, tc := range testCases {
+		tc := tc
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
 
-			req := &pb.CreateLaptopRequest{Laptop: tc.laptop}
-			server := NewLaptopServer(tc.store, nil, nil)
-			res, err := server.CreateLaptop(context.Background(), req)
-			if tc.code == codes.OK {
-				require.Nil(t, err)
-				require.NotNil(t, res)
-				require.NotEmpty(t, res.Id)
-				if len(tc.laptop.Id) > 0 {
-					require.Equal(t, res.Id, tc.laptop.Id)
-				}
-			} else {
-				require.Error(t, err)
-				require.Nil(t, res)
-				st, ok := status.FromError(err)
-				require.True(t, ok)
-				require.Equal(t, st.Code(), tc.code)
-			}
+			batchSize, batchBytes := batchLimits(tc.req)
+			require.Equal(t, tc.batchSize, batchSize)
+			require.Equal(t, tc.batchBytes, batchBytes)
 		})
 	}
 }
+
+type testSearchBatchStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (stream *testSearchBatchStream) Context() context.Context {
+	return stream.ctx
+}
+
+func (stream *testSearchBatchStream) Send(res *pb.SearchLaptopBatchResponse) error {
+	return stream.ctx.Err()
+}
+
+type testSearchStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (stream *testSearchStream) Context() context.Context {
+	return stream.ctx
+}
+
+func (stream *testSearchStream) Send(res *pb.SearchLaptopResponse) error {
+	return stream.ctx.Err()
+}
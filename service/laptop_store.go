@@ -15,17 +15,34 @@ import (
 // Errors
 var (
 	ErrAlreadyExists = errors.New("Already Exists")
+	ErrNotFound      = errors.New("Not Found")
 )
 
 type LaptopStore interface {
 	Save(*pb.Laptop) error
 	Find(id string) (*pb.Laptop, error)
-	Search(ctx context.Context, filter *pb.Filter, found func(laptop *pb.Laptop) error) error
+	// Delete removes the laptop, it returns ErrNotFound if there is none
+	Delete(id string) error
+	Search(ctx context.Context, filter *pb.Filter, found func(laptop *pb.Laptop) error) (SearchStats, error)
 }
 
+// SearchStats counts the laptops a search looked at and the ones it passed
+// to found. Scanned only counts the laptops the store actually read, so a
+// store that narrows the candidates with an index scans fewer than it holds.
+type SearchStats struct {
+	Scanned int
+	Matched int
+}
+
+// InMemoryLaptopStore never modifies a laptop once it is stored and only
+// ever appends to laptops, Delete replaces laptops with a copy instead. A
+// search takes the current laptops as its snapshot and iterates it without
+// holding the lock, so a slow consumer of the results never blocks Save, and
+// the results are consistent as of the moment the search started.
 type InMemoryLaptopStore struct {
-	mutex sync.RWMutex
-	data  map[string]*pb.Laptop
+	mutex   sync.RWMutex
+	data    map[string]*pb.Laptop
+	laptops []*pb.Laptop
 }
 
 func NewInMemoryLaptopStore() *InMemoryLaptopStore {
@@ -35,8 +52,8 @@ func NewInMemoryLaptopStore() *InMemoryLaptopStore {
 }
 
 func (store *InMemoryLaptopStore) Save(laptop *pb.Laptop) error {
-	store.mutex.RLock()
-	defer store.mutex.RUnlock()
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
 
 	if store.data[laptop.Id] != nil {
 		return ErrAlreadyExists
@@ -48,12 +65,13 @@ func (store *InMemoryLaptopStore) Save(laptop *pb.Laptop) error {
 	}
 
 	store.data[tmp.Id] = tmp
+	store.laptops = append(store.laptops, tmp)
 	return nil
 }
 
 func (store *InMemoryLaptopStore) Find(id string) (*pb.Laptop, error) {
-	store.mutex.Lock()
-	defer store.mutex.Unlock()
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
 
 	laptop := store.data[id]
 	if laptop == nil {
@@ -61,78 +79,60 @@ func (store *InMemoryLaptopStore) Find(id string) (*pb.Laptop, error) {
 	}
 
 	return deepCopy(laptop)
+}
+
+func (store *InMemoryLaptopStore) Delete(id string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	laptop := store.data[id]
+	if laptop == nil {
+		return ErrNotFound
+	}
+	delete(store.data, id)
 
+	laptops := make([]*pb.Laptop, 0, len(store.laptops)-1)
+	for _, other := range store.laptops {
+		if other != laptop {
+			laptops = append(laptops, other)
+		}
+	}
+	store.laptops = laptops
+	return nil
 }
 
 func (store *InMemoryLaptopStore) Search(
 	ctx context.Context,
 	filter *pb.Filter,
 	found func(laptop *pb.Laptop,
-	) error) error {
+	) error) (SearchStats, error) {
 	store.mutex.RLock()
-	defer store.mutex.RUnlock()
+	snapshot := store.laptops
+	store.mutex.RUnlock()
 
-	for _, laptop := range store.data {
-		if ctx.Err() == context.Canceled || ctx.Err() == context.DeadlineExceeded {
+	stats := SearchStats{}
+	for _, laptop := range snapshot {
+		if err := ctx.Err(); err != nil {
 			log.Println("Context is cancelled or timed out!")
-			return nil
+			return stats, err
 		}
 
+		stats.Scanned++
 		if isQualified(filter, laptop) {
 			other, err := deepCopy(laptop)
 			if err != nil {
-				return err
+				return stats, err
 			}
 
+			stats.Matched++
 			err = found(other)
 			if err != nil {
-				return err
+				return stats, err
 			}
 		}
 	}
 
-	return nil
-}
-
-func isQualified(filter *pb.Filter, laptop *pb.Laptop) bool {
-	if laptop.PriceUsd > filter.MaxPriceUsd {
-		return false
-	}
-
-	if laptop.Cpu.NumberCores < filter.MinCpuCores {
-		return false
-	}
-
-	if laptop.Cpu.MinGhz < filter.MinCpuGhz {
-		return false
-	}
-
-	if toBit(laptop.Ram) < toBit(filter.MinRam) {
-		return false
-	}
-
-	return true
-}
-
-func toBit(memory *pb.Memory) uint64 {
-	value := memory.Value
-
-	switch memory.Unit {
-	case pb.Memory_BIT:
-		return value
-	case pb.Memory_BYTE:
-		return value << 3
-	case pb.Memory_KILOBYTE:
-		return value << 13
-	case pb.Memory_MEGABYTE:
-		return value << 23
-	case pb.Memory_GIGABYTE:
-		return value << 33
-	case pb.Memory_TERABYTE:
-		return value << 43
-	default:
-		return 0
-	}
+	return stats, nil
 }
 
 func deepCopy(laptop *pb.Laptop) (*pb.Laptop, error) {
@@ -0,0 +1,63 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/orkhanrustamli/pcbook/genarator"
+	pb "github.com/orkhanrustamli/pcbook/pcbook_proto/go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInMemoryLaptopStoreSearchSnapshot(t *testing.T) {
+	t.Parallel()
+
+	store := NewInMemoryLaptopStore()
+	for i := 0; i < 3; i++ {
+		require.NoError(t, store.Save(genarator.NewLaptop()))
+	}
+
+	blocked := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan SearchStats)
+	go func() {
+		calls := 0
+		stats, err := store.Search(context.Background(), nil, func(laptop *pb.Laptop) error {
+			calls++
+			if calls == 1 {
+				close(blocked)
+				<-release
+			}
+			return nil
+		})
+		if err != nil {
+			t.Errorf("cannot search laptops: %v", err)
+		}
+		done <- stats
+	}()
+	<-blocked
+
+	// the search is stuck in found, as it would be with a stalled client
+	saved := make(chan error)
+	laptop := genarator.NewLaptop()
+	go func() {
+		saved <- store.Save(laptop)
+	}()
+
+	select {
+	case err := <-saved:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("Save is blocked by a search")
+	}
+
+	other, err := store.Find(laptop.GetId())
+	require.NoError(t, err)
+	require.NotNil(t, other)
+
+	close(release)
+	stats := <-done
+	require.Equal(t, 3, stats.Scanned)
+	require.Equal(t, 3, stats.Matched)
+}
@@ -0,0 +1,395 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"math"
+	"os"
+	"sort"
+
+	pb "github.com/orkhanrustamli/pcbook/pcbook_proto/go"
+	"google.golang.org/protobuf/proto"
+)
+
+const migrationProgressInterval = 100
+
+var (
+	ErrDestinationNotEmpty = errors.New("destination is not empty")
+	ErrChecksumMismatch    = errors.New("checksum mismatch")
+)
+
+// Migration kinds, in the order they are copied
+const (
+	MigrationLaptops = "laptops"
+	MigrationRatings = "ratings"
+	MigrationUsers   = "users"
+	MigrationImages  = "images"
+)
+
+var migrationKinds = []string{MigrationLaptops, MigrationRatings, MigrationUsers, MigrationImages}
+
+type MigrationStats struct {
+	Copied  int
+	Skipped int
+}
+
+type MigrationChecksum struct {
+	Kind        string
+	Items       int
+	Missing     int
+	Mismatched  int
+	Source      string
+	Destination string
+}
+
+// Migration copies every laptop, rating, user and image record from one set
+// of stores to another. Records already in the destination are skipped, so
+// running it again with Resume picks up where an interrupted run stopped.
+type Migration struct {
+	Source      *Stores
+	Destination *Stores
+	// DryRun counts what would be copied without writing anything
+	DryRun bool
+	// Resume allows the destination to already hold records
+	Resume bool
+	// Progress, if set, is called every few records and once a kind is done
+	Progress func(kind string, stats MigrationStats)
+}
+
+func (migration *Migration) Run(ctx context.Context) (map[string]MigrationStats, error) {
+	if !migration.Resume && !migration.DryRun {
+		empty, err := isEmpty(ctx, migration.Destination)
+		if err != nil {
+			return nil, err
+		}
+		if !empty {
+			return nil, ErrDestinationNotEmpty
+		}
+	}
+
+	copiers := map[string]func(ctx context.Context, copied func(copied bool)) error{
+		MigrationLaptops: migration.copyLaptops,
+		MigrationRatings: migration.copyRatings,
+		MigrationUsers:   migration.copyUsers,
+		MigrationImages:  migration.copyImages,
+	}
+
+	results := make(map[string]MigrationStats)
+	for _, kind := range migrationKinds {
+		stats := MigrationStats{}
+		err := copiers[kind](ctx, func(copied bool) {
+			if copied {
+				stats.Copied++
+			} else {
+				stats.Skipped++
+			}
+
+			if (stats.Copied+stats.Skipped)%migrationProgressInterval == 0 {
+				migration.progress(kind, stats)
+			}
+		})
+		results[kind] = stats
+		if err != nil {
+			return results, fmt.Errorf("cannot migrate %s: %w", kind, err)
+		}
+
+		migration.progress(kind, stats)
+	}
+
+	return results, nil
+}
+
+// Verify compares a checksum of every record of the source with the record
+// of the same ID in the destination. Records that only exist in the
+// destination are ignored.
+func (migration *Migration) Verify(ctx context.Context) ([]*MigrationChecksum, error) {
+	checksums := []*MigrationChecksum{}
+	mismatch := false
+	for _, kind := range migrationKinds {
+		source, err := checksumStores(ctx, migration.Source, kind)
+		if err != nil {
+			return nil, fmt.Errorf("cannot checksum source %s: %w", kind, err)
+		}
+
+		destination, err := checksumStores(ctx, migration.Destination, kind)
+		if err != nil {
+			return nil, fmt.Errorf("cannot checksum destination %s: %w", kind, err)
+		}
+
+		checksum := compareChecksums(kind, source, destination)
+		if checksum.Missing > 0 || checksum.Mismatched > 0 {
+			mismatch = true
+		}
+		checksums = append(checksums, checksum)
+	}
+
+	if mismatch {
+		return checksums, ErrChecksumMismatch
+	}
+
+	return checksums, nil
+}
+
+func (migration *Migration) progress(kind string, stats MigrationStats) {
+	if migration.Progress != nil {
+		migration.Progress(kind, stats)
+	}
+}
+
+func (migration *Migration) copyLaptops(ctx context.Context, copied func(copied bool)) error {
+	_, err := migration.Source.Laptops.Search(ctx, nil, func(laptop *pb.Laptop) error {
+		existing, err := migration.Destination.Laptops.Find(laptop.GetId())
+		if err != nil {
+			return err
+		}
+		if existing != nil {
+			copied(false)
+			return nil
+		}
+
+		if !migration.DryRun {
+			if err := migration.Destination.Laptops.Save(laptop); err != nil {
+				return err
+			}
+		}
+		copied(true)
+		return nil
+	})
+
+	return err
+}
+
+func (migration *Migration) copyRatings(ctx context.Context, copied func(copied bool)) error {
+	return migration.Source.Ratings.List(func(laptopId string, rating *Rating) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		existing := migration.Destination.Ratings.Find(laptopId)
+		if existing != nil && existing.count == rating.count && existing.sum == rating.sum {
+			copied(false)
+			return nil
+		}
+
+		if !migration.DryRun {
+			if err := migration.Destination.Ratings.Set(laptopId, rating); err != nil {
+				return err
+			}
+		}
+		copied(true)
+		return nil
+	})
+}
+
+func (migration *Migration) copyUsers(ctx context.Context, copied func(copied bool)) error {
+	return migration.Source.Users.List(func(user *User) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if migration.Destination.Users.Find(user.Username) != nil {
+			copied(false)
+			return nil
+		}
+
+		if !migration.DryRun {
+			if err := migration.Destination.Users.Save(user); err != nil {
+				return err
+			}
+		}
+		copied(true)
+		return nil
+	})
+}
+
+func (migration *Migration) copyImages(ctx context.Context, copied func(copied bool)) error {
+	existing := make(map[string]bool)
+	err := migration.Destination.Images.List(func(image *ImageInfo) error {
+		existing[image.Id] = true
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return migration.Source.Images.List(func(image *ImageInfo) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if existing[image.Id] {
+			copied(false)
+			return nil
+		}
+
+		if !migration.DryRun {
+			if err := importImage(migration.Destination.Images, image); err != nil {
+				return err
+			}
+		}
+		copied(true)
+		return nil
+	})
+}
+
+func importImage(store ImageStore, image *ImageInfo) error {
+	file, err := os.Open(image.Path)
+	if err != nil {
+		return fmt.Errorf("cannot open image %s: %v", image.Id, err)
+	}
+	defer file.Close()
+
+	return store.Import(image, file)
+}
+
+func isEmpty(ctx context.Context, stores *Stores) (bool, error) {
+	errFound := errors.New("found a record")
+
+	_, err := stores.Laptops.Search(ctx, nil, func(laptop *pb.Laptop) error {
+		return errFound
+	})
+	if err == nil {
+		err = stores.Ratings.List(func(laptopId string, rating *Rating) error {
+			return errFound
+		})
+	}
+	if err == nil {
+		err = stores.Users.List(func(user *User) error {
+			return errFound
+		})
+	}
+	if err == nil {
+		err = stores.Images.List(func(image *ImageInfo) error {
+			return errFound
+		})
+	}
+
+	if errors.Is(err, errFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// checksumStores returns a SHA-256 of every record of the kind, by ID
+func checksumStores(ctx context.Context, stores *Stores, kind string) (map[string][]byte, error) {
+	checksums := make(map[string][]byte)
+
+	switch kind {
+	case MigrationLaptops:
+		marshal := proto.MarshalOptions{Deterministic: true}
+		_, err := stores.Laptops.Search(ctx, nil, func(laptop *pb.Laptop) error {
+			data, err := marshal.Marshal(laptop)
+			if err != nil {
+				return fmt.Errorf("cannot marshal laptop: %v", err)
+			}
+
+			sum := sha256.Sum256(data)
+			checksums[laptop.GetId()] = sum[:]
+			return nil
+		})
+		return checksums, err
+	case MigrationRatings:
+		err := stores.Ratings.List(func(laptopId string, rating *Rating) error {
+			hash := sha256.New()
+			writeChecksumInt(hash, int64(rating.count))
+			writeChecksumInt(hash, int64(math.Float64bits(rating.sum)))
+			checksums[laptopId] = hash.Sum(nil)
+			return nil
+		})
+		return checksums, err
+	case MigrationUsers:
+		err := stores.Users.List(func(user *User) error {
+			hash := sha256.New()
+			writeChecksumString(hash, user.Username)
+			writeChecksumString(hash, user.Password)
+			writeChecksumString(hash, user.Role)
+			writeChecksumString(hash, user.Tenant)
+			checksums[user.Username] = hash.Sum(nil)
+			return nil
+		})
+		return checksums, err
+	case MigrationImages:
+		err := stores.Images.List(func(image *ImageInfo) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			hash := sha256.New()
+			writeChecksumString(hash, image.LaptopId)
+			writeChecksumString(hash, image.Type)
+			writeChecksumInt(hash, image.Size)
+			writeChecksumInt(hash, image.UploadedAt.UnixNano())
+
+			file, err := os.Open(image.Path)
+			if err != nil {
+				return fmt.Errorf("cannot open image %s: %v", image.Id, err)
+			}
+			defer file.Close()
+
+			if _, err := io.Copy(hash, file); err != nil {
+				return fmt.Errorf("cannot read image %s: %v", image.Id, err)
+			}
+
+			checksums[image.Id] = hash.Sum(nil)
+			return nil
+		})
+		return checksums, err
+	default:
+		return nil, fmt.Errorf("unknown migration kind: %s", kind)
+	}
+}
+
+// compareChecksums digests the source checksums and the destination
+// checksums of the same IDs in ID order, so both digests are equal exactly
+// when every source record made it to the destination unchanged
+func compareChecksums(kind string, source, destination map[string][]byte) *MigrationChecksum {
+	ids := make([]string, 0, len(source))
+	for id := range source {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	checksum := &MigrationChecksum{
+		Kind:  kind,
+		Items: len(ids),
+	}
+
+	sourceHash := sha256.New()
+	destinationHash := sha256.New()
+	for _, id := range ids {
+		writeChecksumString(sourceHash, id)
+		sourceHash.Write(source[id])
+
+		other, ok := destination[id]
+		switch {
+		case !ok:
+			checksum.Missing++
+		case string(other) != string(source[id]):
+			checksum.Mismatched++
+		}
+
+		writeChecksumString(destinationHash, id)
+		destinationHash.Write(other)
+	}
+
+	checksum.Source = hex.EncodeToString(sourceHash.Sum(nil))
+	checksum.Destination = hex.EncodeToString(destinationHash.Sum(nil))
+	return checksum
+}
+
+func writeChecksumString(hash hash.Hash, value string) {
+	writeChecksumInt(hash, int64(len(value)))
+	hash.Write([]byte(value))
+}
+
+func writeChecksumInt(hash hash.Hash, value int64) {
+	var buffer [8]byte
+	binary.LittleEndian.PutUint64(buffer[:], uint64(value))
+	hash.Write(buffer[:])
+}
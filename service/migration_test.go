@@ -0,0 +1,93 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/orkhanrustamli/pcbook/genarator"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigration(t *testing.T) {
+	t.Parallel()
+
+	source := &Stores{
+		Laptops: NewInMemoryLaptopStore(),
+		Ratings: NewInMemoryRatingStore(),
+		Users:   NewInMemoryUserStore(),
+		Images:  NewDiskImageStore(t.TempDir()),
+	}
+
+	destination, err := OpenStores(StoreOptions{
+		Type:        "bbolt",
+		Path:        filepath.Join(t.TempDir(), "pcbook.bolt"),
+		ImageFolder: t.TempDir(),
+	})
+	require.NoError(t, err)
+	defer destination.Close()
+
+	laptopIds := []string{}
+	for i := 0; i < 3; i++ {
+		laptop := genarator.NewLaptop()
+		laptopIds = append(laptopIds, laptop.GetId())
+		require.NoError(t, source.Laptops.Save(laptop))
+		source.Ratings.Rate(laptop.GetId(), float64(i+5))
+
+		_, err := source.Images.Save(laptop.GetId(), ".jpg", *bytes.NewBufferString(laptop.GetName()))
+		require.NoError(t, err)
+	}
+
+	user, err := NewUser("admin1", "secret", "admin", "")
+	require.NoError(t, err)
+	require.NoError(t, source.Users.Save(user))
+
+	want := map[string]MigrationStats{
+		MigrationLaptops: {Copied: 3},
+		MigrationRatings: {Copied: 3},
+		MigrationUsers:   {Copied: 1},
+		MigrationImages:  {Copied: 3},
+	}
+
+	migration := &Migration{Source: source, Destination: destination, DryRun: true}
+	stats, err := migration.Run(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, want, stats)
+
+	_, err = migration.Verify(context.Background())
+	require.ErrorIs(t, err, ErrChecksumMismatch)
+
+	migration.DryRun = false
+	stats, err = migration.Run(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, want, stats)
+
+	checksums, err := migration.Verify(context.Background())
+	require.NoError(t, err)
+	for _, checksum := range checksums {
+		require.Equal(t, checksum.Source, checksum.Destination)
+	}
+
+	_, err = migration.Run(context.Background())
+	require.ErrorIs(t, err, ErrDestinationNotEmpty)
+
+	migration.Resume = true
+	stats, err = migration.Run(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, map[string]MigrationStats{
+		MigrationLaptops: {Skipped: 3},
+		MigrationRatings: {Skipped: 3},
+		MigrationUsers:   {Skipped: 1},
+		MigrationImages:  {Skipped: 3},
+	}, stats)
+
+	// a rating changed in the destination only is caught by verification
+	_, err = destination.Ratings.Rate(laptopIds[0], 1)
+	require.NoError(t, err)
+
+	checksums, err = migration.Verify(context.Background())
+	require.ErrorIs(t, err, ErrChecksumMismatch)
+	require.Equal(t, MigrationRatings, checksums[1].Kind)
+	require.Equal(t, 1, checksums[1].Mismatched)
+}
@@ -0,0 +1,190 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/orkhanrustamli/pcbook/memory"
+	pb "github.com/orkhanrustamli/pcbook/pcbook_proto/go"
+)
+
+const maxLaptopScore = 10
+
+var defaultRankingOptions = &pb.RankingOptions{
+	SpecMatchWeight:        1,
+	AverageRatingWeight:    1,
+	RatingCountWeight:      1,
+	PricePerformanceWeight: 1,
+	RecencyWeight:          1,
+}
+
+type RankedLaptop struct {
+	Laptop *pb.Laptop
+	Score  float64
+}
+
+// RankLaptops scores every laptop against the filter and the rating store and
+// returns them ordered from the most to the least relevant. Each component is
+// normalized to [0, 1] before being multiplied by its weight.
+func RankLaptops(
+	laptops []*pb.Laptop,
+	filter *pb.Filter,
+	ratingStore RatingStore,
+	options *pb.RankingOptions,
+) []*RankedLaptop {
+	ratings := make([]*Rating, len(laptops))
+	if ratingStore != nil {
+		for i, laptop := range laptops {
+			ratings[i] = ratingStore.Find(laptop.GetId())
+		}
+	}
+
+	return rankLaptops(laptops, ratings, filter, options)
+}
+
+// rankLaptops ranks laptops whose ratings were looked up already, ratings[i]
+// being the rating of laptops[i] or nil. The scores are only comparable
+// within one call, since each component is normalized against its laptops.
+func rankLaptops(
+	laptops []*pb.Laptop,
+	ratings []*Rating,
+	filter *pb.Filter,
+	options *pb.RankingOptions,
+) []*RankedLaptop {
+	if isZeroRankingOptions(options) {
+		options = defaultRankingOptions
+	}
+
+	maxRatingCount := 0
+	maxPricePerformance := 0.0
+	minYear, maxYear := uint32(math.MaxUint32), uint32(0)
+
+	for i, laptop := range laptops {
+		if ratings[i] != nil && ratings[i].count > maxRatingCount {
+			maxRatingCount = ratings[i].count
+		}
+
+		maxPricePerformance = math.Max(maxPricePerformance, pricePerformance(laptop))
+
+		if laptop.GetReleaseYear() < minYear {
+			minYear = laptop.GetReleaseYear()
+		}
+		if laptop.GetReleaseYear() > maxYear {
+			maxYear = laptop.GetReleaseYear()
+		}
+	}
+
+	ranked := make([]*RankedLaptop, len(laptops))
+	for i, laptop := range laptops {
+		averageRating, ratingCount := 0.0, 0.0
+		if rating := ratings[i]; rating != nil {
+			averageRating = rating.sum / float64(rating.count) / maxLaptopScore
+			ratingCount = float64(rating.count) / float64(maxRatingCount)
+		}
+
+		score := options.GetSpecMatchWeight()*specMatch(filter, laptop) +
+			options.GetAverageRatingWeight()*averageRating +
+			options.GetRatingCountWeight()*ratingCount +
+			options.GetPricePerformanceWeight()*ratio(pricePerformance(laptop), maxPricePerformance) +
+			options.GetRecencyWeight()*ratio(float64(laptop.GetReleaseYear()-minYear), float64(maxYear-minYear))
+
+		ranked[i] = &RankedLaptop{
+			Laptop: laptop,
+			Score:  score,
+		}
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].Score > ranked[j].Score
+	})
+
+	return ranked
+}
+
+// specMatch averages how far the laptop beats each threshold of the filter,
+// relative to the threshold itself
+func specMatch(filter *pb.Filter, laptop *pb.Laptop) float64 {
+	margins := []float64{
+		ratio(filter.GetMaxPriceUsd()-laptop.GetPriceUsd(), filter.GetMaxPriceUsd()),
+		ratio(float64(laptop.GetCpu().GetNumberCores())-float64(filter.GetMinCpuCores()), float64(filter.GetMinCpuCores())),
+		ratio(laptop.GetCpu().GetMinGhz()-filter.GetMinCpuGhz(), filter.GetMinCpuGhz()),
+		ramMatch(filter, laptop),
+	}
+
+	sum := 0.0
+	for _, margin := range margins {
+		sum += margin
+	}
+
+	return sum / float64(len(margins))
+}
+
+func pricePerformance(laptop *pb.Laptop) float64 {
+	if laptop.GetPriceUsd() <= 0 {
+		return 0
+	}
+
+	cpu := float64(laptop.GetCpu().GetNumberCores()) * laptop.GetCpu().GetMinGhz()
+	ramGB, err := memory.In(laptop.GetRam(), pb.Memory_GIGABYTE)
+	if err != nil {
+		ramGB = 0
+	}
+
+	return (cpu + ramGB) / laptop.GetPriceUsd()
+}
+
+func ramMatch(filter *pb.Filter, laptop *pb.Laptop) float64 {
+	ram, err := memory.In(laptop.GetRam(), pb.Memory_BIT)
+	if err != nil {
+		return 0
+	}
+
+	minRam, err := memory.In(filter.GetMinRam(), pb.Memory_BIT)
+	if err != nil {
+		return 0
+	}
+
+	return ratio(ram-minRam, minRam)
+}
+
+// ratio returns value/total clamped to [0, 1], or 0 when total is not positive
+func ratio(value, total float64) float64 {
+	if total <= 0 {
+		return 0
+	}
+
+	return math.Max(0, math.Min(1, value/total))
+}
+
+// validateRankingOptions rejects negative weights, which would push the most
+// relevant laptops to the bottom of the results, and weights that are not
+// finite, which turn scores into NaN and leave the order undefined
+func validateRankingOptions(options *pb.RankingOptions) error {
+	weights := []struct {
+		name  string
+		value float64
+	}{
+		{"spec match", options.GetSpecMatchWeight()},
+		{"average rating", options.GetAverageRatingWeight()},
+		{"rating count", options.GetRatingCountWeight()},
+		{"price performance", options.GetPricePerformanceWeight()},
+		{"recency", options.GetRecencyWeight()},
+	}
+
+	for _, weight := range weights {
+		if weight.value < 0 || math.IsNaN(weight.value) || math.IsInf(weight.value, 0) {
+			return fmt.Errorf("%s weight must be a finite non-negative number: %v", weight.name, weight.value)
+		}
+	}
+
+	return nil
+}
+
+func isZeroRankingOptions(options *pb.RankingOptions) bool {
+	return options.GetSpecMatchWeight() == 0 &&
+		options.GetAverageRatingWeight() == 0 &&
+		options.GetRatingCountWeight() == 0 &&
+		options.GetPricePerformanceWeight() == 0 &&
+		options.GetRecencyWeight() == 0
+}
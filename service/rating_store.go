@@ -1,11 +1,25 @@
 package service
 
 import (
+	"sort"
 	"sync"
 )
 
 type RatingStore interface {
-	Rate(laptopId string, score float64) *Rating
+	Rate(laptopId string, score float64) (*Rating, error)
+	// Unrate takes back one earlier rating with the given score. It returns
+	// a nil rating if the laptop has no rating left.
+	Unrate(laptopId string, score float64) (*Rating, error)
+	Find(laptopId string) *Rating
+	// List calls found with the rating of every rated laptop, ordered by
+	// laptop ID
+	List(found func(laptopId string, rating *Rating) error) error
+	// Set replaces the rating of a laptop, it is meant for copying ratings
+	// between stores
+	Set(laptopId string, rating *Rating) error
+	// Delete removes the rating of a laptop, it returns ErrNotFound if the
+	// laptop is not rated
+	Delete(laptopId string) error
 }
 
 type Rating struct {
@@ -13,6 +27,21 @@ type Rating struct {
 	sum   float64
 }
 
+func NewRating(count int, sum float64) *Rating {
+	return &Rating{
+		count: count,
+		sum:   sum,
+	}
+}
+
+func (rating *Rating) Count() int {
+	return rating.count
+}
+
+func (rating *Rating) Sum() float64 {
+	return rating.sum
+}
+
 type InMemoryRatingStore struct {
 	mutex   sync.RWMutex
 	ratings map[string]*Rating
@@ -24,7 +53,7 @@ func NewInMemoryRatingStore() *InMemoryRatingStore {
 	}
 }
 
-func (store *InMemoryRatingStore) Rate(laptopId string, score float64) *Rating {
+func (store *InMemoryRatingStore) Rate(laptopId string, score float64) (*Rating, error) {
 	store.mutex.Lock()
 	defer store.mutex.Unlock()
 
@@ -40,5 +69,76 @@ func (store *InMemoryRatingStore) Rate(laptopId string, score float64) *Rating {
 		rating.sum += score
 	}
 
-	return rating
+	return NewRating(rating.count, rating.sum), nil
+}
+
+func (store *InMemoryRatingStore) Unrate(laptopId string, score float64) (*Rating, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	rating := store.ratings[laptopId]
+	if rating == nil {
+		return nil, nil
+	}
+
+	rating.count--
+	rating.sum -= score
+	if rating.count <= 0 {
+		delete(store.ratings, laptopId)
+		return nil, nil
+	}
+
+	return NewRating(rating.count, rating.sum), nil
+}
+
+func (store *InMemoryRatingStore) Set(laptopId string, rating *Rating) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	store.ratings[laptopId] = NewRating(rating.count, rating.sum)
+	return nil
+}
+
+func (store *InMemoryRatingStore) Delete(laptopId string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if store.ratings[laptopId] == nil {
+		return ErrNotFound
+	}
+
+	delete(store.ratings, laptopId)
+	return nil
+}
+
+func (store *InMemoryRatingStore) List(found func(laptopId string, rating *Rating) error) error {
+	store.mutex.RLock()
+	laptopIds := make([]string, 0, len(store.ratings))
+	ratings := make(map[string]*Rating, len(store.ratings))
+	for laptopId, rating := range store.ratings {
+		laptopIds = append(laptopIds, laptopId)
+		ratings[laptopId] = NewRating(rating.count, rating.sum)
+	}
+	store.mutex.RUnlock()
+
+	sort.Strings(laptopIds)
+	for _, laptopId := range laptopIds {
+		if err := found(laptopId, ratings[laptopId]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (store *InMemoryRatingStore) Find(laptopId string) *Rating {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	rating := store.ratings[laptopId]
+	if rating == nil {
+		return nil
+	}
+
+	return NewRating(rating.count, rating.sum)
 }
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// LeaderAddressKey is the trailer in which a follower tells where to send
+// the writes it rejects
+const LeaderAddressKey = "leader-address"
+
+// ReadOnlyInterceptor lets only the read-only RPCs of a follower through
+type ReadOnlyInterceptor struct {
+	leaderAddress string
+	readOnly      map[string]bool
+}
+
+func NewReadOnlyInterceptor(leaderAddress string, readOnly map[string]bool) *ReadOnlyInterceptor {
+	return &ReadOnlyInterceptor{
+		leaderAddress: leaderAddress,
+		readOnly:      readOnly,
+	}
+}
+
+func (interceptor *ReadOnlyInterceptor) Unary() grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		if !interceptor.readOnly[info.FullMethod] {
+			grpc.SetTrailer(ctx, interceptor.trailer())
+			return nil, interceptor.reject()
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+func (interceptor *ReadOnlyInterceptor) Stream() grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		stream grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		if !interceptor.readOnly[info.FullMethod] {
+			stream.SetTrailer(interceptor.trailer())
+			return interceptor.reject()
+		}
+
+		return handler(srv, stream)
+	}
+}
+
+func (interceptor *ReadOnlyInterceptor) trailer() metadata.MD {
+	return metadata.Pairs(LeaderAddressKey, interceptor.leaderAddress)
+}
+
+func (interceptor *ReadOnlyInterceptor) reject() error {
+	return status.Errorf(
+		codes.FailedPrecondition,
+		"This server is a read-only follower, send writes to the leader at %s",
+		interceptor.leaderAddress,
+	)
+}
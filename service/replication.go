@@ -0,0 +1,343 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	pb "github.com/orkhanrustamli/pcbook/pcbook_proto/go"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const replicationBuffer = 1024
+
+// ReplicationLog numbers every write to the stores of a leader and hands it
+// to the followers subscribed at the time. Writes are applied and recorded
+// under one lock, so the log order is the order the stores saw. The log
+// itself is not kept: a follower that falls more than replicationBuffer
+// mutations behind is dropped and starts over from a new snapshot.
+type ReplicationLog struct {
+	mutex       sync.Mutex
+	sequence    uint64
+	subscribers map[*replicationSubscriber]bool
+}
+
+type replicationSubscriber struct {
+	mutations chan *pb.Mutation
+}
+
+func NewReplicationLog() *ReplicationLog {
+	return &ReplicationLog{
+		subscribers: make(map[*replicationSubscriber]bool),
+	}
+}
+
+// Wrap returns stores whose writes are recorded in the log. Only writes
+// through the returned stores are replicated.
+func (replication *ReplicationLog) Wrap(stores *Stores) *Stores {
+	return &Stores{
+		Laptops: &replicatedLaptopStore{stores.Laptops, replication},
+		Ratings: &replicatedRatingStore{stores.Ratings, replication},
+		Users:   &replicatedUserStore{stores.Users, replication},
+		Images:  &replicatedImageStore{stores.Images, replication},
+		close:   stores.close,
+	}
+}
+
+func (replication *ReplicationLog) Sequence() uint64 {
+	replication.mutex.Lock()
+	defer replication.mutex.Unlock()
+
+	return replication.sequence
+}
+
+// record runs write and, if it returns a mutation, appends it to the log
+func (replication *ReplicationLog) record(write func() (*pb.Mutation, error)) error {
+	replication.mutex.Lock()
+	defer replication.mutex.Unlock()
+
+	mutation, err := write()
+	if err != nil || mutation == nil {
+		return err
+	}
+
+	replication.sequence++
+	mutation.Sequence = replication.sequence
+	mutation.CommittedAt = timestamppb.Now()
+
+	for subscriber := range replication.subscribers {
+		select {
+		case subscriber.mutations <- mutation:
+		default:
+			close(subscriber.mutations)
+			delete(replication.subscribers, subscriber)
+		}
+	}
+
+	return nil
+}
+
+// subscribe takes a snapshot of stores and starts collecting the mutations
+// that follow it, with no write in between
+func (replication *ReplicationLog) subscribe(
+	ctx context.Context,
+	stores *Stores,
+) (*backupSnapshot, uint64, *replicationSubscriber, error) {
+	replication.mutex.Lock()
+	defer replication.mutex.Unlock()
+
+	snapshot := &backupSnapshot{}
+	if err := snapshot.take(ctx, stores); err != nil {
+		snapshot.close()
+		return nil, 0, nil, err
+	}
+
+	subscriber := &replicationSubscriber{
+		mutations: make(chan *pb.Mutation, replicationBuffer),
+	}
+	replication.subscribers[subscriber] = true
+
+	return snapshot, replication.sequence, subscriber, nil
+}
+
+func (replication *ReplicationLog) unsubscribe(subscriber *replicationSubscriber) {
+	replication.mutex.Lock()
+	defer replication.mutex.Unlock()
+
+	if replication.subscribers[subscriber] {
+		close(subscriber.mutations)
+		delete(replication.subscribers, subscriber)
+	}
+}
+
+type replicatedLaptopStore struct {
+	LaptopStore
+	replication *ReplicationLog
+}
+
+func (store *replicatedLaptopStore) Save(laptop *pb.Laptop) error {
+	return store.replication.record(func() (*pb.Mutation, error) {
+		if err := store.LaptopStore.Save(laptop); err != nil {
+			return nil, err
+		}
+
+		return &pb.Mutation{Change: &pb.Mutation_SaveLaptop{
+			SaveLaptop: proto.Clone(laptop).(*pb.Laptop),
+		}}, nil
+	})
+}
+
+func (store *replicatedLaptopStore) Delete(id string) error {
+	return store.replication.record(func() (*pb.Mutation, error) {
+		if err := store.LaptopStore.Delete(id); err != nil {
+			return nil, err
+		}
+
+		return &pb.Mutation{Change: &pb.Mutation_DeleteLaptop{DeleteLaptop: id}}, nil
+	})
+}
+
+// replicatedRatingStore replicates the rating a write results in rather
+// than the score, so that followers never add a score twice
+type replicatedRatingStore struct {
+	RatingStore
+	replication *ReplicationLog
+}
+
+func (store *replicatedRatingStore) Rate(laptopId string, score float64) (*Rating, error) {
+	return store.update(laptopId, func() (*Rating, error) {
+		return store.RatingStore.Rate(laptopId, score)
+	})
+}
+
+func (store *replicatedRatingStore) Unrate(laptopId string, score float64) (*Rating, error) {
+	return store.update(laptopId, func() (*Rating, error) {
+		return store.RatingStore.Unrate(laptopId, score)
+	})
+}
+
+func (store *replicatedRatingStore) update(laptopId string, update func() (*Rating, error)) (*Rating, error) {
+	var rating *Rating
+	err := store.replication.record(func() (*pb.Mutation, error) {
+		var err error
+		rating, err = update()
+		if err != nil {
+			return nil, err
+		}
+
+		return ratingMutation(laptopId, store.RatingStore.Find(laptopId)), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return rating, nil
+}
+
+func (store *replicatedRatingStore) Set(laptopId string, rating *Rating) error {
+	return store.replication.record(func() (*pb.Mutation, error) {
+		if err := store.RatingStore.Set(laptopId, rating); err != nil {
+			return nil, err
+		}
+
+		return ratingMutation(laptopId, rating), nil
+	})
+}
+
+func (store *replicatedRatingStore) Delete(laptopId string) error {
+	return store.replication.record(func() (*pb.Mutation, error) {
+		if err := store.RatingStore.Delete(laptopId); err != nil {
+			return nil, err
+		}
+
+		return ratingMutation(laptopId, nil), nil
+	})
+}
+
+func ratingMutation(laptopId string, rating *Rating) *pb.Mutation {
+	if rating == nil {
+		return &pb.Mutation{Change: &pb.Mutation_DeleteRating{DeleteRating: laptopId}}
+	}
+
+	return &pb.Mutation{Change: &pb.Mutation_SetRating{SetRating: &pb.BackupRating{
+		LaptopId: laptopId,
+		Rating: &pb.RatingRecord{
+			Count: uint32(rating.count),
+			Sum:   rating.sum,
+		},
+	}}}
+}
+
+type replicatedUserStore struct {
+	UserStore
+	replication *ReplicationLog
+}
+
+func (store *replicatedUserStore) Save(user *User) error {
+	return store.replication.record(func() (*pb.Mutation, error) {
+		if err := store.UserStore.Save(user); err != nil {
+			return nil, err
+		}
+
+		return &pb.Mutation{Change: &pb.Mutation_SaveUser{SaveUser: &pb.UserRecord{
+			Username:     user.Username,
+			PasswordHash: user.Password,
+			Role:         user.Role,
+			Tenant:       user.Tenant,
+		}}}, nil
+	})
+}
+
+func (store *replicatedUserStore) Delete(username string) error {
+	return store.replication.record(func() (*pb.Mutation, error) {
+		if err := store.UserStore.Delete(username); err != nil {
+			return nil, err
+		}
+
+		return &pb.Mutation{Change: &pb.Mutation_DeleteUser{DeleteUser: username}}, nil
+	})
+}
+
+// replicatedImageStore keeps a copy of the data of every image it writes,
+// since followers receive the image along with its record
+type replicatedImageStore struct {
+	ImageStore
+	replication *ReplicationLog
+}
+
+func (store *replicatedImageStore) Save(laptopId string, imageType string, imageData bytes.Buffer) (string, error) {
+	imageId, err := uuid.NewRandom()
+	if err != nil {
+		return "", fmt.Errorf("cannot generate random id for image: %v", err)
+	}
+
+	image := &ImageInfo{
+		Id:         imageId.String(),
+		LaptopId:   laptopId,
+		Type:       imageType,
+		UploadedAt: time.Now(),
+	}
+	if err := store.Import(image, &imageData); err != nil {
+		return "", err
+	}
+
+	return image.Id, nil
+}
+
+func (store *replicatedImageStore) Import(image *ImageInfo, imageData io.Reader) error {
+	data, err := io.ReadAll(imageData)
+	if err != nil {
+		return fmt.Errorf("cannot read image data: %v", err)
+	}
+
+	return store.replication.record(func() (*pb.Mutation, error) {
+		if err := store.ImageStore.Import(image, bytes.NewReader(data)); err != nil {
+			return nil, err
+		}
+
+		return &pb.Mutation{
+			Change: &pb.Mutation_SaveImage{SaveImage: &pb.ImageRecord{
+				Id:         image.Id,
+				LaptopId:   image.LaptopId,
+				ImageType:  image.Type,
+				Size:       uint64(len(data)),
+				UploadedAt: timestamppb.New(image.UploadedAt),
+			}},
+			ImageData: data,
+		}, nil
+	})
+}
+
+func (store *replicatedImageStore) Delete(imageId string) error {
+	return store.replication.record(func() (*pb.Mutation, error) {
+		if err := store.ImageStore.Delete(imageId); err != nil {
+			return nil, err
+		}
+
+		return &pb.Mutation{Change: &pb.Mutation_DeleteImage{DeleteImage: imageId}}, nil
+	})
+}
+
+// applyMutation applies a mutation recorded by a leader to stores
+func applyMutation(stores *Stores, mutation *pb.Mutation) error {
+	switch change := mutation.GetChange().(type) {
+	case *pb.Mutation_SaveLaptop:
+		return stores.Laptops.Save(change.SaveLaptop)
+	case *pb.Mutation_DeleteLaptop:
+		return stores.Laptops.Delete(change.DeleteLaptop)
+	case *pb.Mutation_SetRating:
+		rating := change.SetRating.GetRating()
+		return stores.Ratings.Set(change.SetRating.GetLaptopId(), NewRating(int(rating.GetCount()), rating.GetSum()))
+	case *pb.Mutation_DeleteRating:
+		if err := stores.Ratings.Delete(change.DeleteRating); err != nil && !errors.Is(err, ErrNotFound) {
+			return err
+		}
+		return nil
+	case *pb.Mutation_SaveUser:
+		return stores.Users.Save(&User{
+			Username: change.SaveUser.GetUsername(),
+			Password: change.SaveUser.GetPasswordHash(),
+			Role:     change.SaveUser.GetRole(),
+			Tenant:   change.SaveUser.GetTenant(),
+		})
+	case *pb.Mutation_DeleteUser:
+		return stores.Users.Delete(change.DeleteUser)
+	case *pb.Mutation_SaveImage:
+		return stores.Images.Import(&ImageInfo{
+			Id:         change.SaveImage.GetId(),
+			LaptopId:   change.SaveImage.GetLaptopId(),
+			Type:       change.SaveImage.GetImageType(),
+			UploadedAt: change.SaveImage.GetUploadedAt().AsTime(),
+		}, bytes.NewReader(mutation.GetImageData()))
+	case *pb.Mutation_DeleteImage:
+		return stores.Images.Delete(change.DeleteImage)
+	default:
+		return fmt.Errorf("unknown mutation: %v", mutation)
+	}
+}
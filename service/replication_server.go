@@ -0,0 +1,135 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"log"
+	"time"
+
+	pb "github.com/orkhanrustamli/pcbook/pcbook_proto/go"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const replicationHeartbeatInterval = time.Second
+
+// ReplicationServer streams the stores of a leader to its followers, and
+// reports the replication status of either side. A leader has a log, a
+// follower has a Follower.
+type ReplicationServer struct {
+	replication *ReplicationLog
+	stores      *Stores
+	follower    *Follower
+	pb.UnimplementedReplicationServiceServer
+}
+
+// NewLeaderReplicationServer replicates stores, which must be the stores
+// returned by replication.Wrap
+func NewLeaderReplicationServer(replication *ReplicationLog, stores *Stores) *ReplicationServer {
+	return &ReplicationServer{
+		replication: replication,
+		stores:      stores,
+	}
+}
+
+func NewFollowerReplicationServer(follower *Follower) *ReplicationServer {
+	return &ReplicationServer{
+		follower: follower,
+	}
+}
+
+func (server *ReplicationServer) Replicate(req *pb.ReplicateRequest, stream pb.ReplicationService_ReplicateServer) error {
+	if server.replication == nil {
+		return logAndReturnError(status.Errorf(codes.FailedPrecondition, "Only the leader can be replicated"))
+	}
+	if err := authorizeBackup(stream.Context()); err != nil {
+		return err
+	}
+
+	log.Print("Received a replicate request")
+
+	snapshot, sequence, subscriber, err := server.replication.subscribe(stream.Context(), server.stores)
+	if err != nil {
+		return logAndReturnError(status.Errorf(codes.Internal, "Cannot take snapshot: %v", err))
+	}
+	defer server.replication.unsubscribe(subscriber)
+	defer snapshot.close()
+
+	err = stream.Send(&pb.ReplicateResponse{Data: &pb.ReplicateResponse_SnapshotSequence{SnapshotSequence: sequence}})
+	if err != nil {
+		return status.Errorf(codes.Unknown, "Cannot send snapshot: %v", err)
+	}
+
+	writer := bufio.NewWriterSize(&snapshotStreamWriter{stream}, backupChunkSize)
+	err = snapshot.write(stream.Context(), writer)
+	if err == nil {
+		err = writer.Flush()
+	}
+	if err != nil {
+		return logAndReturnError(status.Errorf(codes.Internal, "Cannot send snapshot: %v", err))
+	}
+	snapshot.close()
+
+	log.Printf("Sent snapshot at sequence %d", sequence)
+
+	heartbeat := time.NewTicker(replicationHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	// a heartbeat right away marks the end of the snapshot
+	res := server.heartbeat()
+	for {
+		if err := stream.Send(res); err != nil {
+			return status.Errorf(codes.Unknown, "Cannot send mutation: %v", err)
+		}
+
+		select {
+		case <-stream.Context().Done():
+			return status.FromContextError(stream.Context().Err()).Err()
+		case mutation, ok := <-subscriber.mutations:
+			if !ok {
+				return logAndReturnError(status.Errorf(codes.ResourceExhausted, "Follower fell too far behind"))
+			}
+			res = &pb.ReplicateResponse{Data: &pb.ReplicateResponse_Mutation{Mutation: mutation}}
+		case <-heartbeat.C:
+			res = server.heartbeat()
+		}
+	}
+}
+
+func (server *ReplicationServer) ReplicationStatus(
+	ctx context.Context,
+	req *pb.ReplicationStatusRequest,
+) (*pb.ReplicationStatusResponse, error) {
+	if server.follower != nil {
+		return server.follower.Status(), nil
+	}
+
+	sequence := server.replication.Sequence()
+	return &pb.ReplicationStatusResponse{
+		Role:            pb.ReplicationStatusResponse_LEADER,
+		Connected:       true,
+		AppliedSequence: sequence,
+		LeaderSequence:  sequence,
+	}, nil
+}
+
+func (server *ReplicationServer) heartbeat() *pb.ReplicateResponse {
+	return &pb.ReplicateResponse{Data: &pb.ReplicateResponse_Heartbeat{Heartbeat: &pb.ReplicationHeartbeat{
+		Sequence: server.replication.Sequence(),
+		SentAt:   timestamppb.Now(),
+	}}}
+}
+
+type snapshotStreamWriter struct {
+	stream pb.ReplicationService_ReplicateServer
+}
+
+func (writer *snapshotStreamWriter) Write(p []byte) (int, error) {
+	err := writer.stream.Send(&pb.ReplicateResponse{Data: &pb.ReplicateResponse_SnapshotChunk{SnapshotChunk: p}})
+	if err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
@@ -0,0 +1,192 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/orkhanrustamli/pcbook/genarator"
+	pb "github.com/orkhanrustamli/pcbook/pcbook_proto/go"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestReplication(t *testing.T) {
+	t.Parallel()
+
+	base := newTestBackupStores(t)
+	replication := NewReplicationLog()
+	leader := replication.Wrap(base)
+
+	laptop1 := genarator.NewLaptop()
+	require.NoError(t, leader.Laptops.Save(laptop1))
+	leader.Ratings.Rate(laptop1.GetId(), 4)
+	_, err := leader.Images.Save(laptop1.GetId(), ".jpg", *bytes.NewBufferString("image1"))
+	require.NoError(t, err)
+	user, err := NewUser("user1", "secret", "user", "")
+	require.NoError(t, err)
+	require.NoError(t, leader.Users.Save(user))
+
+	grpcServer := grpc.NewServer()
+	pb.RegisterReplicationServiceServer(grpcServer, NewLeaderReplicationServer(replication, leader))
+	listener, err := net.Listen("tcp", ":0")
+	require.NoError(t, err)
+	go grpcServer.Serve(listener)
+	defer grpcServer.Stop()
+
+	conn, err := grpc.Dial(listener.Addr().String(), grpc.WithInsecure())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// the snapshot replaces whatever the follower had
+	local := newTestBackupStores(t)
+	stale := genarator.NewLaptop()
+	require.NoError(t, local.Laptops.Save(stale))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	follower := NewFollower(conn, listener.Addr().String(), local)
+	go follower.Run(ctx, 10*time.Millisecond)
+	requireCaughtUp(t, follower, replication)
+
+	other, err := local.Laptops.Find(stale.GetId())
+	require.NoError(t, err)
+	require.Nil(t, other)
+
+	laptop2 := genarator.NewLaptop()
+	require.NoError(t, leader.Laptops.Save(laptop2))
+	leader.Ratings.Rate(laptop2.GetId(), 3)
+	leader.Ratings.Rate(laptop2.GetId(), 5)
+	leader.Ratings.Unrate(laptop1.GetId(), 4)
+	imageId, err := leader.Images.Save(laptop2.GetId(), ".png", *bytes.NewBufferString("image2"))
+	require.NoError(t, err)
+	_, err = leader.Images.Save(laptop2.GetId(), ".png", *bytes.NewBufferString("image3"))
+	require.NoError(t, err)
+	require.NoError(t, leader.Images.Delete(imageId))
+	require.NoError(t, leader.Users.Delete("user1"))
+	require.NoError(t, leader.Laptops.Delete(laptop1.GetId()))
+	requireCaughtUp(t, follower, replication)
+
+	// checking both ways makes sure the follower has nothing more either
+	for _, migration := range []*Migration{
+		{Source: base, Destination: local},
+		{Source: local, Destination: base},
+	} {
+		_, err := migration.Verify(context.Background())
+		require.NoError(t, err)
+	}
+
+	rating := local.Ratings.Find(laptop2.GetId())
+	require.NotNil(t, rating)
+	require.Equal(t, 2, rating.Count())
+	require.Nil(t, local.Ratings.Find(laptop1.GetId()))
+}
+
+func TestReplicationLogDropsSlowFollower(t *testing.T) {
+	t.Parallel()
+
+	replication := NewReplicationLog()
+	leader := replication.Wrap(newTestBackupStores(t))
+
+	snapshot, sequence, subscriber, err := replication.subscribe(context.Background(), leader)
+	require.NoError(t, err)
+	defer snapshot.close()
+	require.Zero(t, sequence)
+
+	for i := 0; i <= replicationBuffer; i++ {
+		leader.Ratings.Rate("laptop", 1)
+	}
+
+	received := 0
+	for range subscriber.mutations {
+		received++
+	}
+	require.Equal(t, replicationBuffer, received)
+
+	// unsubscribing a dropped follower is harmless
+	replication.unsubscribe(subscriber)
+}
+
+func TestFollowerStatus(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	follower := &Follower{
+		leaderAddress: "leader:8080",
+		now:           func() time.Time { return now },
+	}
+
+	status := follower.Status()
+	require.Equal(t, pb.ReplicationStatusResponse_FOLLOWER, status.GetRole())
+	require.False(t, status.GetConnected())
+	require.Zero(t, status.GetLagSeconds())
+
+	follower.update(5, 5)
+	now = now.Add(3 * time.Second)
+	follower.update(5, 8)
+
+	status = follower.Status()
+	require.True(t, status.GetConnected())
+	require.Equal(t, uint64(3), status.GetLagSequences())
+	require.Equal(t, 3.0, status.GetLagSeconds())
+
+	follower.update(8, 8)
+	status = follower.Status()
+	require.Zero(t, status.GetLagSequences())
+	require.Zero(t, status.GetLagSeconds())
+}
+
+func TestServerReadOnlyFollower(t *testing.T) {
+	t.Parallel()
+
+	store := NewInMemoryLaptopStore()
+	laptop := genarator.NewLaptop()
+	require.NoError(t, store.Save(laptop))
+
+	interceptor := NewReadOnlyInterceptor("leader:8080", map[string]bool{
+		"/pcbook.LaptopService/GetLaptop": true,
+	})
+	grpcServer := grpc.NewServer(
+		grpc.UnaryInterceptor(interceptor.Unary()),
+		grpc.StreamInterceptor(interceptor.Stream()),
+	)
+	pb.RegisterLaptopServiceServer(grpcServer, NewLaptopServer(store, nil, nil))
+	listener, err := net.Listen("tcp", ":0")
+	require.NoError(t, err)
+	go grpcServer.Serve(listener)
+	defer grpcServer.Stop()
+
+	laptopClient := startTestLaptopClient(t, listener.Addr().String())
+
+	res, err := laptopClient.GetLaptop(context.Background(), &pb.GetLaptopRequest{Id: laptop.GetId()})
+	require.NoError(t, err)
+	require.Equal(t, laptop.GetId(), res.GetLaptop().GetId())
+
+	trailer := metadata.MD{}
+	_, err = laptopClient.CreateLaptop(
+		context.Background(),
+		&pb.CreateLaptopRequest{Laptop: genarator.NewLaptop()},
+		grpc.Trailer(&trailer),
+	)
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+	require.Equal(t, []string{"leader:8080"}, trailer.Get(LeaderAddressKey))
+
+	stream, err := laptopClient.RateLaptop(context.Background())
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+	require.Equal(t, []string{"leader:8080"}, stream.Trailer().Get(LeaderAddressKey))
+}
+
+func requireCaughtUp(t *testing.T, follower *Follower, replication *ReplicationLog) {
+	require.Eventually(t, func() bool {
+		status := follower.Status()
+		return status.GetConnected() && status.GetAppliedSequence() == replication.Sequence()
+	}, 5*time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,195 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	pb "github.com/orkhanrustamli/pcbook/pcbook_proto/go"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type SavedSearchServer struct {
+	laptopStore      LaptopStore
+	savedSearchStore SavedSearchStore
+	// Cluster, if set, runs the saved searches over the laptops of every
+	// node. The saved searches themselves stay on the node they were saved on.
+	Cluster *Cluster
+	pb.UnimplementedSavedSearchServiceServer
+}
+
+func NewSavedSearchServer(laptopStore LaptopStore, savedSearchStore SavedSearchStore) *SavedSearchServer {
+	return &SavedSearchServer{
+		laptopStore:      laptopStore,
+		savedSearchStore: savedSearchStore,
+	}
+}
+
+func (server *SavedSearchServer) SaveSearch(
+	ctx context.Context,
+	req *pb.SaveSearchRequest,
+) (*pb.SaveSearchResponse, error) {
+	username, err := usernameFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Received a save-search request from user %s with name: %s", username, req.GetName())
+
+	if len(req.GetName()) == 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "Saved search name is required")
+	}
+	if req.GetFilter() == nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Saved search filter is required")
+	}
+	if err := validateFilter(req.GetFilter()); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid filter: %v", err)
+	}
+
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Cannot generate a new saved search ID: %v", err)
+	}
+
+	search := &pb.SavedSearch{
+		Id:        id.String(),
+		Name:      req.GetName(),
+		Filter:    req.GetFilter(),
+		CreatedAt: timestamppb.Now(),
+	}
+
+	if err := server.savedSearchStore.Save(username, search); err != nil {
+		code := codes.Internal
+		if errors.Is(err, ErrAlreadyExists) {
+			code = codes.AlreadyExists
+		}
+		return nil, status.Errorf(code, err.Error())
+	}
+
+	log.Printf("Saved search was saved with ID: %v", search.Id)
+
+	res := &pb.SaveSearchResponse{
+		SavedSearch: search,
+	}
+	return res, nil
+}
+
+func (server *SavedSearchServer) ListSavedSearches(
+	ctx context.Context,
+	req *pb.ListSavedSearchesRequest,
+) (*pb.ListSavedSearchesResponse, error) {
+	username, err := usernameFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Received a list-saved-searches request from user %s", username)
+
+	searches, err := server.savedSearchStore.List(username)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Cannot list saved searches: %v", err)
+	}
+
+	res := &pb.ListSavedSearchesResponse{
+		SavedSearches: searches,
+	}
+	return res, nil
+}
+
+func (server *SavedSearchServer) DeleteSavedSearch(
+	ctx context.Context,
+	req *pb.DeleteSavedSearchRequest,
+) (*pb.DeleteSavedSearchResponse, error) {
+	username, err := usernameFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Received a delete-saved-search request from user %s with id: %s", username, req.GetId())
+
+	if err := server.savedSearchStore.Delete(username, req.GetId()); err != nil {
+		code := codes.Internal
+		if errors.Is(err, ErrNotFound) {
+			code = codes.NotFound
+		}
+		return nil, status.Errorf(code, "Cannot delete saved search %s: %v", req.GetId(), err)
+	}
+
+	return &pb.DeleteSavedSearchResponse{}, nil
+}
+
+func (server *SavedSearchServer) RunSavedSearch(
+	req *pb.RunSavedSearchRequest,
+	stream pb.SavedSearchService_RunSavedSearchServer,
+) error {
+	username, err := usernameFromContext(stream.Context())
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Received a run-saved-search request from user %s with id: %s", username, req.GetId())
+
+	search, err := server.savedSearchStore.Find(username, req.GetId())
+	if err != nil {
+		return logAndReturnError(status.Errorf(codes.Internal, "Cannot find saved search: %v", err))
+	}
+	if search == nil {
+		return logAndReturnError(status.Errorf(codes.NotFound, "Saved search %s does not exist", req.GetId()))
+	}
+
+	filter := proto.Clone(search.GetFilter()).(*pb.Filter)
+	if req.GetOnlyNew() && search.GetLastRunAt() != nil {
+		filter.CreatedAfter = search.GetLastRunAt()
+	}
+
+	// the next run starts after the newest laptop this run returned rather
+	// than when this run started, since a laptop created before then may
+	// only be saved once the search went past it
+	var runAt time.Time
+	if search.GetLastRunAt() != nil {
+		runAt = search.GetLastRunAt().AsTime()
+	}
+	found := func(laptop *pb.Laptop) error {
+		res := &pb.SearchLaptopResponse{Laptop: laptop}
+		if err := stream.Send(res); err != nil {
+			return err
+		}
+
+		if createdAt := laptop.GetCreatedAt().AsTime(); createdAt.After(runAt) {
+			runAt = createdAt
+		}
+		log.Printf("Sent laptop with id: %s", laptop.GetId())
+		return nil
+	}
+	if server.Cluster != nil {
+		err = server.Cluster.collect(stream.Context(), filter, found)
+	} else {
+		_, err = ScopeLaptopStore(stream.Context(), server.laptopStore).Search(stream.Context(), filter, found)
+	}
+	if err != nil {
+		return searchError(stream.Context(), err)
+	}
+
+	if runAt.IsZero() {
+		return nil
+	}
+	if err := server.savedSearchStore.MarkRun(username, search.GetId(), runAt); err != nil {
+		return status.Errorf(codes.Internal, "Cannot update saved search: %v", err)
+	}
+
+	return nil
+}
+
+func usernameFromContext(ctx context.Context) (string, error) {
+	userClaims, ok := UserClaimsFromContext(ctx)
+	if !ok || len(userClaims.Username) == 0 {
+		return "", status.Errorf(codes.Unauthenticated, "user is not authenticated")
+	}
+
+	return userClaims.Username, nil
+}
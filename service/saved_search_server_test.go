@@ -0,0 +1,165 @@
+package service
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/orkhanrustamli/pcbook/genarator"
+	pb "github.com/orkhanrustamli/pcbook/pcbook_proto/go"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestServerSavedSearch(t *testing.T) {
+	t.Parallel()
+
+	server := NewSavedSearchServer(NewInMemoryLaptopStore(), NewInMemorySavedSearchStore())
+	filter := &pb.Filter{
+		MaxPriceUsd: 3000,
+		MinRam:      &pb.Memory{Value: 8, Unit: pb.Memory_GIGABYTE},
+	}
+
+	_, err := server.SaveSearch(context.Background(), &pb.SaveSearchRequest{Name: "daily", Filter: filter})
+	require.Error(t, err)
+	st, ok := status.FromError(err)
+	require.True(t, ok)
+	require.Equal(t, codes.Unauthenticated, st.Code())
+
+	alice := ContextWithUserClaims(context.Background(), &UserClaims{Username: "alice", Role: "user"})
+	bob := ContextWithUserClaims(context.Background(), &UserClaims{Username: "bob", Role: "user"})
+
+	res, err := server.SaveSearch(alice, &pb.SaveSearchRequest{Name: "daily", Filter: filter})
+	require.NoError(t, err)
+	require.NotEmpty(t, res.GetSavedSearch().GetId())
+
+	_, err = server.SaveSearch(alice, &pb.SaveSearchRequest{Name: "daily", Filter: filter})
+	st, _ = status.FromError(err)
+	require.Equal(t, codes.AlreadyExists, st.Code())
+
+	list, err := server.ListSavedSearches(alice, &pb.ListSavedSearchesRequest{})
+	require.NoError(t, err)
+	require.Len(t, list.GetSavedSearches(), 1)
+	require.Equal(t, "daily", list.GetSavedSearches()[0].GetName())
+
+	list, err = server.ListSavedSearches(bob, &pb.ListSavedSearchesRequest{})
+	require.NoError(t, err)
+	require.Empty(t, list.GetSavedSearches())
+
+	_, err = server.DeleteSavedSearch(bob, &pb.DeleteSavedSearchRequest{Id: res.GetSavedSearch().GetId()})
+	st, _ = status.FromError(err)
+	require.Equal(t, codes.NotFound, st.Code())
+
+	_, err = server.DeleteSavedSearch(alice, &pb.DeleteSavedSearchRequest{Id: res.GetSavedSearch().GetId()})
+	require.NoError(t, err)
+
+	list, err = server.ListSavedSearches(alice, &pb.ListSavedSearchesRequest{})
+	require.NoError(t, err)
+	require.Empty(t, list.GetSavedSearches())
+}
+
+func TestClientRunSavedSearch(t *testing.T) {
+	t.Parallel()
+
+	runWithLaptopStores(t, func(t *testing.T, newStore func(t *testing.T) LaptopStore) {
+		laptopStore := newStore(t)
+		jwtManager := NewJWTManager("secret", time.Minute)
+		serverAddress := startTestSavedSearchServer(t, laptopStore, jwtManager)
+		client := startTestSavedSearchClient(t, serverAddress)
+
+		accessToken, err := jwtManager.Generate("user1", "user", "")
+		require.NoError(t, err)
+		ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", accessToken)
+
+		filter := &pb.Filter{
+			MaxPriceUsd: 5000,
+			MinRam:      &pb.Memory{Value: 1, Unit: pb.Memory_BIT},
+		}
+		saved, err := client.SaveSearch(ctx, &pb.SaveSearchRequest{Name: "all", Filter: filter})
+		require.NoError(t, err)
+		id := saved.GetSavedSearch().GetId()
+
+		oldLaptop := genarator.NewLaptop()
+		oldLaptop.CreatedAt = timestamppb.New(time.Now().Add(-time.Minute))
+		require.NoError(t, laptopStore.Save(oldLaptop))
+
+		found := runTestSavedSearch(t, ctx, client, id, true)
+		require.Equal(t, []string{oldLaptop.GetId()}, found)
+
+		// a laptop created before the last run but saved after it is still new
+		lateLaptop := genarator.NewLaptop()
+		lateLaptop.CreatedAt = timestamppb.New(time.Now().Add(-time.Second))
+		require.NoError(t, laptopStore.Save(lateLaptop))
+
+		found = runTestSavedSearch(t, ctx, client, id, true)
+		require.Equal(t, []string{lateLaptop.GetId()}, found)
+
+		newLaptop := genarator.NewLaptop()
+		newLaptop.CreatedAt = timestamppb.New(time.Now().Add(time.Second))
+		require.NoError(t, laptopStore.Save(newLaptop))
+
+		found = runTestSavedSearch(t, ctx, client, id, true)
+		require.Equal(t, []string{newLaptop.GetId()}, found)
+
+		found = runTestSavedSearch(t, ctx, client, id, true)
+		require.Empty(t, found)
+
+		found = runTestSavedSearch(t, ctx, client, id, false)
+		require.ElementsMatch(t, []string{oldLaptop.GetId(), newLaptop.GetId(), lateLaptop.GetId()}, found)
+	})
+}
+
+func runTestSavedSearch(
+	t *testing.T,
+	ctx context.Context,
+	client pb.SavedSearchServiceClient,
+	id string,
+	onlyNew bool,
+) []string {
+	stream, err := client.RunSavedSearch(ctx, &pb.RunSavedSearchRequest{Id: id, OnlyNew: onlyNew})
+	require.NoError(t, err)
+
+	found := []string{}
+	for {
+		res, err := stream.Recv()
+		if err == io.EOF {
+			return found
+		}
+		require.NoError(t, err)
+		found = append(found, res.GetLaptop().GetId())
+	}
+}
+
+func startTestSavedSearchServer(t *testing.T, laptopStore LaptopStore, jwtManager *JWTManager) string {
+	savedSearchServer := NewSavedSearchServer(laptopStore, NewInMemorySavedSearchStore())
+	authInterceptor := NewAuthInterceptor(jwtManager, map[string][]string{
+		"/pcbook.SavedSearchService/SaveSearch":     {"user"},
+		"/pcbook.SavedSearchService/RunSavedSearch": {"user"},
+	})
+
+	grpcServer := grpc.NewServer(
+		grpc.UnaryInterceptor(authInterceptor.Unary()),
+		grpc.StreamInterceptor(authInterceptor.Stream()),
+	)
+	pb.RegisterSavedSearchServiceServer(grpcServer, savedSearchServer)
+
+	listener, err := net.Listen("tcp", ":0")
+	require.NoError(t, err)
+
+	go grpcServer.Serve(listener)
+
+	return listener.Addr().String()
+}
+
+func startTestSavedSearchClient(t *testing.T, address string) pb.SavedSearchServiceClient {
+	conn, err := grpc.Dial(address, grpc.WithInsecure())
+	require.NoError(t, err)
+
+	return pb.NewSavedSearchServiceClient(conn)
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	pb "github.com/orkhanrustamli/pcbook/pcbook_proto/go"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type SavedSearchStore interface {
+	Save(username string, search *pb.SavedSearch) error
+	Find(username string, id string) (*pb.SavedSearch, error)
+	List(username string) ([]*pb.SavedSearch, error)
+	Delete(username string, id string) error
+	MarkRun(username string, id string, runAt time.Time) error
+}
+
+type InMemorySavedSearchStore struct {
+	mutex    sync.RWMutex
+	searches map[string]map[string]*pb.SavedSearch
+}
+
+func NewInMemorySavedSearchStore() *InMemorySavedSearchStore {
+	return &InMemorySavedSearchStore{
+		searches: make(map[string]map[string]*pb.SavedSearch),
+	}
+}
+
+func (store *InMemorySavedSearchStore) Save(username string, search *pb.SavedSearch) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	userSearches := store.searches[username]
+	if userSearches == nil {
+		userSearches = make(map[string]*pb.SavedSearch)
+		store.searches[username] = userSearches
+	}
+
+	if userSearches[search.GetId()] != nil {
+		return ErrAlreadyExists
+	}
+
+	for _, other := range userSearches {
+		if other.GetName() == search.GetName() {
+			return fmt.Errorf("saved search named %q: %w", search.GetName(), ErrAlreadyExists)
+		}
+	}
+
+	userSearches[search.GetId()] = proto.Clone(search).(*pb.SavedSearch)
+	return nil
+}
+
+func (store *InMemorySavedSearchStore) Find(username string, id string) (*pb.SavedSearch, error) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	search := store.searches[username][id]
+	if search == nil {
+		return nil, nil
+	}
+
+	return proto.Clone(search).(*pb.SavedSearch), nil
+}
+
+func (store *InMemorySavedSearchStore) List(username string) ([]*pb.SavedSearch, error) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	searches := make([]*pb.SavedSearch, 0, len(store.searches[username]))
+	for _, search := range store.searches[username] {
+		searches = append(searches, proto.Clone(search).(*pb.SavedSearch))
+	}
+
+	sort.Slice(searches, func(i, j int) bool {
+		return searches[i].GetCreatedAt().AsTime().Before(searches[j].GetCreatedAt().AsTime())
+	})
+
+	return searches, nil
+}
+
+func (store *InMemorySavedSearchStore) Delete(username string, id string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if store.searches[username][id] == nil {
+		return ErrNotFound
+	}
+
+	delete(store.searches[username], id)
+	return nil
+}
+
+func (store *InMemorySavedSearchStore) MarkRun(username string, id string, runAt time.Time) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	search := store.searches[username][id]
+	if search == nil {
+		return ErrNotFound
+	}
+
+	search.LastRunAt = timestamppb.New(runAt)
+	return nil
+}
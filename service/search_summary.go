@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Trailer keys set on every SearchLaptop stream
+const (
+	SearchCompleteTrailer   = "search-complete"
+	SearchScannedTrailer    = "search-scanned"
+	SearchMatchedTrailer    = "search-matched"
+	SearchStopReasonTrailer = "search-stop-reason"
+)
+
+// Reasons why a search stopped
+const (
+	SearchCompleted        = "completed"
+	SearchDeadlineExceeded = "deadline_exceeded"
+	SearchCanceled         = "canceled"
+)
+
+// maxSearchDeadlineMargin caps the part of the caller's deadline that is kept
+// free to report a truncated result set before the caller gives up
+const maxSearchDeadlineMargin = 500 * time.Millisecond
+
+// searchContext returns a context that expires a little before the caller's
+// deadline, so the server can still set its trailer when the search runs late
+func searchContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return context.WithCancel(ctx)
+	}
+
+	margin := time.Until(deadline) / 10
+	if margin > maxSearchDeadlineMargin {
+		margin = maxSearchDeadlineMargin
+	}
+
+	return context.WithDeadline(ctx, deadline.Add(-margin))
+}
+
+// searchError turns the error a search failed with into a status. A search
+// cut short by the caller is reported as Canceled or DeadlineExceeded, not
+// as a fault of the server.
+func searchError(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return logAndReturnError(status.FromContextError(ctxErr).Err())
+	}
+	if isContextError(err) {
+		return logAndReturnError(status.FromContextError(err).Err())
+	}
+
+	return status.Errorf(codes.Internal, "unexpected error: %v", err)
+}
+
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+func searchStopReason(err error) string {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return SearchDeadlineExceeded
+	case errors.Is(err, context.Canceled):
+		return SearchCanceled
+	default:
+		return SearchCompleted
+	}
+}
+
+func searchTrailer(stats SearchStats, err error) metadata.MD {
+	return metadata.Pairs(
+		SearchCompleteTrailer, strconv.FormatBool(err == nil),
+		SearchScannedTrailer, strconv.Itoa(stats.Scanned),
+		SearchMatchedTrailer, strconv.Itoa(stats.Matched),
+		SearchStopReasonTrailer, searchStopReason(err),
+	)
+}
@@ -0,0 +1,156 @@
+package service
+
+import (
+	"context"
+	"hash/fnv"
+	"log"
+	"sync"
+
+	pb "github.com/orkhanrustamli/pcbook/pcbook_proto/go"
+)
+
+const defaultShards = 32
+
+// ShardedLaptopStore spreads laptops over shards by a hash of their ID, each
+// with its own lock, so that writers to different shards never wait on each
+// other. Search holds one shard lock at a time, and only while it copies the
+// matches of that shard out, never while found runs.
+type ShardedLaptopStore struct {
+	shards []*laptopShard
+}
+
+type laptopShard struct {
+	mutex sync.RWMutex
+	data  map[string]*pb.Laptop
+}
+
+// NewShardedLaptopStore creates a store with the given number of shards, or
+// defaultShards if shards is not positive
+func NewShardedLaptopStore(shards int) *ShardedLaptopStore {
+	if shards <= 0 {
+		shards = defaultShards
+	}
+
+	store := &ShardedLaptopStore{
+		shards: make([]*laptopShard, shards),
+	}
+	for i := range store.shards {
+		store.shards[i] = &laptopShard{
+			data: make(map[string]*pb.Laptop),
+		}
+	}
+
+	return store
+}
+
+func (store *ShardedLaptopStore) Save(laptop *pb.Laptop) error {
+	tmp, err := deepCopy(laptop)
+	if err != nil {
+		return err
+	}
+
+	shard := store.shard(laptop.GetId())
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+
+	if shard.data[tmp.Id] != nil {
+		return ErrAlreadyExists
+	}
+
+	shard.data[tmp.Id] = tmp
+	return nil
+}
+
+func (store *ShardedLaptopStore) Find(id string) (*pb.Laptop, error) {
+	shard := store.shard(id)
+	shard.mutex.RLock()
+	defer shard.mutex.RUnlock()
+
+	laptop := shard.data[id]
+	if laptop == nil {
+		return nil, nil
+	}
+
+	return deepCopy(laptop)
+}
+
+func (store *ShardedLaptopStore) Delete(id string) error {
+	shard := store.shard(id)
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+
+	if shard.data[id] == nil {
+		return ErrNotFound
+	}
+
+	delete(shard.data, id)
+	return nil
+}
+
+// Search is consistent per shard, but not across shards: a laptop saved while
+// the search runs may or may not be found
+func (store *ShardedLaptopStore) Search(
+	ctx context.Context,
+	filter *pb.Filter,
+	found func(laptop *pb.Laptop) error,
+) (SearchStats, error) {
+	stats := SearchStats{}
+	for _, shard := range store.shards {
+		if err := ctx.Err(); err != nil {
+			log.Println("Context is cancelled or timed out!")
+			return stats, err
+		}
+
+		matches, scanned, err := shard.search(ctx, filter)
+		stats.Scanned += scanned
+		if err != nil {
+			return stats, err
+		}
+
+		for _, laptop := range matches {
+			if err := ctx.Err(); err != nil {
+				log.Println("Context is cancelled or timed out!")
+				return stats, err
+			}
+
+			stats.Matched++
+			if err := found(laptop); err != nil {
+				return stats, err
+			}
+		}
+	}
+
+	return stats, nil
+}
+
+func (store *ShardedLaptopStore) shard(id string) *laptopShard {
+	hash := fnv.New32a()
+	hash.Write([]byte(id))
+
+	return store.shards[hash.Sum32()%uint32(len(store.shards))]
+}
+
+func (shard *laptopShard) search(ctx context.Context, filter *pb.Filter) ([]*pb.Laptop, int, error) {
+	shard.mutex.RLock()
+	defer shard.mutex.RUnlock()
+
+	matches := []*pb.Laptop{}
+	scanned := 0
+	for _, laptop := range shard.data {
+		if err := ctx.Err(); err != nil {
+			log.Println("Context is cancelled or timed out!")
+			return nil, scanned, err
+		}
+
+		scanned++
+		if isQualified(filter, laptop) {
+			other, err := deepCopy(laptop)
+			if err != nil {
+				return nil, scanned, err
+			}
+			matches = append(matches, other)
+		}
+	}
+
+	return matches, scanned, nil
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"github.com/orkhanrustamli/pcbook/genarator"
+	pb "github.com/orkhanrustamli/pcbook/pcbook_proto/go"
+	"google.golang.org/protobuf/proto"
+)
+
+// BenchmarkLaptopStoreMixed runs a mix of 1 search, 10 saves and 89 finds
+// out of every 100 operations against a store of 1000 laptops. Neither store
+// holds a lock while found runs, so it compares how saves and finds contend
+// on the single lock of InMemoryLaptopStore and on the per-shard locks of
+// ShardedLaptopStore, along with the cost of taking a search snapshot.
+func BenchmarkLaptopStoreMixed(b *testing.B) {
+	stores := []struct {
+		name     string
+		newStore func() LaptopStore
+	}{
+		{
+			name:     "memory",
+			newStore: func() LaptopStore { return NewInMemoryLaptopStore() },
+		},
+		{
+			name:     "sharded",
+			newStore: func() LaptopStore { return NewShardedLaptopStore(0) },
+		},
+	}
+
+	for _, tc := range stores {
+		tc := tc
+		b.Run(tc.name, func(b *testing.B) {
+			store := tc.newStore()
+
+			ids := make([]string, 1000)
+			for i := range ids {
+				laptop := genarator.NewLaptop()
+				if err := store.Save(laptop); err != nil {
+					b.Fatal(err)
+				}
+				ids[i] = laptop.GetId()
+			}
+
+			filter := &pb.Filter{MaxPriceUsd: 2000}
+			var counter uint64
+
+			b.ResetTimer()
+			b.RunParallel(func(parallel *testing.PB) {
+				for parallel.Next() {
+					i := atomic.AddUint64(&counter, 1)
+					switch {
+					case i%100 == 0:
+						_, err := store.Search(context.Background(), filter, func(laptop *pb.Laptop) error {
+							// stands in for stream.Send, which marshals the laptop
+							_, err := proto.Marshal(laptop)
+							return err
+						})
+						if err != nil {
+							b.Error(err)
+						}
+					case i%100 <= 10:
+						if err := store.Save(genarator.NewLaptop()); err != nil {
+							b.Error(err)
+						}
+					default:
+						if _, err := store.Find(ids[i%uint64(len(ids))]); err != nil {
+							b.Error(err)
+						}
+					}
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"math"
+	"sort"
+
+	"github.com/orkhanrustamli/pcbook/memory"
+	pb "github.com/orkhanrustamli/pcbook/pcbook_proto/go"
+)
+
+const defaultSimilarLaptops = 5
+
+type SimilarLaptop struct {
+	Laptop   *pb.Laptop
+	Distance float64
+}
+
+// FindSimilarLaptops returns the k laptops of the catalog that are nearest to
+// the reference and qualify for the filter. Every dimension of the spec vector
+// is scaled to [0, 1] over the whole catalog before measuring the euclidean
+// distance, so that price does not dwarf screen size.
+func FindSimilarLaptops(reference *pb.Laptop, catalog []*pb.Laptop, filter *pb.Filter, k int) []*SimilarLaptop {
+	if k <= 0 {
+		k = defaultSimilarLaptops
+	}
+
+	vectors := make([][]float64, len(catalog))
+	for i, laptop := range catalog {
+		vectors[i] = specVector(laptop)
+	}
+
+	referenceVector := specVector(reference)
+	low := append([]float64{}, referenceVector...)
+	high := append([]float64{}, referenceVector...)
+	for _, vector := range vectors {
+		for d, value := range vector {
+			low[d] = math.Min(low[d], value)
+			high[d] = math.Max(high[d], value)
+		}
+	}
+
+	similar := []*SimilarLaptop{}
+	for i, laptop := range catalog {
+		if laptop.GetId() == reference.GetId() || !isQualified(filter, laptop) {
+			continue
+		}
+
+		sum := 0.0
+		for d := range referenceVector {
+			delta := scale(vectors[i][d], low[d], high[d]) - scale(referenceVector[d], low[d], high[d])
+			sum += delta * delta
+		}
+
+		similar = append(similar, &SimilarLaptop{
+			Laptop:   laptop,
+			Distance: math.Sqrt(sum),
+		})
+	}
+
+	sort.SliceStable(similar, func(i, j int) bool {
+		return similar[i].Distance < similar[j].Distance
+	})
+
+	if len(similar) > k {
+		similar = similar[:k]
+	}
+
+	return similar
+}
+
+func specVector(laptop *pb.Laptop) []float64 {
+	storage := 0.0
+	for _, drive := range laptop.GetStorages() {
+		storage += memoryBits(drive.GetMemory())
+	}
+
+	gpuMemory := 0.0
+	for _, gpu := range laptop.GetGpu() {
+		gpuMemory += memoryBits(gpu.GetMemory())
+	}
+
+	return []float64{
+		float64(laptop.GetCpu().GetNumberCores()),
+		laptop.GetCpu().GetMinGhz(),
+		memoryBits(laptop.GetRam()),
+		storage,
+		gpuMemory,
+		float64(laptop.GetScreen().GetSizeInch()),
+		laptop.GetPriceUsd(),
+	}
+}
+
+func memoryBits(value *pb.Memory) float64 {
+	bits, err := memory.In(value, pb.Memory_BIT)
+	if err != nil {
+		return 0
+	}
+
+	return bits
+}
+
+func scale(value, low, high float64) float64 {
+	if high <= low {
+		return 0
+	}
+
+	return (value - low) / (high - low)
+}
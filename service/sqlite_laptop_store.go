@@ -0,0 +1,272 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/orkhanrustamli/pcbook/memory"
+	pb "github.com/orkhanrustamli/pcbook/pcbook_proto/go"
+	"google.golang.org/protobuf/proto"
+
+	_ "modernc.org/sqlite"
+)
+
+// sqliteMigrations are applied in order, each one exactly once. Append new
+// migrations to the end, never edit one that has been released.
+var sqliteMigrations = []string{
+	`CREATE TABLE laptops (
+		id TEXT PRIMARY KEY,
+		brand TEXT NOT NULL,
+		name TEXT NOT NULL,
+		price_usd REAL NOT NULL,
+		cpu_cores INTEGER NOT NULL,
+		cpu_min_ghz REAL NOT NULL,
+		ram_bits REAL,
+		created_at INTEGER NOT NULL,
+		data BLOB NOT NULL
+	)`,
+	`CREATE INDEX laptops_price_usd ON laptops (price_usd)`,
+	`CREATE INDEX laptops_brand ON laptops (brand COLLATE NOCASE)`,
+}
+
+type SQLiteLaptopStore struct {
+	db *sql.DB
+}
+
+func NewSQLiteLaptopStore(path string) (*SQLiteLaptopStore, error) {
+	dsn := fmt.Sprintf("file:%s?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)", path)
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open sqlite database: %v", err)
+	}
+
+	store := &SQLiteLaptopStore{db}
+	if err := store.migrate(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return store, nil
+}
+
+func (store *SQLiteLaptopStore) Close() error {
+	return store.db.Close()
+}
+
+func (store *SQLiteLaptopStore) migrate() error {
+	_, err := store.db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (version INTEGER PRIMARY KEY)`)
+	if err != nil {
+		return fmt.Errorf("cannot create migrations table: %v", err)
+	}
+
+	var version int
+	err = store.db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version)
+	if err != nil {
+		return fmt.Errorf("cannot read schema version: %v", err)
+	}
+
+	for i := version; i < len(sqliteMigrations); i++ {
+		tx, err := store.db.Begin()
+		if err != nil {
+			return fmt.Errorf("cannot begin migration %d: %v", i+1, err)
+		}
+
+		if _, err := tx.Exec(sqliteMigrations[i]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("cannot apply migration %d: %v", i+1, err)
+		}
+
+		if _, err := tx.Exec(`INSERT INTO schema_migrations (version) VALUES (?)`, i+1); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("cannot record migration %d: %v", i+1, err)
+		}
+
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("cannot commit migration %d: %v", i+1, err)
+		}
+
+		log.Printf("Applied sqlite migration %d", i+1)
+	}
+
+	return nil
+}
+
+func (store *SQLiteLaptopStore) Save(laptop *pb.Laptop) error {
+	data, err := proto.Marshal(laptop)
+	if err != nil {
+		return fmt.Errorf("cannot marshal laptop: %v", err)
+	}
+
+	res, err := store.db.Exec(
+		`INSERT INTO laptops (id, brand, name, price_usd, cpu_cores, cpu_min_ghz, ram_bits, created_at, data)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO NOTHING`,
+		laptop.GetId(),
+		laptop.GetBrand(),
+		laptop.GetName(),
+		laptop.GetPriceUsd(),
+		laptop.GetCpu().GetNumberCores(),
+		laptop.GetCpu().GetMinGhz(),
+		sqliteRamBits(laptop.GetRam()),
+		laptop.GetCreatedAt().AsTime().UnixNano(),
+		data,
+	)
+	if err != nil {
+		return fmt.Errorf("cannot insert laptop: %v", err)
+	}
+
+	inserted, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("cannot insert laptop: %v", err)
+	}
+	if inserted == 0 {
+		return ErrAlreadyExists
+	}
+
+	return nil
+}
+
+func (store *SQLiteLaptopStore) Find(id string) (*pb.Laptop, error) {
+	var data []byte
+	err := store.db.QueryRow(`SELECT data FROM laptops WHERE id = ?`, id).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot find laptop: %v", err)
+	}
+
+	return unmarshalLaptop(data)
+}
+
+func (store *SQLiteLaptopStore) Delete(id string) error {
+	res, err := store.db.Exec(`DELETE FROM laptops WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("cannot delete laptop: %v", err)
+	}
+
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("cannot delete laptop: %v", err)
+	}
+	if deleted == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// Search narrows the candidates with SQL and then applies isQualified to
+// each row, so that exact memory comparison and fuzzy matching behave as in
+// InMemoryLaptopStore. Scanned counts the rows the query returned, not the
+// rows SQLite skipped.
+func (store *SQLiteLaptopStore) Search(
+	ctx context.Context,
+	filter *pb.Filter,
+	found func(laptop *pb.Laptop) error,
+) (SearchStats, error) {
+	stats := SearchStats{}
+	if err := ctx.Err(); err != nil {
+		return stats, err
+	}
+
+	// The context is checked for every row instead of being handed to the
+	// driver, whose interrupt goroutine races with closing the database
+	where, args := sqliteWhere(filter)
+	rows, err := store.db.Query(`SELECT data FROM laptops`+where+` ORDER BY rowid`, args...)
+	if err != nil {
+		return stats, fmt.Errorf("cannot search laptops: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err := ctx.Err(); err != nil {
+			log.Println("Context is cancelled or timed out!")
+			return stats, err
+		}
+
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return stats, fmt.Errorf("cannot read laptop: %v", err)
+		}
+
+		laptop, err := unmarshalLaptop(data)
+		if err != nil {
+			return stats, err
+		}
+
+		stats.Scanned++
+		if isQualified(filter, laptop) {
+			stats.Matched++
+			if err := found(laptop); err != nil {
+				return stats, err
+			}
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return stats, fmt.Errorf("cannot search laptops: %v", err)
+	}
+
+	return stats, nil
+}
+
+func sqliteWhere(filter *pb.Filter) (string, []interface{}) {
+	if filter == nil {
+		return "", nil
+	}
+
+	conditions := []string{
+		"price_usd <= ?",
+		"cpu_cores >= ?",
+		"cpu_min_ghz >= ?",
+	}
+	args := []interface{}{
+		filter.GetMaxPriceUsd(),
+		filter.GetMinCpuCores(),
+		filter.GetMinCpuGhz(),
+	}
+
+	if filter.GetMinRam() != nil {
+		// ram_bits is approximate, the exact comparison is left to isQualified
+		minRam, _ := memory.In(filter.GetMinRam(), pb.Memory_BIT)
+		conditions = append(conditions, "ram_bits >= ?")
+		args = append(args, minRam*(1-1e-9))
+	}
+
+	if len(filter.GetBrand()) > 0 && filter.GetMaxEditDistance() == 0 {
+		conditions = append(conditions, "brand = ? COLLATE NOCASE")
+		args = append(args, filter.GetBrand())
+	}
+
+	if filter.GetCreatedAfter() != nil {
+		conditions = append(conditions, "created_at > ?")
+		args = append(args, filter.GetCreatedAfter().AsTime().UnixNano())
+	}
+
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// sqliteRamBits returns nil for an unknown unit, so the laptop never passes a
+// minimum RAM condition
+func sqliteRamBits(ram *pb.Memory) interface{} {
+	bits, err := memory.In(ram, pb.Memory_BIT)
+	if err != nil {
+		return nil
+	}
+
+	return bits
+}
+
+func unmarshalLaptop(data []byte) (*pb.Laptop, error) {
+	laptop := &pb.Laptop{}
+	if err := proto.Unmarshal(data, laptop); err != nil {
+		return nil, fmt.Errorf("cannot unmarshal laptop: %v", err)
+	}
+
+	return laptop, nil
+}
@@ -0,0 +1,113 @@
+package service_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/orkhanrustamli/pcbook/service"
+	"github.com/orkhanrustamli/pcbook/service/storetest"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestLaptopStoreConformance(t *testing.T) {
+	t.Parallel()
+
+	t.Run("memory", func(t *testing.T) {
+		storetest.TestLaptopStore(t, func(t *testing.T) service.LaptopStore {
+			return service.NewInMemoryLaptopStore()
+		})
+	})
+
+	t.Run("sharded", func(t *testing.T) {
+		storetest.TestLaptopStore(t, func(t *testing.T) service.LaptopStore {
+			return service.NewShardedLaptopStore(4)
+		})
+	})
+
+	t.Run("cached", func(t *testing.T) {
+		storetest.TestLaptopStore(t, func(t *testing.T) service.LaptopStore {
+			return service.NewCachedLaptopStore(service.NewInMemoryLaptopStore(), 2, time.Minute)
+		})
+	})
+
+	t.Run("journal", func(t *testing.T) {
+		storetest.TestLaptopStore(t, func(t *testing.T) service.LaptopStore {
+			store, err := service.NewJournaledLaptopStore(service.JournalOptions{
+				Dir:   t.TempDir(),
+				Fsync: service.FsyncNever,
+			})
+			require.NoError(t, err)
+			t.Cleanup(func() { store.Close() })
+
+			return store
+		})
+	})
+
+	t.Run("sqlite", func(t *testing.T) {
+		storetest.TestLaptopStore(t, func(t *testing.T) service.LaptopStore {
+			store, err := service.NewSQLiteLaptopStore(filepath.Join(t.TempDir(), "pcbook.db"))
+			require.NoError(t, err)
+			t.Cleanup(func() { store.Close() })
+
+			return store
+		})
+	})
+
+	t.Run("bbolt", func(t *testing.T) {
+		storetest.TestLaptopStore(t, func(t *testing.T) service.LaptopStore {
+			return service.NewBoltLaptopStore(openTestBoltDB(t))
+		})
+	})
+}
+
+func TestImageStoreConformance(t *testing.T) {
+	t.Parallel()
+
+	t.Run("disk", func(t *testing.T) {
+		storetest.TestImageStore(t, func(t *testing.T) service.ImageStore {
+			return service.NewDiskImageStore(t.TempDir())
+		})
+	})
+}
+
+func TestRatingStoreConformance(t *testing.T) {
+	t.Parallel()
+
+	t.Run("memory", func(t *testing.T) {
+		storetest.TestRatingStore(t, func(t *testing.T) service.RatingStore {
+			return service.NewInMemoryRatingStore()
+		})
+	})
+
+	t.Run("bbolt", func(t *testing.T) {
+		storetest.TestRatingStore(t, func(t *testing.T) service.RatingStore {
+			return service.NewBoltRatingStore(openTestBoltDB(t))
+		})
+	})
+}
+
+func TestUserStoreConformance(t *testing.T) {
+	t.Parallel()
+
+	t.Run("memory", func(t *testing.T) {
+		storetest.TestUserStore(t, func(t *testing.T) service.UserStore {
+			return service.NewInMemoryUserStore()
+		})
+	})
+
+	t.Run("bbolt", func(t *testing.T) {
+		storetest.TestUserStore(t, func(t *testing.T) service.UserStore {
+			return service.NewBoltUserStore(openTestBoltDB(t))
+		})
+	})
+}
+
+func openTestBoltDB(t *testing.T) *bolt.DB {
+	db, err := service.OpenBoltDB(filepath.Join(t.TempDir(), "pcbook.bolt"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	return db
+}
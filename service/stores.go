@@ -0,0 +1,80 @@
+package service
+
+import (
+	"fmt"
+	"time"
+)
+
+type StoreOptions struct {
+	// Type is one of memory, sharded, journal, sqlite or bbolt
+	Type string
+	// Path is the database file of the sqlite and bbolt stores, or the
+	// directory of the journal store
+	Path             string
+	ImageFolder      string
+	Fsync            FsyncPolicy
+	SnapshotInterval time.Duration
+}
+
+// Stores groups the stores of one backend. Only the bbolt backend persists
+// ratings and users, the others keep them in memory.
+type Stores struct {
+	Laptops LaptopStore
+	Ratings RatingStore
+	Users   UserStore
+	Images  ImageStore
+
+	close func() error
+}
+
+func OpenStores(options StoreOptions) (*Stores, error) {
+	stores := &Stores{
+		Ratings: NewInMemoryRatingStore(),
+		Users:   NewInMemoryUserStore(),
+		Images:  NewDiskImageStore(options.ImageFolder),
+		close:   func() error { return nil },
+	}
+
+	switch options.Type {
+	case "memory":
+		stores.Laptops = NewInMemoryLaptopStore()
+	case "sharded":
+		stores.Laptops = NewShardedLaptopStore(0)
+	case "journal":
+		laptopStore, err := NewJournaledLaptopStore(JournalOptions{
+			Dir:              options.Path,
+			Fsync:            options.Fsync,
+			FsyncInterval:    time.Second,
+			SnapshotInterval: options.SnapshotInterval,
+		})
+		if err != nil {
+			return nil, err
+		}
+		stores.Laptops = laptopStore
+		stores.close = laptopStore.Close
+	case "sqlite":
+		laptopStore, err := NewSQLiteLaptopStore(options.Path)
+		if err != nil {
+			return nil, err
+		}
+		stores.Laptops = laptopStore
+		stores.close = laptopStore.Close
+	case "bbolt":
+		db, err := OpenBoltDB(options.Path)
+		if err != nil {
+			return nil, err
+		}
+		stores.Laptops = NewBoltLaptopStore(db)
+		stores.Ratings = NewBoltRatingStore(db)
+		stores.Users = NewBoltUserStore(db)
+		stores.close = db.Close
+	default:
+		return nil, fmt.Errorf("unknown store type: %s", options.Type)
+	}
+
+	return stores, nil
+}
+
+func (stores *Stores) Close() error {
+	return stores.close()
+}
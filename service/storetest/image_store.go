@@ -0,0 +1,185 @@
+package storetest
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/orkhanrustamli/pcbook/genarator"
+	"github.com/orkhanrustamli/pcbook/service"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImageStore(t *testing.T, newStore func(t *testing.T) service.ImageStore) {
+	t.Run("Save", func(t *testing.T) {
+		store := newStore(t)
+
+		imageId, err := store.Save(genarator.NewLaptop().GetId(), ".jpg", *bytes.NewBufferString("image"))
+		require.NoError(t, err)
+		require.NotEmpty(t, imageId)
+	})
+
+	t.Run("Find", func(t *testing.T) {
+		store := newStore(t)
+
+		laptopId := genarator.NewLaptop().GetId()
+		imageId, err := store.Save(laptopId, ".jpg", *bytes.NewBufferString("image"))
+		require.NoError(t, err)
+
+		image, err := store.Find(imageId)
+		require.NoError(t, err)
+		require.NotNil(t, image)
+		require.Equal(t, imageId, image.Id)
+		require.Equal(t, laptopId, image.LaptopId)
+		require.Equal(t, "image/jpeg", image.Type)
+		require.EqualValues(t, len("image"), image.Size)
+
+		data, err := os.ReadFile(image.Path)
+		require.NoError(t, err)
+		require.Equal(t, "image", string(data))
+
+		// the info returned is a copy
+		image.LaptopId = "other"
+		image, err = store.Find(imageId)
+		require.NoError(t, err)
+		require.Equal(t, laptopId, image.LaptopId)
+
+		require.NoError(t, store.Delete(imageId))
+		image, err = store.Find(imageId)
+		require.NoError(t, err)
+		require.Nil(t, image)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		store := newStore(t)
+
+		imageId, err := store.Save(genarator.NewLaptop().GetId(), ".jpg", *bytes.NewBufferString("image"))
+		require.NoError(t, err)
+
+		require.NoError(t, store.Delete(imageId))
+		require.ErrorIs(t, store.Delete(imageId), service.ErrNotFound)
+	})
+
+	t.Run("ListImport", func(t *testing.T) {
+		store := newStore(t)
+
+		laptopId := genarator.NewLaptop().GetId()
+		imageId, err := store.Save(laptopId, ".jpg", *bytes.NewBufferString("image"))
+		require.NoError(t, err)
+
+		imported := &service.ImageInfo{
+			Id:         "imported",
+			LaptopId:   laptopId,
+			Type:       ".png",
+			UploadedAt: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		}
+		require.NoError(t, store.Import(imported, strings.NewReader("imported image")))
+		require.ErrorIs(t, store.Import(imported, strings.NewReader("other")), service.ErrAlreadyExists)
+
+		images := make(map[string]*service.ImageInfo)
+		err = store.List(func(image *service.ImageInfo) error {
+			images[image.Id] = image
+			return nil
+		})
+		require.NoError(t, err)
+		require.Len(t, images, 2)
+
+		require.Equal(t, laptopId, images[imageId].LaptopId)
+		require.Equal(t, "image/jpeg", images[imageId].Type)
+		require.EqualValues(t, len("image"), images[imageId].Size)
+		require.False(t, images[imageId].UploadedAt.IsZero())
+
+		require.Equal(t, laptopId, images["imported"].LaptopId)
+		require.Equal(t, "image/png", images["imported"].Type)
+		require.EqualValues(t, len("imported image"), images["imported"].Size)
+		require.True(t, imported.UploadedAt.Equal(images["imported"].UploadedAt))
+	})
+
+	t.Run("Variants", func(t *testing.T) {
+		store := newStore(t)
+
+		imageId, err := store.Save(genarator.NewLaptop().GetId(), ".png", *bytes.NewBufferString("image"))
+		require.NoError(t, err)
+
+		require.ErrorIs(t, store.SaveVariant("unknown", "small", strings.NewReader("small")), service.ErrNotFound)
+		require.Error(t, store.SaveVariant(imageId, "../small", strings.NewReader("small")))
+
+		variant, err := store.FindVariant(imageId, "small")
+		require.NoError(t, err)
+		require.Nil(t, variant)
+
+		require.NoError(t, store.SaveVariant(imageId, "small", strings.NewReader("small image")))
+		require.NoError(t, store.SaveVariant(imageId, "large", strings.NewReader("large image")))
+		require.NoError(t, store.SaveVariant(imageId, "small", strings.NewReader("small")))
+
+		variant, err = store.FindVariant(imageId, "small")
+		require.NoError(t, err)
+		require.NotNil(t, variant)
+		require.Equal(t, imageId, variant.Id)
+		require.Equal(t, "image/png", variant.Type)
+		require.EqualValues(t, len("small"), variant.Size)
+
+		data, err := os.ReadFile(variant.Path)
+		require.NoError(t, err)
+		require.Equal(t, "small", string(data))
+
+		// the original is left as it was
+		image, err := store.Find(imageId)
+		require.NoError(t, err)
+		require.EqualValues(t, len("image"), image.Size)
+		require.Equal(t, map[string]int64{"small": 5, "large": 11}, image.Variants)
+
+		require.NoError(t, store.Delete(imageId))
+		require.NoFileExists(t, variant.Path)
+		variant, err = store.FindVariant(imageId, "small")
+		require.NoError(t, err)
+		require.Nil(t, variant)
+	})
+
+	t.Run("UnsupportedType", func(t *testing.T) {
+		store := newStore(t)
+
+		laptopId := genarator.NewLaptop().GetId()
+		_, err := store.Save(laptopId, ".exe", *bytes.NewBufferString("image"))
+		require.ErrorIs(t, err, service.ErrUnsupportedImageType)
+
+		imported := &service.ImageInfo{
+			Id:       "imported",
+			LaptopId: laptopId,
+			Type:     "../x",
+		}
+		require.ErrorIs(t, store.Import(imported, strings.NewReader("image")), service.ErrUnsupportedImageType)
+
+		images := 0
+		require.NoError(t, store.List(func(image *service.ImageInfo) error {
+			images++
+			return nil
+		}))
+		require.Zero(t, images)
+	})
+
+	t.Run("ConcurrentSave", func(t *testing.T) {
+		store := newStore(t)
+
+		laptopId := genarator.NewLaptop().GetId()
+		imageIds := make(chan string, concurrency)
+		parallel(concurrency, func(i int) {
+			imageId, err := store.Save(laptopId, ".png", *bytes.NewBufferString("image"))
+			if err != nil {
+				t.Errorf("cannot save image: %v", err)
+				return
+			}
+			imageIds <- imageId
+		})
+		close(imageIds)
+
+		unique := make(map[string]bool)
+		for imageId := range imageIds {
+			require.NotEmpty(t, imageId)
+			unique[imageId] = true
+		}
+		require.Len(t, unique, concurrency)
+	})
+}
@@ -0,0 +1,279 @@
+// Package storetest checks that store implementations honor the contracts
+// the servers in package service rely on. A new backend runs the suite from
+// its own tests:
+//
+//	func TestMyLaptopStore(t *testing.T) {
+//		storetest.TestLaptopStore(t, func(t *testing.T) service.LaptopStore {
+//			return NewMyLaptopStore(t.TempDir())
+//		})
+//	}
+//
+// newStore must return an empty store every time it is called. Run the tests
+// with -race to make the concurrent scenarios meaningful.
+package storetest
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/orkhanrustamli/pcbook/genarator"
+	pb "github.com/orkhanrustamli/pcbook/pcbook_proto/go"
+	"github.com/orkhanrustamli/pcbook/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+const concurrency = 16
+
+func TestLaptopStore(t *testing.T, newStore func(t *testing.T) service.LaptopStore) {
+	t.Run("FindMissing", func(t *testing.T) {
+		store := newStore(t)
+
+		laptop, err := store.Find(genarator.NewLaptop().GetId())
+		require.NoError(t, err)
+		require.Nil(t, laptop)
+	})
+
+	t.Run("SaveFind", func(t *testing.T) {
+		store := newStore(t)
+
+		laptop := genarator.NewLaptop()
+		require.NoError(t, store.Save(laptop))
+
+		other, err := store.Find(laptop.GetId())
+		require.NoError(t, err)
+		require.True(t, proto.Equal(laptop, other))
+	})
+
+	t.Run("SaveDuplicate", func(t *testing.T) {
+		store := newStore(t)
+
+		laptop := genarator.NewLaptop()
+		require.NoError(t, store.Save(laptop))
+
+		duplicate := genarator.NewLaptop()
+		duplicate.Id = laptop.GetId()
+		require.ErrorIs(t, store.Save(duplicate), service.ErrAlreadyExists)
+
+		other, err := store.Find(laptop.GetId())
+		require.NoError(t, err)
+		require.True(t, proto.Equal(laptop, other))
+	})
+
+	t.Run("SaveCopies", func(t *testing.T) {
+		store := newStore(t)
+
+		laptop := genarator.NewLaptop()
+		want := proto.Clone(laptop)
+		require.NoError(t, store.Save(laptop))
+		laptop.Brand = "changed after save"
+
+		other, err := store.Find(laptop.GetId())
+		require.NoError(t, err)
+		require.True(t, proto.Equal(want, other))
+	})
+
+	t.Run("FindCopies", func(t *testing.T) {
+		store := newStore(t)
+
+		laptop := genarator.NewLaptop()
+		require.NoError(t, store.Save(laptop))
+
+		other, err := store.Find(laptop.GetId())
+		require.NoError(t, err)
+		other.Brand = "changed after find"
+
+		other, err = store.Find(laptop.GetId())
+		require.NoError(t, err)
+		require.True(t, proto.Equal(laptop, other))
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		store := newStore(t)
+
+		laptops := saveLaptops(t, store, 3)
+		require.NoError(t, store.Delete(laptops[1].GetId()))
+		require.ErrorIs(t, store.Delete(laptops[1].GetId()), service.ErrNotFound)
+
+		other, err := store.Find(laptops[1].GetId())
+		require.NoError(t, err)
+		require.Nil(t, other)
+
+		found := []string{}
+		_, err = store.Search(context.Background(), nil, func(laptop *pb.Laptop) error {
+			found = append(found, laptop.GetId())
+			return nil
+		})
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{laptops[0].GetId(), laptops[2].GetId()}, found)
+
+		// a deleted ID can be used again
+		require.NoError(t, store.Save(laptops[1]))
+	})
+
+	t.Run("SearchAll", func(t *testing.T) {
+		store := newStore(t)
+
+		laptops := saveLaptops(t, store, 5)
+
+		found := make(map[string]*pb.Laptop)
+		stats, err := store.Search(context.Background(), nil, func(laptop *pb.Laptop) error {
+			found[laptop.GetId()] = laptop
+			return nil
+		})
+		require.NoError(t, err)
+		require.Len(t, found, len(laptops))
+		require.Equal(t, len(laptops), stats.Matched)
+		require.Equal(t, len(laptops), stats.Scanned)
+
+		for _, laptop := range laptops {
+			require.True(t, proto.Equal(laptop, found[laptop.GetId()]))
+		}
+	})
+
+	t.Run("SearchFilter", func(t *testing.T) {
+		store := newStore(t)
+
+		laptops := saveLaptops(t, store, 6)
+		want := make(map[string]bool)
+		for i, laptop := range laptops {
+			if i%2 == 0 {
+				want[laptop.GetId()] = true
+			}
+		}
+
+		filter := &pb.Filter{MaxPriceUsd: 2000}
+
+		found := make(map[string]bool)
+		stats, err := store.Search(context.Background(), filter, func(laptop *pb.Laptop) error {
+			require.LessOrEqual(t, laptop.GetPriceUsd(), filter.GetMaxPriceUsd())
+			found[laptop.GetId()] = true
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, want, found)
+		require.Equal(t, len(want), stats.Matched)
+		require.GreaterOrEqual(t, stats.Scanned, stats.Matched)
+	})
+
+	t.Run("SearchStopsOnError", func(t *testing.T) {
+		store := newStore(t)
+
+		saveLaptops(t, store, 3)
+
+		errStop := errors.New("stop")
+		calls := 0
+		_, err := store.Search(context.Background(), nil, func(laptop *pb.Laptop) error {
+			calls++
+			return errStop
+		})
+		require.ErrorIs(t, err, errStop)
+		require.Equal(t, 1, calls)
+	})
+
+	t.Run("SearchCanceled", func(t *testing.T) {
+		store := newStore(t)
+
+		saveLaptops(t, store, 3)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		calls := 0
+		_, err := store.Search(ctx, nil, func(laptop *pb.Laptop) error {
+			calls++
+			cancel()
+			return nil
+		})
+		require.ErrorIs(t, err, context.Canceled)
+		require.Equal(t, 1, calls)
+
+		_, err = store.Search(ctx, nil, func(laptop *pb.Laptop) error {
+			t.Error("found called after the context was canceled")
+			return nil
+		})
+		require.ErrorIs(t, err, context.Canceled)
+	})
+
+	t.Run("ConcurrentSaveDuplicate", func(t *testing.T) {
+		store := newStore(t)
+
+		laptop := genarator.NewLaptop()
+		errs := make(chan error, concurrency)
+		parallel(concurrency, func(i int) {
+			errs <- store.Save(proto.Clone(laptop).(*pb.Laptop))
+		})
+		close(errs)
+
+		saved := 0
+		for err := range errs {
+			if err == nil {
+				saved++
+				continue
+			}
+			require.ErrorIs(t, err, service.ErrAlreadyExists)
+		}
+		require.Equal(t, 1, saved)
+	})
+
+	t.Run("ConcurrentAccess", func(t *testing.T) {
+		store := newStore(t)
+
+		existing := saveLaptops(t, store, 3)
+		laptops := make([]*pb.Laptop, concurrency)
+		for i := range laptops {
+			laptops[i] = genarator.NewLaptop()
+		}
+
+		parallel(concurrency, func(i int) {
+			assert.NoError(t, store.Save(laptops[i]))
+
+			other, err := store.Find(existing[i%len(existing)].GetId())
+			assert.NoError(t, err)
+			assert.NotNil(t, other)
+
+			_, err = store.Search(context.Background(), nil, func(laptop *pb.Laptop) error {
+				return nil
+			})
+			assert.NoError(t, err)
+		})
+
+		for _, laptop := range laptops {
+			other, err := store.Find(laptop.GetId())
+			require.NoError(t, err)
+			require.True(t, proto.Equal(laptop, other))
+		}
+	})
+}
+
+// parallel runs do on n goroutines at once. Failures inside do must be
+// reported with assert, since require cannot stop a test from another goroutine.
+func parallel(n int, do func(i int)) {
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			do(i)
+		}(i)
+	}
+	wg.Wait()
+}
+
+// saveLaptops stores n laptops, alternating prices below and above 2000 USD
+func saveLaptops(t *testing.T, store service.LaptopStore, n int) []*pb.Laptop {
+	laptops := make([]*pb.Laptop, n)
+	for i := range laptops {
+		laptop := genarator.NewLaptop()
+		laptop.PriceUsd = 1500
+		if i%2 == 1 {
+			laptop.PriceUsd = 2500
+		}
+
+		require.NoError(t, store.Save(laptop))
+		laptops[i] = laptop
+	}
+
+	return laptops
+}
@@ -0,0 +1,136 @@
+package storetest
+
+import (
+	"testing"
+
+	"github.com/orkhanrustamli/pcbook/genarator"
+	"github.com/orkhanrustamli/pcbook/service"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRatingStore(t *testing.T, newStore func(t *testing.T) service.RatingStore) {
+	t.Run("FindMissing", func(t *testing.T) {
+		store := newStore(t)
+
+		require.Nil(t, store.Find(genarator.NewLaptop().GetId()))
+	})
+
+	t.Run("RateFind", func(t *testing.T) {
+		store := newStore(t)
+
+		laptopId := genarator.NewLaptop().GetId()
+		otherId := genarator.NewLaptop().GetId()
+
+		rating, err := store.Rate(laptopId, 8)
+		require.NoError(t, err)
+		require.NotNil(t, rating)
+		require.Equal(t, 1, rating.Count())
+		require.Equal(t, 8.0, rating.Sum())
+
+		rating, err = store.Rate(laptopId, 5)
+		require.NoError(t, err)
+		require.NotNil(t, rating)
+		require.Equal(t, 2, rating.Count())
+		require.Equal(t, 13.0, rating.Sum())
+
+		store.Rate(otherId, 1)
+
+		rating = store.Find(laptopId)
+		require.NotNil(t, rating)
+		require.Equal(t, 2, rating.Count())
+		require.Equal(t, 13.0, rating.Sum())
+	})
+
+	t.Run("RateReturnsSnapshot", func(t *testing.T) {
+		store := newStore(t)
+
+		laptopId := genarator.NewLaptop().GetId()
+		first, err := store.Rate(laptopId, 4)
+		require.NoError(t, err)
+		found := store.Find(laptopId)
+		store.Rate(laptopId, 6)
+
+		require.Equal(t, 1, first.Count())
+		require.Equal(t, 4.0, first.Sum())
+		require.Equal(t, 1, found.Count())
+		require.Equal(t, 4.0, found.Sum())
+	})
+
+	t.Run("Unrate", func(t *testing.T) {
+		store := newStore(t)
+
+		laptopId := genarator.NewLaptop().GetId()
+		rating, err := store.Unrate(laptopId, 3)
+		require.NoError(t, err)
+		require.Nil(t, rating)
+
+		store.Rate(laptopId, 3)
+		store.Rate(laptopId, 9)
+
+		rating, err = store.Unrate(laptopId, 9)
+		require.NoError(t, err)
+		require.NotNil(t, rating)
+		require.Equal(t, 1, rating.Count())
+		require.Equal(t, 3.0, rating.Sum())
+
+		rating, err = store.Unrate(laptopId, 3)
+		require.NoError(t, err)
+		require.Nil(t, rating)
+		require.Nil(t, store.Find(laptopId))
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		store := newStore(t)
+
+		laptopId := genarator.NewLaptop().GetId()
+		require.ErrorIs(t, store.Delete(laptopId), service.ErrNotFound)
+
+		store.Rate(laptopId, 3)
+		require.NoError(t, store.Delete(laptopId))
+		require.Nil(t, store.Find(laptopId))
+	})
+
+	t.Run("ListSet", func(t *testing.T) {
+		store := newStore(t)
+
+		laptopIds := []string{"b", "a", "c"}
+		for i, laptopId := range laptopIds {
+			require.NoError(t, store.Set(laptopId, service.NewRating(i+1, float64(10*(i+1)))))
+		}
+		store.Rate("a", 4)
+
+		listed := []string{}
+		err := store.List(func(laptopId string, rating *service.Rating) error {
+			listed = append(listed, laptopId)
+			if laptopId == "a" {
+				require.Equal(t, 3, rating.Count())
+				require.Equal(t, 24.0, rating.Sum())
+			}
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, []string{"a", "b", "c"}, listed)
+	})
+
+	t.Run("ConcurrentRate", func(t *testing.T) {
+		store := newStore(t)
+
+		laptopId := genarator.NewLaptop().GetId()
+		parallel(concurrency, func(i int) {
+			rating, err := store.Rate(laptopId, 2)
+			if err != nil {
+				t.Errorf("cannot rate laptop: %v", err)
+				return
+			}
+
+			// reading the returned rating must not race with other writers
+			_ = rating.Count()
+			_ = store.Find(laptopId).Sum()
+		})
+
+		rating := store.Find(laptopId)
+		require.NotNil(t, rating)
+		require.Equal(t, concurrency, rating.Count())
+		require.Equal(t, 2.0*concurrency, rating.Sum())
+	})
+}
@@ -0,0 +1,122 @@
+package storetest
+
+import (
+	"testing"
+
+	"github.com/orkhanrustamli/pcbook/service"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserStore(t *testing.T, newStore func(t *testing.T) service.UserStore) {
+	t.Run("FindMissing", func(t *testing.T) {
+		store := newStore(t)
+
+		require.Nil(t, store.Find("nobody"))
+	})
+
+	t.Run("SaveFind", func(t *testing.T) {
+		store := newStore(t)
+
+		user := newUser(t, "user1", "user")
+		user.Tenant = "acme"
+		require.NoError(t, store.Save(user))
+
+		other := store.Find(user.Username)
+		require.Equal(t, user, other)
+		require.True(t, other.IsCorrectPassword("secret"))
+	})
+
+	t.Run("SaveDuplicate", func(t *testing.T) {
+		store := newStore(t)
+
+		user := newUser(t, "user1", "user")
+		require.NoError(t, store.Save(user))
+		require.ErrorIs(t, store.Save(newUser(t, "user1", "admin")), service.ErrAlreadyExists)
+
+		require.Equal(t, user, store.Find(user.Username))
+	})
+
+	t.Run("SaveCopies", func(t *testing.T) {
+		store := newStore(t)
+
+		user := newUser(t, "user1", "user")
+		require.NoError(t, store.Save(user))
+		user.Role = "admin"
+
+		require.Equal(t, "user", store.Find(user.Username).Role)
+	})
+
+	t.Run("FindCopies", func(t *testing.T) {
+		store := newStore(t)
+
+		require.NoError(t, store.Save(newUser(t, "user1", "user")))
+		store.Find("user1").Role = "admin"
+
+		require.Equal(t, "user", store.Find("user1").Role)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		store := newStore(t)
+
+		require.ErrorIs(t, store.Delete("user1"), service.ErrNotFound)
+
+		require.NoError(t, store.Save(newUser(t, "user1", "user")))
+		require.NoError(t, store.Delete("user1"))
+		require.Nil(t, store.Find("user1"))
+	})
+
+	t.Run("List", func(t *testing.T) {
+		store := newStore(t)
+
+		users := []*service.User{
+			newUser(t, "user2", "user"),
+			newUser(t, "admin1", "admin"),
+		}
+		for _, user := range users {
+			require.NoError(t, store.Save(user))
+		}
+
+		listed := []*service.User{}
+		err := store.List(func(user *service.User) error {
+			listed = append(listed, user)
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, []*service.User{users[1], users[0]}, listed)
+	})
+
+	t.Run("ConcurrentSave", func(t *testing.T) {
+		store := newStore(t)
+
+		user := newUser(t, "user1", "user")
+		errs := make(chan error, concurrency)
+		parallel(concurrency, func(i int) {
+			clone, err := user.Clone()
+			if err != nil {
+				errs <- err
+				return
+			}
+
+			errs <- store.Save(clone)
+			store.Find(user.Username)
+		})
+		close(errs)
+
+		saved := 0
+		for err := range errs {
+			if err == nil {
+				saved++
+				continue
+			}
+			require.ErrorIs(t, err, service.ErrAlreadyExists)
+		}
+		require.Equal(t, 1, saved)
+	})
+}
+
+func newUser(t *testing.T, username, role string) *service.User {
+	user, err := service.NewUser(username, "secret", role, "")
+	require.NoError(t, err)
+
+	return user
+}
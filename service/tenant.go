@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+
+	pb "github.com/orkhanrustamli/pcbook/pcbook_proto/go"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// SuperAdminRole acts across tenants. It sees every tenant unless it
+	// names one in the tenant metadata of the request.
+	SuperAdminRole = "superadmin"
+
+	tenantMetadataKey = "tenant"
+)
+
+// tenantScope returns the tenant the request is limited to, or false if it
+// may act on every tenant. Requests without user claims are limited to the
+// default tenant.
+func tenantScope(ctx context.Context) (string, bool) {
+	userClaims, ok := UserClaimsFromContext(ctx)
+	if !ok {
+		return "", true
+	}
+
+	if userClaims.Role != SuperAdminRole {
+		return userClaims.Tenant, true
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get(tenantMetadataKey); len(values) > 0 {
+		return values[0], true
+	}
+
+	return "", false
+}
+
+// ScopeLaptopStore limits store to the tenant of the request. Images and
+// ratings are partitioned through their laptop: they can only be added to,
+// and listed for, a laptop that the scoped store can find.
+func ScopeLaptopStore(ctx context.Context, store LaptopStore) LaptopStore {
+	tenant, scoped := tenantScope(ctx)
+	if !scoped {
+		return store
+	}
+
+	return NewTenantLaptopStore(store, tenant)
+}
+
+// TenantLaptopStore shows only the laptops of one tenant of the underlying
+// store, and assigns new laptops to that tenant. Laptop IDs stay unique
+// across tenants.
+type TenantLaptopStore struct {
+	store  LaptopStore
+	tenant string
+}
+
+func NewTenantLaptopStore(store LaptopStore, tenant string) *TenantLaptopStore {
+	return &TenantLaptopStore{
+		store:  store,
+		tenant: tenant,
+	}
+}
+
+func (store *TenantLaptopStore) Save(laptop *pb.Laptop) error {
+	laptop.Tenant = store.tenant
+	return store.store.Save(laptop)
+}
+
+func (store *TenantLaptopStore) Find(id string) (*pb.Laptop, error) {
+	laptop, err := store.store.Find(id)
+	if err != nil || laptop == nil {
+		return nil, err
+	}
+
+	if laptop.GetTenant() != store.tenant {
+		return nil, nil
+	}
+
+	return laptop, nil
+}
+
+func (store *TenantLaptopStore) Delete(id string) error {
+	laptop, err := store.Find(id)
+	if err != nil {
+		return err
+	}
+	if laptop == nil {
+		return ErrNotFound
+	}
+
+	return store.store.Delete(id)
+}
+
+// Search leaves the filter to the underlying store, so that it can use its
+// indexes, and only checks the tenant of the matches. Scanned is the count of
+// the underlying store, while Matched only counts the laptops of the tenant.
+func (store *TenantLaptopStore) Search(
+	ctx context.Context,
+	filter *pb.Filter,
+	found func(laptop *pb.Laptop) error,
+) (SearchStats, error) {
+	matched := 0
+	stats, err := store.store.Search(ctx, filter, func(laptop *pb.Laptop) error {
+		if laptop.GetTenant() != store.tenant {
+			return nil
+		}
+
+		matched++
+		return found(laptop)
+	})
+	stats.Matched = matched
+
+	return stats, err
+}
@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/orkhanrustamli/pcbook/genarator"
+	pb "github.com/orkhanrustamli/pcbook/pcbook_proto/go"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestTenantLaptopStore(t *testing.T) {
+	t.Parallel()
+
+	store := NewInMemoryLaptopStore()
+	acme := NewTenantLaptopStore(store, "acme")
+	globex := NewTenantLaptopStore(store, "globex")
+
+	laptop1 := genarator.NewLaptop()
+	require.NoError(t, acme.Save(laptop1))
+	require.Equal(t, "acme", laptop1.GetTenant())
+
+	laptop2 := genarator.NewLaptop()
+	require.NoError(t, globex.Save(laptop2))
+
+	found, err := acme.Find(laptop1.GetId())
+	require.NoError(t, err)
+	require.NotNil(t, found)
+
+	found, err = acme.Find(laptop2.GetId())
+	require.NoError(t, err)
+	require.Nil(t, found)
+
+	// IDs are unique across tenants
+	other := genarator.NewLaptop()
+	other.Id = laptop1.GetId()
+	require.ErrorIs(t, globex.Save(other), ErrAlreadyExists)
+
+	ids := []string{}
+	stats, err := globex.Search(context.Background(), nil, func(laptop *pb.Laptop) error {
+		ids = append(ids, laptop.GetId())
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{laptop2.GetId()}, ids)
+	// the laptop of the other tenant is scanned but does not match
+	require.Equal(t, SearchStats{Scanned: 2, Matched: 1}, stats)
+
+	// the filter reaches the underlying store, so that it can use its indexes
+	recording := &filterRecordingStore{LaptopStore: store}
+	filter := &pb.Filter{MaxPriceUsd: laptop2.GetPriceUsd() - 1}
+	stats, err = NewTenantLaptopStore(recording, "globex").Search(context.Background(), filter, func(laptop *pb.Laptop) error {
+		return nil
+	})
+	require.NoError(t, err)
+	require.Same(t, filter, recording.filter)
+	require.Equal(t, SearchStats{Scanned: 2}, stats)
+}
+
+type filterRecordingStore struct {
+	LaptopStore
+	filter *pb.Filter
+}
+
+func (store *filterRecordingStore) Search(
+	ctx context.Context,
+	filter *pb.Filter,
+	found func(laptop *pb.Laptop) error,
+) (SearchStats, error) {
+	store.filter = filter
+	return store.LaptopStore.Search(ctx, filter, found)
+}
+
+func TestServerTenantScope(t *testing.T) {
+	t.Parallel()
+
+	server := NewLaptopServer(NewInMemoryLaptopStore(), NewDiskImageStore(t.TempDir()), NewInMemoryRatingStore())
+
+	acme := ContextWithUserClaims(context.Background(), &UserClaims{Username: "alice", Role: "admin", Tenant: "acme"})
+	globex := ContextWithUserClaims(context.Background(), &UserClaims{Username: "bob", Role: "admin", Tenant: "globex"})
+	superAdmin := ContextWithUserClaims(context.Background(), &UserClaims{Username: "root", Role: SuperAdminRole})
+
+	acmeLaptop := genarator.NewLaptop()
+	_, err := server.CreateLaptop(acme, &pb.CreateLaptopRequest{Laptop: acmeLaptop})
+	require.NoError(t, err)
+
+	_, err = server.SimilarLaptops(globex, &pb.SimilarLaptopsRequest{LaptopId: acmeLaptop.GetId(), K: 1})
+	st, ok := status.FromError(err)
+	require.True(t, ok)
+	require.Equal(t, codes.NotFound, st.Code())
+
+	_, err = server.SimilarLaptops(acme, &pb.SimilarLaptopsRequest{LaptopId: acmeLaptop.GetId(), K: 1})
+	require.NoError(t, err)
+
+	_, err = server.SimilarLaptops(superAdmin, &pb.SimilarLaptopsRequest{LaptopId: acmeLaptop.GetId(), K: 1})
+	require.NoError(t, err)
+
+	// a super-admin naming a tenant acts as that tenant
+	globexLaptop := genarator.NewLaptop()
+	asGlobex := metadata.NewIncomingContext(superAdmin, metadata.Pairs("tenant", "globex"))
+	_, err = server.CreateLaptop(asGlobex, &pb.CreateLaptopRequest{Laptop: globexLaptop})
+	require.NoError(t, err)
+	require.Equal(t, "globex", globexLaptop.GetTenant())
+
+	_, err = server.SimilarLaptops(asGlobex, &pb.SimilarLaptopsRequest{LaptopId: acmeLaptop.GetId(), K: 1})
+	st, _ = status.FromError(err)
+	require.Equal(t, codes.NotFound, st.Code())
+
+	_, err = server.SimilarLaptops(globex, &pb.SimilarLaptopsRequest{LaptopId: globexLaptop.GetId(), K: 1})
+	require.NoError(t, err)
+}
+
+func TestAuthInterceptorTenant(t *testing.T) {
+	t.Parallel()
+
+	jwtManager := NewJWTManager("secret", time.Minute)
+	authInterceptor := NewAuthInterceptor(jwtManager, map[string][]string{"/protected": {"admin"}})
+
+	acme, err := NewUser("acme1", "secret", "admin", "acme")
+	require.NoError(t, err)
+	accessToken, err := jwtManager.Generate(acme.Username, acme.Role, acme.Tenant)
+	require.NoError(t, err)
+
+	withToken := func(token string) context.Context {
+		return metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", token))
+	}
+
+	ctx, err := authInterceptor.authorize(withToken(accessToken), "/public")
+	require.NoError(t, err)
+	tenant, scoped := tenantScope(ctx)
+	require.True(t, scoped)
+	require.Equal(t, "acme", tenant)
+
+	ctx, err = authInterceptor.authorize(context.Background(), "/public")
+	require.NoError(t, err)
+	tenant, scoped = tenantScope(ctx)
+	require.True(t, scoped)
+	require.Equal(t, "", tenant)
+
+	// an invalid token is rejected on public methods too
+	_, err = authInterceptor.authorize(withToken("invalid"), "/public")
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+}
@@ -0,0 +1,230 @@
+package service
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+
+	pb "github.com/orkhanrustamli/pcbook/pcbook_proto/go"
+)
+
+var ErrUnitOfWorkDone = errors.New("unit of work is already committed or rolled back")
+
+// UnitOfWork stages laptop, image and rating changes and applies them all or
+// none on Commit. Nothing touches the stores before Commit, which applies
+// images first, then ratings, then laptops. If a change fails, the changes
+// already applied are compensated in reverse order: laptops are deleted,
+// ratings are taken back and image files are removed.
+type UnitOfWork struct {
+	laptopStore LaptopStore
+	imageStore  ImageStore
+	ratingStore RatingStore
+
+	mutex   sync.Mutex
+	done    bool
+	laptops []*pb.Laptop
+	images  []stagedImage
+	ratings []stagedRating
+}
+
+type stagedImage struct {
+	laptopId  string
+	imageType string
+	imageData bytes.Buffer
+}
+
+type stagedRating struct {
+	laptopId string
+	score    float64
+}
+
+type UnitOfWorkResult struct {
+	// ImageIds and Ratings are in the order the changes were staged
+	ImageIds []string
+	Ratings  []*Rating
+}
+
+func NewUnitOfWork(laptopStore LaptopStore, imageStore ImageStore, ratingStore RatingStore) *UnitOfWork {
+	return &UnitOfWork{
+		laptopStore: laptopStore,
+		imageStore:  imageStore,
+		ratingStore: ratingStore,
+	}
+}
+
+func (uow *UnitOfWork) SaveLaptop(laptop *pb.Laptop) {
+	uow.mutex.Lock()
+	defer uow.mutex.Unlock()
+
+	uow.laptops = append(uow.laptops, laptop)
+}
+
+func (uow *UnitOfWork) SaveImage(laptopId string, imageType string, imageData bytes.Buffer) {
+	uow.mutex.Lock()
+	defer uow.mutex.Unlock()
+
+	uow.images = append(uow.images, stagedImage{laptopId, imageType, imageData})
+}
+
+func (uow *UnitOfWork) RateLaptop(laptopId string, score float64) {
+	uow.mutex.Lock()
+	defer uow.mutex.Unlock()
+
+	uow.ratings = append(uow.ratings, stagedRating{laptopId, score})
+}
+
+// Rollback discards every staged change
+func (uow *UnitOfWork) Rollback() {
+	uow.mutex.Lock()
+	defer uow.mutex.Unlock()
+
+	uow.finish()
+}
+
+// Commit applies every staged change. Images and ratings must refer to a
+// laptop that is in the store or staged in the same unit of work, otherwise
+// Commit fails with ErrNotFound. The laptops of the images are checked again
+// once the files are written, so an image never outlives a laptop removed
+// while it was being uploaded.
+func (uow *UnitOfWork) Commit() (*UnitOfWorkResult, error) {
+	uow.mutex.Lock()
+	defer uow.mutex.Unlock()
+
+	if uow.done {
+		return nil, ErrUnitOfWorkDone
+	}
+	defer uow.finish()
+
+	if err := uow.checkNewLaptops(); err != nil {
+		return nil, err
+	}
+	if err := uow.checkLaptops(); err != nil {
+		return nil, err
+	}
+
+	result := &UnitOfWorkResult{}
+	undo := []func(){}
+	rollback := func(err error) (*UnitOfWorkResult, error) {
+		for i := len(undo) - 1; i >= 0; i-- {
+			undo[i]()
+		}
+		return nil, err
+	}
+
+	for _, image := range uow.images {
+		imageId, err := uow.imageStore.Save(image.laptopId, image.imageType, image.imageData)
+		if err != nil {
+			return rollback(err)
+		}
+
+		undo = append(undo, func() {
+			if err := uow.imageStore.Delete(imageId); err != nil {
+				log.Printf("Cannot remove image %s while rolling back: %v", imageId, err)
+			}
+		})
+		result.ImageIds = append(result.ImageIds, imageId)
+	}
+
+	if err := uow.checkLaptops(); err != nil {
+		return rollback(err)
+	}
+
+	for _, staged := range uow.ratings {
+		rating, err := uow.ratingStore.Rate(staged.laptopId, staged.score)
+		if err != nil {
+			return rollback(fmt.Errorf("cannot rate laptop %s: %w", staged.laptopId, err))
+		}
+
+		staged := staged
+		undo = append(undo, func() {
+			if _, err := uow.ratingStore.Unrate(staged.laptopId, staged.score); err != nil {
+				log.Printf("Cannot take back rating of laptop %s while rolling back: %v", staged.laptopId, err)
+			}
+		})
+		result.Ratings = append(result.Ratings, rating)
+	}
+
+	for _, laptop := range uow.laptops {
+		if err := uow.laptopStore.Save(laptop); err != nil {
+			return rollback(err)
+		}
+
+		laptopId := laptop.GetId()
+		undo = append(undo, func() {
+			if err := uow.laptopStore.Delete(laptopId); err != nil {
+				log.Printf("Cannot remove laptop %s while rolling back: %v", laptopId, err)
+			}
+		})
+	}
+
+	return result, nil
+}
+
+// checkNewLaptops fails on staged laptops that are already in the store
+// before anything is applied, so that saving them only fails on a race with
+// another writer
+func (uow *UnitOfWork) checkNewLaptops() error {
+	staged := make(map[string]bool)
+	for _, laptop := range uow.laptops {
+		if staged[laptop.GetId()] {
+			return fmt.Errorf("laptop %s: %w", laptop.GetId(), ErrAlreadyExists)
+		}
+		staged[laptop.GetId()] = true
+
+		other, err := uow.laptopStore.Find(laptop.GetId())
+		if err != nil {
+			return fmt.Errorf("cannot find laptop: %v", err)
+		}
+		if other != nil {
+			return fmt.Errorf("laptop %s: %w", laptop.GetId(), ErrAlreadyExists)
+		}
+	}
+
+	return nil
+}
+
+func (uow *UnitOfWork) checkLaptops() error {
+	known := make(map[string]bool)
+	for _, laptop := range uow.laptops {
+		known[laptop.GetId()] = true
+	}
+
+	for _, image := range uow.images {
+		if err := uow.checkLaptop(image.laptopId, known); err != nil {
+			return err
+		}
+	}
+	for _, rating := range uow.ratings {
+		if err := uow.checkLaptop(rating.laptopId, known); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (uow *UnitOfWork) checkLaptop(laptopId string, known map[string]bool) error {
+	if known[laptopId] {
+		return nil
+	}
+	known[laptopId] = true
+
+	laptop, err := uow.laptopStore.Find(laptopId)
+	if err != nil {
+		return fmt.Errorf("cannot find laptop: %v", err)
+	}
+	if laptop == nil {
+		return fmt.Errorf("laptop %s: %w", laptopId, ErrNotFound)
+	}
+
+	return nil
+}
+
+func (uow *UnitOfWork) finish() {
+	uow.done = true
+	uow.laptops = nil
+	uow.images = nil
+	uow.ratings = nil
+}
@@ -0,0 +1,147 @@
+package service
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/orkhanrustamli/pcbook/genarator"
+	pb "github.com/orkhanrustamli/pcbook/pcbook_proto/go"
+	"github.com/stretchr/testify/require"
+)
+
+// failingLaptopStore refuses to save any laptop once it saved the first
+// saves of them
+type failingLaptopStore struct {
+	LaptopStore
+	saves int
+}
+
+func (store *failingLaptopStore) Save(laptop *pb.Laptop) error {
+	if store.saves == 0 {
+		return errors.New("disk full")
+	}
+
+	store.saves--
+	return store.LaptopStore.Save(laptop)
+}
+
+func TestUnitOfWorkCommit(t *testing.T) {
+	t.Parallel()
+
+	laptopStore := NewInMemoryLaptopStore()
+	imageStore := NewDiskImageStore(t.TempDir())
+	ratingStore := NewInMemoryRatingStore()
+
+	laptop := genarator.NewLaptop()
+	uow := NewUnitOfWork(laptopStore, imageStore, ratingStore)
+	uow.SaveLaptop(laptop)
+	uow.SaveImage(laptop.GetId(), ".jpg", *bytes.NewBufferString("image"))
+	uow.RateLaptop(laptop.GetId(), 7)
+
+	result, err := uow.Commit()
+	require.NoError(t, err)
+	require.Len(t, result.ImageIds, 1)
+	require.Len(t, result.Ratings, 1)
+	require.Equal(t, 1, result.Ratings[0].Count())
+
+	other, err := laptopStore.Find(laptop.GetId())
+	require.NoError(t, err)
+	require.NotNil(t, other)
+	require.FileExists(t, imageStore.images[result.ImageIds[0]].Path)
+
+	_, err = uow.Commit()
+	require.ErrorIs(t, err, ErrUnitOfWorkDone)
+}
+
+func TestUnitOfWorkMissingLaptop(t *testing.T) {
+	t.Parallel()
+
+	imageFolder := t.TempDir()
+	ratingStore := NewInMemoryRatingStore()
+
+	uow := NewUnitOfWork(NewInMemoryLaptopStore(), NewDiskImageStore(imageFolder), ratingStore)
+	laptopId := genarator.NewLaptop().GetId()
+	uow.SaveImage(laptopId, ".jpg", *bytes.NewBufferString("image"))
+	uow.RateLaptop(laptopId, 7)
+
+	_, err := uow.Commit()
+	require.ErrorIs(t, err, ErrNotFound)
+	require.Nil(t, ratingStore.Find(laptopId))
+
+	files, err := os.ReadDir(imageFolder)
+	require.NoError(t, err)
+	require.Empty(t, files)
+}
+
+func TestUnitOfWorkCompensation(t *testing.T) {
+	t.Parallel()
+
+	imageFolder := t.TempDir()
+	laptopStore := NewInMemoryLaptopStore()
+	ratingStore := NewInMemoryRatingStore()
+
+	rated := genarator.NewLaptop()
+	require.NoError(t, laptopStore.Save(rated))
+	ratingStore.Rate(rated.GetId(), 9)
+
+	laptop := genarator.NewLaptop()
+	uow := NewUnitOfWork(&failingLaptopStore{LaptopStore: laptopStore}, NewDiskImageStore(imageFolder), ratingStore)
+	uow.SaveLaptop(laptop)
+	uow.SaveImage(laptop.GetId(), ".png", *bytes.NewBufferString("image"))
+	uow.RateLaptop(laptop.GetId(), 5)
+	uow.RateLaptop(rated.GetId(), 1)
+
+	_, err := uow.Commit()
+	require.EqualError(t, err, "disk full")
+
+	require.Nil(t, ratingStore.Find(laptop.GetId()))
+	rating := ratingStore.Find(rated.GetId())
+	require.Equal(t, 1, rating.Count())
+	require.Equal(t, 9.0, rating.Sum())
+
+	files, err := os.ReadDir(imageFolder)
+	require.NoError(t, err)
+	require.Empty(t, files)
+}
+
+func TestUnitOfWorkLaptopCompensation(t *testing.T) {
+	t.Parallel()
+
+	laptopStore := NewInMemoryLaptopStore()
+	laptop1 := genarator.NewLaptop()
+	laptop2 := genarator.NewLaptop()
+
+	uow := NewUnitOfWork(&failingLaptopStore{LaptopStore: laptopStore, saves: 1}, nil, nil)
+	uow.SaveLaptop(laptop1)
+	uow.SaveLaptop(laptop2)
+
+	_, err := uow.Commit()
+	require.EqualError(t, err, "disk full")
+
+	// the laptop saved before the failure is taken back
+	for _, laptop := range []*pb.Laptop{laptop1, laptop2} {
+		other, err := laptopStore.Find(laptop.GetId())
+		require.NoError(t, err)
+		require.Nil(t, other)
+	}
+}
+
+func TestUnitOfWorkRollback(t *testing.T) {
+	t.Parallel()
+
+	laptopStore := NewInMemoryLaptopStore()
+	laptop := genarator.NewLaptop()
+
+	uow := NewUnitOfWork(laptopStore, nil, nil)
+	uow.SaveLaptop(laptop)
+	uow.Rollback()
+
+	_, err := uow.Commit()
+	require.ErrorIs(t, err, ErrUnitOfWorkDone)
+
+	other, err := laptopStore.Find(laptop.GetId())
+	require.NoError(t, err)
+	require.Nil(t, other)
+}
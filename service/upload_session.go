@@ -0,0 +1,273 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const uploadSessionExt = ".upload"
+
+var (
+	ErrUploadGap        = errors.New("chunk leaves a gap after the committed offset")
+	ErrUploadOverflow   = errors.New("chunk goes past the size of the upload")
+	ErrUploadIncomplete = errors.New("upload is not complete")
+	ErrUploadFinishing  = errors.New("upload is being finished")
+)
+
+// UploadSession is an image upload that is sent in chunks, possibly over
+// several connections. Committed is the number of bytes written so far.
+type UploadSession struct {
+	Id        string
+	LaptopId  string
+	ImageType string
+	Size      int64
+	Committed int64
+	ExpiresAt time.Time
+	path      string
+	// finishing is set while a claimed session is saved
+	finishing bool
+}
+
+// UploadSessionStore writes the chunks of every session to a file of its
+// own in folder. A session expires when no chunk arrived for ttl, and its
+// file is removed by the next Collect.
+type UploadSessionStore struct {
+	mutex    sync.Mutex
+	folder   string
+	ttl      time.Duration
+	now      func() time.Time
+	sessions map[string]*UploadSession
+}
+
+// NewUploadSessionStore creates folder if needed and removes the sessions
+// left in it, since nothing tells how far they got
+func NewUploadSessionStore(folder string, ttl time.Duration) (*UploadSessionStore, error) {
+	if err := os.MkdirAll(folder, 0755); err != nil {
+		return nil, fmt.Errorf("cannot create upload folder: %v", err)
+	}
+
+	paths, err := filepath.Glob(filepath.Join(folder, "*"+uploadSessionExt))
+	if err != nil {
+		return nil, fmt.Errorf("cannot list upload sessions: %v", err)
+	}
+	for _, path := range paths {
+		os.Remove(path)
+	}
+
+	return &UploadSessionStore{
+		folder:   folder,
+		ttl:      ttl,
+		now:      time.Now,
+		sessions: make(map[string]*UploadSession),
+	}, nil
+}
+
+// Start opens a session for an image of size bytes
+func (store *UploadSessionStore) Start(laptopId string, imageType string, size int64) (*UploadSession, error) {
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return nil, fmt.Errorf("cannot generate upload session id: %v", err)
+	}
+
+	session := &UploadSession{
+		Id:        id.String(),
+		LaptopId:  laptopId,
+		ImageType: imageType,
+		Size:      size,
+		path:      filepath.Join(store.folder, id.String()+uploadSessionExt),
+	}
+
+	file, err := os.Create(session.path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create upload file: %v", err)
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(session.path)
+		return nil, fmt.Errorf("cannot create upload file: %v", err)
+	}
+
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	session.ExpiresAt = store.now().Add(store.ttl)
+	store.sessions[session.Id] = session
+
+	other := *session
+	return &other, nil
+}
+
+// Find returns a copy of the session, or nil if there is none or it expired
+func (store *UploadSessionStore) Find(id string) (*UploadSession, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	session := store.find(id)
+	if session == nil {
+		return nil, nil
+	}
+
+	other := *session
+	return &other, nil
+}
+
+// Write appends the chunk at offset and returns the committed offset. The
+// part of a chunk that was committed already is skipped, so a chunk whose
+// acknowledgement was lost can be sent again.
+func (store *UploadSessionStore) Write(id string, offset int64, chunk []byte) (int64, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	session := store.find(id)
+	if session == nil {
+		return 0, ErrNotFound
+	}
+
+	if session.finishing {
+		return session.Committed, ErrUploadFinishing
+	}
+	if offset > session.Committed {
+		return session.Committed, fmt.Errorf("%w: chunk at %d, committed %d", ErrUploadGap, offset, session.Committed)
+	}
+	if offset+int64(len(chunk)) > session.Size {
+		return session.Committed, fmt.Errorf("%w: chunk ends at %d, size %d", ErrUploadOverflow, offset+int64(len(chunk)), session.Size)
+	}
+
+	chunk = chunk[session.Committed-offset:]
+	if len(chunk) > 0 {
+		file, err := os.OpenFile(session.path, os.O_WRONLY, 0)
+		if err != nil {
+			return session.Committed, fmt.Errorf("cannot open upload file: %v", err)
+		}
+
+		_, err = file.WriteAt(chunk, session.Committed)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return session.Committed, fmt.Errorf("cannot write upload file: %v", err)
+		}
+
+		session.Committed += int64(len(chunk))
+	}
+	session.ExpiresAt = store.now().Add(store.ttl)
+
+	return session.Committed, nil
+}
+
+// Claim returns the data of a complete session, and keeps it from being
+// written or claimed again until it is deleted or released, so that a
+// session finished twice at once is only saved once
+func (store *UploadSessionStore) Claim(id string) ([]byte, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	session := store.find(id)
+	if session == nil {
+		return nil, ErrNotFound
+	}
+	if session.finishing {
+		return nil, ErrUploadFinishing
+	}
+	if session.Committed < session.Size {
+		return nil, fmt.Errorf("%w: committed %d of %d bytes", ErrUploadIncomplete, session.Committed, session.Size)
+	}
+
+	data, err := os.ReadFile(session.path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read upload file: %v", err)
+	}
+	session.finishing = true
+
+	return data, nil
+}
+
+// Release lets a claimed session be claimed again, once saving it failed
+func (store *UploadSessionStore) Release(id string) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if session := store.sessions[id]; session != nil {
+		session.finishing = false
+		session.ExpiresAt = store.now().Add(store.ttl)
+	}
+}
+
+// Delete removes the session along with its file, expired or not
+func (store *UploadSessionStore) Delete(id string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	session := store.sessions[id]
+	if session == nil {
+		return ErrNotFound
+	}
+
+	return store.remove(session)
+}
+
+// Collect removes every expired session and returns how many there were
+func (store *UploadSessionStore) Collect() int {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	now := store.now()
+	collected := 0
+	for _, session := range store.sessions {
+		if session.finishing || now.Before(session.ExpiresAt) {
+			continue
+		}
+
+		if err := store.remove(session); err != nil {
+			log.Printf("Cannot remove upload session %s: %v", session.Id, err)
+			continue
+		}
+		collected++
+	}
+
+	return collected
+}
+
+// Run collects expired sessions every interval until ctx is done
+func (store *UploadSessionStore) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if collected := store.Collect(); collected > 0 {
+				log.Printf("Collected %d abandoned upload sessions", collected)
+			}
+		}
+	}
+}
+
+// find must be called with the lock held
+func (store *UploadSessionStore) find(id string) *UploadSession {
+	session := store.sessions[id]
+	if session == nil || !store.now().Before(session.ExpiresAt) {
+		return nil
+	}
+
+	return session
+}
+
+// remove must be called with the lock held
+func (store *UploadSessionStore) remove(session *UploadSession) error {
+	if err := os.Remove(session.path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	delete(store.sessions, session.Id)
+
+	return nil
+}
@@ -0,0 +1,249 @@
+package service
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/orkhanrustamli/pcbook/genarator"
+	pb "github.com/orkhanrustamli/pcbook/pcbook_proto/go"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestUploadSessionStore(t *testing.T) {
+	t.Parallel()
+
+	folder := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(folder, "left"+uploadSessionExt), []byte("x"), 0644))
+
+	store, err := NewUploadSessionStore(folder, time.Hour)
+	require.NoError(t, err)
+
+	paths, err := filepath.Glob(filepath.Join(folder, "*"))
+	require.NoError(t, err)
+	require.Empty(t, paths)
+
+	session, err := store.Start("laptop", "image/png", 10)
+	require.NoError(t, err)
+
+	committed, err := store.Write(session.Id, 0, []byte("0123"))
+	require.NoError(t, err)
+	require.EqualValues(t, 4, committed)
+
+	// a chunk sent again only adds what was not committed
+	committed, err = store.Write(session.Id, 2, []byte("23456"))
+	require.NoError(t, err)
+	require.EqualValues(t, 7, committed)
+
+	committed, err = store.Write(session.Id, 8, []byte("89"))
+	require.ErrorIs(t, err, ErrUploadGap)
+	require.EqualValues(t, 7, committed)
+
+	_, err = store.Write(session.Id, 7, []byte("7890"))
+	require.ErrorIs(t, err, ErrUploadOverflow)
+
+	_, err = store.Claim(session.Id)
+	require.ErrorIs(t, err, ErrUploadIncomplete)
+
+	committed, err = store.Write(session.Id, 7, []byte("789"))
+	require.NoError(t, err)
+	require.EqualValues(t, 10, committed)
+
+	data, err := store.Claim(session.Id)
+	require.NoError(t, err)
+	require.Equal(t, []byte("0123456789"), data)
+
+	// a claimed session is saved by whoever claimed it only
+	_, err = store.Claim(session.Id)
+	require.ErrorIs(t, err, ErrUploadFinishing)
+	_, err = store.Write(session.Id, 0, []byte("0"))
+	require.ErrorIs(t, err, ErrUploadFinishing)
+
+	store.Release(session.Id)
+	_, err = store.Claim(session.Id)
+	require.NoError(t, err)
+
+	require.NoError(t, store.Delete(session.Id))
+	require.ErrorIs(t, store.Delete(session.Id), ErrNotFound)
+
+	_, err = store.Write("unknown", 0, []byte("0"))
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestUploadSessionStoreCollect(t *testing.T) {
+	t.Parallel()
+
+	store, err := NewUploadSessionStore(t.TempDir(), time.Hour)
+	require.NoError(t, err)
+
+	now := time.Now()
+	store.now = func() time.Time { return now }
+
+	idle, err := store.Start("laptop", "", 10)
+	require.NoError(t, err)
+	active, err := store.Start("laptop", "", 10)
+	require.NoError(t, err)
+
+	// a chunk keeps the session alive for another TTL
+	now = now.Add(50 * time.Minute)
+	_, err = store.Write(active.Id, 0, []byte("0"))
+	require.NoError(t, err)
+
+	now = now.Add(20 * time.Minute)
+	session, err := store.Find(idle.Id)
+	require.NoError(t, err)
+	require.Nil(t, session)
+
+	require.Equal(t, 1, store.Collect())
+	_, err = os.Stat(idle.path)
+	require.True(t, os.IsNotExist(err))
+
+	session, err = store.Find(active.Id)
+	require.NoError(t, err)
+	require.NotNil(t, session)
+	require.EqualValues(t, 1, session.Committed)
+
+	now = now.Add(time.Hour)
+	require.Equal(t, 1, store.Collect())
+	require.Equal(t, 0, store.Collect())
+}
+
+func TestServerResumableUpload(t *testing.T) {
+	t.Parallel()
+
+	laptopStore := NewInMemoryLaptopStore()
+	imageStore := NewDiskImageStore(t.TempDir())
+
+	laptop := genarator.NewLaptop()
+	require.NoError(t, laptopStore.Save(laptop))
+
+	uploads, err := NewUploadSessionStore(t.TempDir(), time.Hour)
+	require.NoError(t, err)
+
+	laptopServer := NewLaptopServer(laptopStore, imageStore, nil)
+	laptopServer.Uploads = uploads
+	grpcServer := grpc.NewServer()
+	pb.RegisterLaptopServiceServer(grpcServer, laptopServer)
+	listener, err := net.Listen("tcp", ":0")
+	require.NoError(t, err)
+	go grpcServer.Serve(listener)
+	defer grpcServer.Stop()
+
+	client := startTestLaptopClient(t, listener.Addr().String())
+	imageData := encodeTestImage(t, "image/png", 64, 64)
+
+	_, err = client.StartUpload(context.Background(), &pb.StartUploadRequest{
+		Info: &pb.ImageInfo{LaptopId: laptop.GetId(), ImageType: ".exe"},
+		Size: uint64(len(imageData)),
+	})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = client.StartUpload(context.Background(), &pb.StartUploadRequest{
+		Info: &pb.ImageInfo{LaptopId: laptop.GetId()},
+		Size: maxImageSize + 1,
+	})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = client.StartUpload(context.Background(), &pb.StartUploadRequest{
+		Info: &pb.ImageInfo{LaptopId: "unknown"},
+		Size: uint64(len(imageData)),
+	})
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	started, err := client.StartUpload(context.Background(), &pb.StartUploadRequest{
+		Info: &pb.ImageInfo{LaptopId: laptop.GetId(), ImageType: ".png"},
+		Size: uint64(len(imageData)),
+	})
+	require.NoError(t, err)
+	sessionId := started.GetSessionId()
+
+	// the connection drops after the first half was sent
+	half := len(imageData) / 2
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := client.UploadChunk(ctx)
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(&pb.UploadChunkRequest{
+		SessionId: sessionId,
+		ChunkData: imageData[:half],
+	}))
+	require.Eventually(t, func() bool {
+		session, err := uploads.Find(sessionId)
+		return err == nil && session.Committed == int64(half)
+	}, time.Second, 10*time.Millisecond)
+	cancel()
+
+	_, err = client.FinishUpload(context.Background(), &pb.FinishUploadRequest{SessionId: sessionId})
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	query, err := client.QueryUpload(context.Background(), &pb.QueryUploadRequest{SessionId: sessionId})
+	require.NoError(t, err)
+	require.EqualValues(t, half, query.GetCommittedOffset())
+	require.EqualValues(t, len(imageData), query.GetSize())
+
+	upload := func(offset int, chunk []byte) (*pb.UploadChunkResponse, error) {
+		stream, err := client.UploadChunk(context.Background())
+		require.NoError(t, err)
+		require.NoError(t, stream.Send(&pb.UploadChunkRequest{
+			SessionId: sessionId,
+			Offset:    uint64(offset),
+			ChunkData: chunk,
+		}))
+		return stream.CloseAndRecv()
+	}
+
+	_, err = upload(half+1, imageData[half+1:])
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	_, err = upload(half, append(imageData[half:len(imageData):len(imageData)], 0))
+	require.Equal(t, codes.OutOfRange, status.Code(err))
+
+	res, err := upload(half, imageData[half:])
+	require.NoError(t, err)
+	require.EqualValues(t, len(imageData), res.GetCommittedOffset())
+
+	// finishing twice at once saves the image once
+	results := make(chan error)
+	finished := make(chan *pb.UploadImageResponse, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			res, err := client.FinishUpload(context.Background(), &pb.FinishUploadRequest{SessionId: sessionId})
+			if err == nil {
+				finished <- res
+			}
+			results <- err
+		}()
+	}
+	errs := []error{<-results, <-results}
+	require.Len(t, finished, 1)
+	saved := <-finished
+	if errs[0] == nil {
+		errs[0], errs[1] = errs[1], errs[0]
+	}
+	require.Nil(t, errs[1])
+	// the other one either found the session claimed or already removed
+	require.Contains(t, []codes.Code{codes.Aborted, codes.NotFound}, status.Code(errs[0]))
+	images := 0
+	require.NoError(t, imageStore.List(func(image *ImageInfo) error {
+		images++
+		return nil
+	}))
+	require.Equal(t, 1, images)
+	require.Equal(t, "image/png", saved.GetImageType())
+	require.EqualValues(t, len(imageData), saved.GetSize())
+
+	image, err := imageStore.Find(saved.GetId())
+	require.NoError(t, err)
+	require.Equal(t, laptop.GetId(), image.LaptopId)
+	stored, err := os.ReadFile(image.Path)
+	require.NoError(t, err)
+	require.Equal(t, imageData, stored)
+
+	_, err = client.QueryUpload(context.Background(), &pb.QueryUploadRequest{SessionId: sessionId})
+	require.Equal(t, codes.NotFound, status.Code(err))
+}
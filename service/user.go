@@ -11,9 +11,12 @@ type User struct {
 	Username string
 	Password string
 	Role     string
+	// Tenant is the catalog the user works in, the empty tenant is the
+	// default catalog
+	Tenant string
 }
 
-func NewUser(username, password, role string) (*User, error) {
+func NewUser(username, password, role, tenant string) (*User, error) {
 	hashedPass, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
 		return nil, fmt.Errorf("cannot generate password hash: %v", err)
@@ -23,6 +26,7 @@ func NewUser(username, password, role string) (*User, error) {
 		Username: username,
 		Password: string(hashedPass),
 		Role:     role,
+		Tenant:   tenant,
 	}
 
 	return user, nil
@@ -2,12 +2,17 @@ package service
 
 import (
 	"fmt"
+	"sort"
 	"sync"
 )
 
 type UserStore interface {
 	Save(user *User) error
 	Find(username string) *User
+	// List calls found with every user, ordered by username
+	List(found func(user *User) error) error
+	// Delete removes the user, it returns ErrNotFound if there is none
+	Delete(username string) error
 }
 
 type InMemoryUserStore struct {
@@ -42,5 +47,53 @@ func (userStore *InMemoryUserStore) Find(username string) *User {
 	userStore.mutex.RLock()
 	defer userStore.mutex.RUnlock()
 
-	return userStore.users[username]
+	user := userStore.users[username]
+	if user == nil {
+		return nil
+	}
+
+	clone, err := user.Clone()
+	if err != nil {
+		return nil
+	}
+
+	return clone
+}
+
+func (userStore *InMemoryUserStore) Delete(username string) error {
+	userStore.mutex.Lock()
+	defer userStore.mutex.Unlock()
+
+	if userStore.users[username] == nil {
+		return ErrNotFound
+	}
+
+	delete(userStore.users, username)
+	return nil
+}
+
+func (userStore *InMemoryUserStore) List(found func(user *User) error) error {
+	userStore.mutex.RLock()
+	users := make([]*User, 0, len(userStore.users))
+	for _, user := range userStore.users {
+		clone, err := user.Clone()
+		if err != nil {
+			userStore.mutex.RUnlock()
+			return fmt.Errorf("cannot clone the user: %v", err)
+		}
+		users = append(users, clone)
+	}
+	userStore.mutex.RUnlock()
+
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].Username < users[j].Username
+	})
+
+	for _, user := range users {
+		if err := found(user); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }